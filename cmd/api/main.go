@@ -4,6 +4,11 @@ import (
 	"context"
 	"database/sql"
 	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/ClickHouse/clickhouse-go/v2"
@@ -11,11 +16,18 @@ import (
 	"github.com/gin-gonic/gin"
 	_ "github.com/lib/pq"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/segmentio/kafka-go"
+	"google.golang.org/grpc"
 
+	"github.com/yourusername/event-analytics-service/internal/botdetect"
 	"github.com/yourusername/event-analytics-service/internal/config"
+	"github.com/yourusername/event-analytics-service/internal/dlq"
+	"github.com/yourusername/event-analytics-service/internal/geoip"
+	"github.com/yourusername/event-analytics-service/internal/grpcserver"
 	"github.com/yourusername/event-analytics-service/internal/handler"
 	"github.com/yourusername/event-analytics-service/internal/metrics"
 	"github.com/yourusername/event-analytics-service/internal/middleware"
+	"github.com/yourusername/event-analytics-service/internal/models"
 	"github.com/yourusername/event-analytics-service/internal/producer"
 	"github.com/yourusername/event-analytics-service/internal/repository"
 	"github.com/yourusername/event-analytics-service/internal/service"
@@ -70,10 +82,28 @@ func main() {
 		log.Fatal("Failed to connect to Redis:", err)
 	}
 
+	// Локальный spool сохраняет события, пока Kafka недоступна
+	var spool *producer.Spool
+	if cfg.SpoolDir != "" {
+		spool, err = producer.NewSpool(cfg.SpoolDir, cfg.SpoolMaxBytes)
+		if err != nil {
+			log.Fatal("Failed to open producer spool:", err)
+		}
+	}
+
+	var requiredAcks kafka.RequiredAcks
+	if err := requiredAcks.UnmarshalText([]byte(cfg.KafkaRequiredAcks)); err != nil {
+		log.Fatal("Invalid KAFKA_REQUIRED_ACKS:", err)
+	}
+
 	// Инициализируем Kafka producer
 	kafkaProducer := producer.NewEventProducer(
 		[]string{cfg.KafkaBroker},
 		cfg.KafkaTopic,
+		requiredAcks,
+		spool,
+		cfg.SpoolReplayInterval,
+		appMetrics,
 	)
 	defer kafkaProducer.Close()
 
@@ -82,9 +112,51 @@ func main() {
 	userRepo := repository.NewUserRepository(psqlDB)
 	sessionRepo := repository.NewSessionRepository(psqlDB)
 	projectRepo := repository.NewProjectRepository(psqlDB)
+	schemaRepo := repository.NewSchemaRepository(psqlDB)
+	transformRepo := repository.NewTransformRepository(psqlDB)
+	identityRepo := repository.NewIdentityRepository(conn)
+
+	// База GeoIP нужна API, чтобы определить страну до анонимизации IP
+	var geoResolver *geoip.Resolver
+	if cfg.GeoIPDBPath != "" {
+		geoResolver, err = geoip.NewResolver(cfg.GeoIPDBPath)
+		if err != nil {
+			log.Printf("Failed to load GeoIP database: %v", err)
+		} else {
+			go geoResolver.Watch(cfg.GeoIPReloadInterval)
+			defer geoResolver.Close()
+		}
+	}
+
+	// Классификация ботов по User-Agent, IP дата-центров и частоте событий
+	botDetector, err := botdetect.NewDetector(cfg.BotIPRangesPath, redisRepo, cfg.BotMaxEventsPerMinute)
+	if err != nil {
+		log.Fatal("Failed to load bot IP ranges:", err)
+	}
 
 	// Инициализируем сервисы
-	eventService := service.NewEventService(eventRepo, kafkaProducer, appMetrics)
+	schemaService := service.NewSchemaService(schemaRepo, redisRepo)
+	projectService := service.NewProjectService(projectRepo, eventRepo, redisRepo)
+	transformService := service.NewTransformService(transformRepo, appMetrics)
+	quotaService := service.NewQuotaService(redisRepo, projectRepo, cfg.QuotaMode)
+	eventService := service.NewEventService(
+		eventRepo,
+		kafkaProducer,
+		schemaService,
+		projectService,
+		quotaService,
+		redisRepo,
+		geoResolver,
+		botDetector,
+		appMetrics,
+		cfg.DedupWindow,
+		cfg.PrivacyHashSalt,
+		models.TimestampWindow{
+			MaxPast:   cfg.EventMaxPast,
+			MaxFuture: cfg.EventMaxFuture,
+			Policy:    cfg.TimestampPolicy,
+		},
+	)
 	statsService := service.NewStatsService(eventRepo, redisRepo, appMetrics)
 	exportService := service.NewExportService(eventRepo)
 	authService := service.NewAuthService(
@@ -94,7 +166,12 @@ func main() {
 		cfg.JWTTokenExpiry,
 		cfg.JWTRefreshExpiry,
 	)
-	projectService := service.NewProjectService(projectRepo, eventRepo, redisRepo)
+	identityService := service.NewIdentityService(identityRepo, projectService, cfg.PrivacyHashSalt)
+
+	// Использование квоты из Redis периодически сохраняется в projects.events_count
+	usageCtx, stopUsageSync := context.WithCancel(context.Background())
+	defer stopUsageSync()
+	go quotaService.SyncUsage(usageCtx, time.Minute)
 
 	// Инициализируем хендлеры
 	eventHandler := handler.NewEventHandler(eventService)
@@ -102,9 +179,20 @@ func main() {
 	exportHandler := handler.NewExportHandler(exportService)
 	authHandler := handler.NewAuthHandler(authService)
 	projectHandler := handler.NewProjectHandler(projectService)
+	schemaHandler := handler.NewSchemaHandler(schemaService, projectService)
+	transformHandler := handler.NewTransformHandler(transformService, projectService)
+	dlqHandler := handler.NewDLQHandler(dlq.NewManager(
+		[]string{cfg.KafkaBroker},
+		cfg.KafkaDLQTopic,
+		kafkaProducer,
+		redisRepo.Client,
+		appMetrics,
+	))
+	segmentHandler := handler.NewSegmentHandler(eventService, identityService)
+	identityHandler := handler.NewIdentityHandler(identityService)
 
 	// Инициализируем middleware
-	authMiddleware := middleware.NewAuthMiddleware(cfg.JWTSecret, cfg.JWTTokenExpiry)
+	authMiddleware := middleware.NewAuthMiddleware(cfg.JWTSecret, cfg.JWTTokenExpiry, projectService)
 
 	// Настраиваем роутер
 	router := gin.New()
@@ -132,8 +220,34 @@ func main() {
 		api.POST("/auth/login", authHandler.Login)
 		api.POST("/auth/refresh", authHandler.RefreshToken)
 
-		// Endpoint для трекинга событий (с API ключом)
-		api.POST("/events/track", authMiddleware.ValidateAPIKey(), eventHandler.TrackEvent)
+		// Endpoints для трекинга событий (с API ключом и лимитами проекта)
+		tracking := api.Group("/events")
+		tracking.Use(authMiddleware.ValidateAPIKey(), middleware.RateLimitMiddleware(quotaService, appMetrics))
+		{
+			tracking.POST("/track", eventHandler.TrackEvent)
+			tracking.POST("/batch", eventHandler.TrackBatch)
+			tracking.POST("/identify", identityHandler.Identify)
+			tracking.POST("/alias", identityHandler.Alias)
+		}
+
+		// Совместимый с Segment API (write key проекта в Basic авторизации)
+		segment := api.Group("/segment")
+		segment.Use(authMiddleware.ValidateAPIKey(), middleware.RateLimitMiddleware(quotaService, appMetrics))
+		{
+			segment.POST("/track", segmentHandler.Track)
+			segment.POST("/page", segmentHandler.Page)
+			segment.POST("/screen", segmentHandler.Screen)
+			segment.POST("/identify", segmentHandler.Identify)
+			segment.POST("/alias", segmentHandler.Alias)
+			segment.POST("/batch", segmentHandler.Batch)
+		}
+
+		// Пиксель всегда отвечает картинкой, поэтому ошибки ключа и лимитов не прерывают запрос
+		api.GET("/events/pixel.gif",
+			authMiddleware.ResolveAPIKey(),
+			middleware.SoftRateLimitMiddleware(quotaService, appMetrics),
+			eventHandler.TrackPixel,
+		)
 
 		// Protected endpoints (требуют JWT токен)
 		protected := api.Group("/")
@@ -152,6 +266,21 @@ func main() {
 			protected.DELETE("/projects/:id", projectHandler.DeleteProject)
 			protected.POST("/projects/:id/regenerate-key", projectHandler.RegenerateAPIKey)
 			protected.GET("/projects/:id/stats", projectHandler.GetProjectStats)
+			protected.GET("/projects/:id/funnel", projectHandler.GetFunnel)
+			protected.GET("/projects/:id/traffic-sources", projectHandler.GetTrafficSources)
+			protected.GET("/projects/:id/ingestion-latency", projectHandler.GetIngestionLatency)
+			protected.GET("/projects/:id/users/:user_id/sessions", projectHandler.GetUserSessions)
+
+			// Event schema endpoints
+			protected.GET("/projects/:id/schemas", schemaHandler.ListSchemas)
+			protected.GET("/projects/:id/schemas/:event_name", schemaHandler.GetSchema)
+			protected.PUT("/projects/:id/schemas/:event_name", schemaHandler.SaveSchema)
+			protected.DELETE("/projects/:id/schemas/:event_name", schemaHandler.DeleteSchema)
+
+			// Event transform endpoints
+			protected.GET("/projects/:id/transforms", transformHandler.GetTransforms)
+			protected.PUT("/projects/:id/transforms", transformHandler.SaveTransforms)
+			protected.POST("/projects/:id/transforms/dry-run", transformHandler.DryRunTransforms)
 
 			// Stats endpoints
 			protected.GET("/stats/events", statsHandler.GetStatistics)
@@ -160,9 +289,77 @@ func main() {
 			// Export endpoints
 			protected.GET("/export/csv", exportHandler.ExportCSV)
 			protected.GET("/export/json", exportHandler.ExportJSON)
+
+			// Dead-letter endpoints (только для администраторов)
+			admin := protected.Group("/admin")
+			admin.Use(authMiddleware.RequireRole("admin"))
+			{
+				admin.GET("/dlq", dlqHandler.ListDeadLetters)
+				admin.POST("/dlq/replay", dlqHandler.ReplayDeadLetters)
+				admin.POST("/dlq/purge", dlqHandler.PurgeDeadLetters)
+			}
+		}
+	}
+
+	// gRPC API приема событий и статистики для бэкенд-сервисов
+	var grpcServer *grpc.Server
+	if cfg.GRPCPort != "" {
+		listener, err := net.Listen("tcp", ":"+cfg.GRPCPort)
+		if err != nil {
+			log.Fatal("Failed to listen on gRPC port:", err)
+		}
+		grpcServer = grpcserver.NewServer(eventService, statsService, projectService, quotaService, appMetrics).GRPCServer()
+
+		go func() {
+			log.Printf("gRPC server starting on port %s", cfg.GRPCPort)
+			if err := grpcServer.Serve(listener); err != nil {
+				log.Printf("gRPC server error: %v", err)
+			}
+		}()
+	}
+
+	httpServer := &http.Server{
+		Addr:    ":" + cfg.Port,
+		Handler: router,
+	}
+
+	go func() {
+		log.Printf("Server starting on port %s", cfg.Port)
+		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal("HTTP server error:", err)
+		}
+	}()
+
+	// Graceful shutdown: принятые запросы и потоки дорабатывают до конца,
+	// затем отложенные вызовы сбрасывают буфер producer в Kafka или spool
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	<-sigChan
+
+	log.Println("Shutting down API...")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := httpServer.Shutdown(ctx); err != nil {
+		log.Printf("HTTP server shutdown error: %v", err)
+	}
+
+	if grpcServer != nil {
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			// Потоки не завершились за отведенное время
+			grpcServer.Stop()
 		}
 	}
 
-	log.Printf("Server starting on port %s", cfg.Port)
-	router.Run(":" + cfg.Port)
+	stopUsageSync()
+	quotaService.FlushUsage(ctx)
+	log.Println("API stopped")
 }
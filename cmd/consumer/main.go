@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"log"
 	"os"
 	"os/signal"
@@ -11,12 +12,16 @@ import (
 	"net/http"
 
 	"github.com/ClickHouse/clickhouse-go/v2"
+	_ "github.com/lib/pq"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"github.com/yourusername/event-analytics-service/internal/config"
 	"github.com/yourusername/event-analytics-service/internal/consumer"
+	"github.com/yourusername/event-analytics-service/internal/dlq"
+	"github.com/yourusername/event-analytics-service/internal/geoip"
 	"github.com/yourusername/event-analytics-service/internal/metrics"
 	"github.com/yourusername/event-analytics-service/internal/repository"
+	"github.com/yourusername/event-analytics-service/internal/service"
 )
 
 func main() {
@@ -46,6 +51,22 @@ func main() {
 		log.Fatal("Failed to connect to ClickHouse:", err)
 	}
 
+	// Подключаемся к PostgreSQL: оттуда читаются правила трансформации проектов
+	psqlConnStr := "host=" + cfg.PostgresHost +
+		" port=" + cfg.PostgresPort +
+		" user=" + cfg.PostgresUser +
+		" password=" + cfg.PostgresPassword +
+		" dbname=" + cfg.PostgresDB +
+		" sslmode=disable"
+
+	psqlDB, err := sql.Open("postgres", psqlConnStr)
+	if err != nil {
+		log.Fatal("Failed to connect to PostgreSQL:", err)
+	}
+	if err := psqlDB.Ping(); err != nil {
+		log.Fatal("PostgreSQL ping failed:", err)
+	}
+
 	// Подключаемся к Redis
 	redisRepo := repository.NewRedisRepository(cfg.RedisAddr, cfg.RedisPassword, cfg.RedisDB)
 	if err := redisRepo.Ping(context.Background()); err != nil {
@@ -54,6 +75,18 @@ func main() {
 
 	// Инициализируем репозиторий событий
 	eventRepo := repository.NewClickHouseRepository(conn)
+	transformService := service.NewTransformService(repository.NewTransformRepository(psqlDB), appMetrics)
+
+	// Загружаем базу GeoIP; без нее события сохраняются без страны
+	var geoResolver *geoip.Resolver
+	if cfg.GeoIPDBPath != "" {
+		geoResolver, err = geoip.NewResolver(cfg.GeoIPDBPath)
+		if err != nil {
+			log.Printf("Failed to load GeoIP database, country enrichment disabled: %v", err)
+		} else {
+			go geoResolver.Watch(cfg.GeoIPReloadInterval)
+		}
+	}
 
 	// Создаем Kafka consumer
 	kafkaConsumer := consumer.NewEventConsumer(
@@ -62,6 +95,20 @@ func main() {
 		cfg.KafkaGroup,
 		eventRepo,
 		redisRepo,
+		geoResolver,
+		transformService,
+		dlq.NewWriter([]string{cfg.KafkaBroker}, cfg.KafkaDLQTopic),
+		consumer.RetryPolicy{
+			MaxAttempts:  cfg.RetryMaxAttempts,
+			BaseDelay:    cfg.RetryBaseDelay,
+			MaxDelay:     cfg.RetryMaxDelay,
+			PollInterval: cfg.RetryPollInterval,
+		},
+		consumer.HealthConfig{
+			LagInterval:  cfg.ConsumerLagInterval,
+			MaxLag:       cfg.ConsumerMaxLag,
+			MaxInsertAge: cfg.ConsumerMaxInsertAge,
+		},
 		cfg.ConsumerWorkers,
 		appMetrics,
 	)
@@ -74,11 +121,10 @@ func main() {
 		// Метрики Prometheus
 		mux.Handle("/metrics", promhttp.Handler())
 
-		// Health check для Docker
-		mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte(`{"status":"ok"}`))
-		})
+		// Health check: лаг по партициям и время последней записи. /health
+		// отвечает 200 и при degraded (проверка живости), /ready - 503
+		mux.HandleFunc("/health", kafkaConsumer.HealthHandler(false))
+		mux.HandleFunc("/ready", kafkaConsumer.HealthHandler(true))
 
 		log.Printf("Consumer metrics server starting on :8081")
 		if err := http.ListenAndServe(":8081", mux); err != nil {
@@ -96,7 +142,11 @@ func main() {
 
 	log.Println("Shutting down consumer...")
 	kafkaConsumer.Stop()
+	if geoResolver != nil {
+		geoResolver.Close()
+	}
 	redisRepo.Close()
+	psqlDB.Close()
 	conn.Close()
 	log.Println("Consumer stopped")
 }
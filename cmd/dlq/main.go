@@ -0,0 +1,138 @@
+// Команда dlq просматривает, повторно отправляет и удаляет события
+// dead-letter топика:
+//
+//	dlq list   [-project id] [-type t] [-reason r] [-since t] [-until t] [-limit n]
+//	dlq replay [фильтр]
+//	dlq purge  [фильтр] [-all]
+//
+// Время задается в формате RFC3339. Результат печатается в JSON.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+
+	"github.com/yourusername/event-analytics-service/internal/config"
+	"github.com/yourusername/event-analytics-service/internal/dlq"
+	"github.com/yourusername/event-analytics-service/internal/metrics"
+	"github.com/yourusername/event-analytics-service/internal/models"
+	"github.com/yourusername/event-analytics-service/internal/producer"
+	"github.com/yourusername/event-analytics-service/internal/repository"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	command := os.Args[1]
+
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	projectID := flags.String("project", "", "project ID")
+	eventType := flags.String("type", "", "event type")
+	reason := flags.String("reason", "", "failure reason (insert_failed, unmarshal_failed, retries_exhausted)")
+	since := flags.String("since", "", "failed at or after (RFC3339)")
+	until := flags.String("until", "", "failed before (RFC3339)")
+	limit := flags.Int("limit", 0, "maximum number of events")
+	all := flags.Bool("all", false, "allow purge without filter")
+	flags.Parse(os.Args[2:])
+
+	filter := models.DeadLetterFilter{
+		ProjectID: *projectID,
+		EventType: *eventType,
+		Reason:    *reason,
+		Since:     parseTime("since", *since),
+		Until:     parseTime("until", *until),
+		Limit:     *limit,
+	}
+
+	cfg := config.LoadConfig()
+
+	redisRepo := repository.NewRedisRepository(cfg.RedisAddr, cfg.RedisPassword, cfg.RedisDB)
+	defer redisRepo.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := redisRepo.Ping(ctx); err != nil {
+		log.Fatal("Failed to connect to Redis:", err)
+	}
+
+	brokers := []string{cfg.KafkaBroker}
+
+	switch command {
+	case "list":
+		manager := dlq.NewManager(brokers, cfg.KafkaDLQTopic, nil, redisRepo.Client, nil)
+		letters, err := manager.List(ctx, filter)
+		if err != nil {
+			log.Fatal("Failed to list dead letters:", err)
+		}
+		printJSON(letters)
+
+	case "replay":
+		// Повторно отправленные события снова проходят через консьюмер
+		eventProducer := producer.NewEventProducer(
+			brokers,
+			cfg.KafkaTopic,
+			kafka.RequireAll,
+			nil,
+			0,
+			metrics.NewMetrics("event-analytics-dlq"),
+		)
+		defer eventProducer.Close()
+
+		manager := dlq.NewManager(brokers, cfg.KafkaDLQTopic, eventProducer, redisRepo.Client, nil)
+		replayed, skipped, err := manager.Replay(ctx, filter)
+		printJSON(map[string]interface{}{"replayed": replayed, "skipped": skipped})
+		if err != nil {
+			log.Fatal("Failed to replay dead letters:", err)
+		}
+
+	case "purge":
+		if filter.Empty() && !*all {
+			log.Fatal("Filter required; pass -all to purge everything")
+		}
+
+		manager := dlq.NewManager(brokers, cfg.KafkaDLQTopic, nil, redisRepo.Client, nil)
+		purged, err := manager.Purge(ctx, filter)
+		if err != nil {
+			log.Fatal("Failed to purge dead letters:", err)
+		}
+		printJSON(map[string]interface{}{"purged": purged})
+
+	default:
+		usage()
+	}
+}
+
+func parseTime(name, value string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		log.Fatalf("Invalid -%s: %v", name, err)
+	}
+	return t
+}
+
+func printJSON(v interface{}) {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: dlq list|replay|purge [-project id] [-type t] [-reason r] [-since t] [-until t] [-limit n] [-all]")
+	os.Exit(2)
+}
@@ -0,0 +1,171 @@
+// Package botdetect отмечает события ботов, краулеров и сервисов мониторинга
+// по User-Agent, диапазонам IP дата-центров и частоте событий пользователя.
+package botdetect
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/yourusername/event-analytics-service/internal/models"
+)
+
+// Причины, по которым событие признано ботом
+const (
+	ReasonUserAgent  = "user_agent"
+	ReasonDatacenter = "datacenter"
+	ReasonRate       = "rate"
+)
+
+// Окно, в котором считается частота событий пользователя
+const rateWindow = time.Minute
+
+// Фрагменты User-Agent поисковых роботов, превью ссылок, headless-браузеров
+// и сервисов мониторинга доступности. Отдельное "bot" не используется,
+// чтобы не задеть устройства вроде "CUBOT".
+var userAgentSignatures = []string{
+	"bot/", "bot;", "bot)", "bot-", "_bot", "-bot", "bot.htm",
+	"crawler", "spider", "slurp", "mediapartners-google", "adsbot",
+	"facebookexternalhit", "embedly", "quora link preview", "whatsapp/",
+	"headlesschrome", "phantomjs", "puppeteer", "playwright", "selenium",
+	"lighthouse", "pagespeed", "gtmetrix", "prerender",
+	"pingdom", "uptimerobot", "statuscake", "site24x7", "newrelicpinger",
+	"datadogsynthetics", "uptime-kuma", "better uptime", "checkly",
+}
+
+// RateCounter считает события пользователя в окне фиксированной длины
+type RateCounter interface {
+	IncrementUserRate(ctx context.Context, projectID, userID string, window time.Duration) (int64, error)
+}
+
+type Detector struct {
+	networks []*net.IPNet
+	rate     RateCounter
+	maxRate  int64
+}
+
+// NewDetector загружает диапазоны IP дата-центров из файла (по одному CIDR
+// в строке, # - комментарий). Пустой путь отключает проверку по IP, maxRate
+// <= 0 или rate == nil - проверку частоты событий пользователя за минуту.
+func NewDetector(networksPath string, rate RateCounter, maxRate int) (*Detector, error) {
+	d := &Detector{
+		rate:    rate,
+		maxRate: int64(maxRate),
+	}
+
+	if networksPath != "" {
+		networks, err := LoadNetworks(networksPath)
+		if err != nil {
+			return nil, err
+		}
+		d.networks = networks
+	}
+
+	return d, nil
+}
+
+// Classify сообщает, похоже ли событие на трафик бота по User-Agent или IP,
+// и причину. Частоту событий проверяет ExceedsRate.
+func (d *Detector) Classify(event *models.Event) (bool, string) {
+	if MatchUserAgent(event.UserAgent) {
+		return true, ReasonUserAgent
+	}
+
+	if d.IsDatacenterIP(event.IPAddress) {
+		return true, ReasonDatacenter
+	}
+
+	return false, ""
+}
+
+// ExceedsRate учитывает событие в частоте событий пользователя и сообщает,
+// превышен ли порог. Вызывается только для принятых событий, чтобы повторы
+// и отклоненные события не делали пользователя ботом. Ошибки счетчика не
+// мешают приему события: проверка просто пропускается.
+func (d *Detector) ExceedsRate(ctx context.Context, event *models.Event) bool {
+	if d.rate == nil || d.maxRate <= 0 || event.UserID == "" {
+		return false
+	}
+
+	count, err := d.rate.IncrementUserRate(ctx, event.ProjectID, event.UserID, rateWindow)
+	return err == nil && count > d.maxRate
+}
+
+// IsDatacenterIP проверяет, входит ли адрес в один из загруженных диапазонов
+func (d *Detector) IsDatacenterIP(ipAddress string) bool {
+	if len(d.networks) == 0 {
+		return false
+	}
+
+	ip := net.ParseIP(ipAddress)
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range d.networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchUserAgent проверяет User-Agent по сигнатурам ботов
+func MatchUserAgent(userAgent string) bool {
+	if userAgent == "" {
+		return false
+	}
+
+	ua := strings.ToLower(userAgent)
+	for _, signature := range userAgentSignatures {
+		if strings.Contains(ua, signature) {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadNetworks читает список CIDR; одиночный адрес без маски считается /32
+// (или /128 для IPv6)
+func LoadNetworks(path string) ([]*net.IPNet, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var networks []*net.IPNet
+	scanner := bufio.NewScanner(file)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		if i := strings.IndexByte(text, '#'); i >= 0 {
+			text = text[:i]
+		}
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+
+		if !strings.Contains(text, "/") {
+			if ip := net.ParseIP(text); ip != nil && ip.To4() != nil {
+				text += "/32"
+			} else {
+				text += "/128"
+			}
+		}
+
+		_, network, err := net.ParseCIDR(text)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		networks = append(networks, network)
+	}
+
+	return networks, scanner.Err()
+}
@@ -12,6 +12,7 @@ import (
 type Config struct {
     // Server
     Port        string
+    GRPCPort    string // пустое значение отключает gRPC сервер
     Environment string
     
     // ClickHouse
@@ -30,8 +31,49 @@ type Config struct {
     KafkaBroker     string
     KafkaTopic      string
     KafkaGroup      string
+    KafkaDLQTopic   string // топик событий, которые консьюмер не смог сохранить
     ConsumerWorkers int
     
+    // Повторная запись событий после временных ошибок ClickHouse
+    RetryMaxAttempts  int
+    RetryBaseDelay    time.Duration
+    RetryMaxDelay     time.Duration
+    RetryPollInterval time.Duration
+    
+    // Лаг консьюмера и пороги деградации /health (0 - не проверять)
+    ConsumerLagInterval  time.Duration
+    ConsumerMaxLag       int64
+    ConsumerMaxInsertAge time.Duration
+    
+    // Подтверждение записи брокером в синхронном режиме доставки: none, one, all
+    KafkaRequiredAcks string
+    
+    // Локальная очередь событий на время недоступности Kafka (пустой каталог отключает)
+    SpoolDir            string
+    SpoolMaxBytes       int64
+    SpoolReplayInterval time.Duration
+    
+    // Ingestion
+    DedupWindow time.Duration
+    QuotaMode   string // hard, soft
+    
+    // Окно приема времени событий после коррекции по sent_at
+    EventMaxPast    time.Duration
+    EventMaxFuture  time.Duration
+    TimestampPolicy string // clamp, reject
+    
+    // GeoIP (пустой путь отключает определение страны)
+    GeoIPDBPath         string
+    GeoIPReloadInterval time.Duration
+    
+    // Обнаружение ботов: файл с диапазонами IP дата-центров (CIDR по строке)
+    // и порог событий одного пользователя в минуту (0 отключает)
+    BotIPRangesPath       string
+    BotMaxEventsPerMinute int
+    
+    // Соль для хэширования IP и полей metadata по настройкам приватности проекта
+    PrivacyHashSalt string
+    
     // JWT
     JWTSecret        string
     JWTTokenExpiry   time.Duration
@@ -59,10 +101,25 @@ func LoadConfig() *Config {
     // Парсим длительности
     tokenExpiry, _ := time.ParseDuration(getEnv("JWT_TOKEN_EXPIRY", "24h"))
     refreshExpiry, _ := time.ParseDuration(getEnv("JWT_REFRESH_EXPIRY", "720h"))
+    dedupWindow, _ := time.ParseDuration(getEnv("DEDUP_WINDOW", "24h"))
+    eventMaxPast, _ := time.ParseDuration(getEnv("EVENT_MAX_PAST", "168h"))
+    eventMaxFuture, _ := time.ParseDuration(getEnv("EVENT_MAX_FUTURE", "10m"))
+    geoIPReloadInterval, _ := time.ParseDuration(getEnv("GEOIP_RELOAD_INTERVAL", "1m"))
+    botMaxEventsPerMinute, _ := strconv.Atoi(getEnv("BOT_MAX_EVENTS_PER_MINUTE", "120"))
+    spoolMaxBytes, _ := strconv.ParseInt(getEnv("SPOOL_MAX_BYTES", "1073741824"), 10, 64)
+    spoolReplayInterval, _ := time.ParseDuration(getEnv("SPOOL_REPLAY_INTERVAL", "5s"))
+    retryMaxAttempts, _ := strconv.Atoi(getEnv("RETRY_MAX_ATTEMPTS", "5"))
+    retryBaseDelay, _ := time.ParseDuration(getEnv("RETRY_BASE_DELAY", "1s"))
+    retryMaxDelay, _ := time.ParseDuration(getEnv("RETRY_MAX_DELAY", "5m"))
+    retryPollInterval, _ := time.ParseDuration(getEnv("RETRY_POLL_INTERVAL", "1s"))
+    consumerLagInterval, _ := time.ParseDuration(getEnv("CONSUMER_LAG_INTERVAL", "15s"))
+    consumerMaxLag, _ := strconv.ParseInt(getEnv("CONSUMER_MAX_LAG", "100000"), 10, 64)
+    consumerMaxInsertAge, _ := time.ParseDuration(getEnv("CONSUMER_MAX_INSERT_AGE", "5m"))
 
     return &Config{
         // Server
         Port:        getEnv("PORT", "8080"),
+        GRPCPort:    getEnv("GRPC_PORT", "50051"),
         Environment: getEnv("ENVIRONMENT", "development"),
         
         // ClickHouse
@@ -78,10 +135,44 @@ func LoadConfig() *Config {
         RedisDB:       redisDB,
         
         // Kafka
-        KafkaBroker:     getEnv("KAFKA_BROKER", "kafka:9092"),
-        KafkaTopic:      getEnv("KAFKA_TOPIC", "events"),
-        KafkaGroup:      getEnv("KAFKA_GROUP", "event-consumers"),
-        ConsumerWorkers: consumerWorkers,
+        KafkaBroker:       getEnv("KAFKA_BROKER", "kafka:9092"),
+        KafkaTopic:        getEnv("KAFKA_TOPIC", "events"),
+        KafkaGroup:        getEnv("KAFKA_GROUP", "event-consumers"),
+        KafkaDLQTopic:     getEnv("KAFKA_DLQ_TOPIC", "events-dlq"),
+        ConsumerWorkers:   consumerWorkers,
+        RetryMaxAttempts:  retryMaxAttempts,
+        RetryBaseDelay:    retryBaseDelay,
+        RetryMaxDelay:     retryMaxDelay,
+        RetryPollInterval: retryPollInterval,
+        ConsumerLagInterval:  consumerLagInterval,
+        ConsumerMaxLag:       consumerMaxLag,
+        ConsumerMaxInsertAge: consumerMaxInsertAge,
+        KafkaRequiredAcks: getEnv("KAFKA_REQUIRED_ACKS", "all"),
+        
+        // Spool
+        SpoolDir:            getEnv("SPOOL_DIR", "data/spool"),
+        SpoolMaxBytes:       spoolMaxBytes,
+        SpoolReplayInterval: spoolReplayInterval,
+        
+        // Ingestion
+        DedupWindow: dedupWindow,
+        QuotaMode:   getEnv("QUOTA_MODE", "hard"),
+        
+        // Timestamps
+        EventMaxPast:    eventMaxPast,
+        EventMaxFuture:  eventMaxFuture,
+        TimestampPolicy: getEnv("TIMESTAMP_POLICY", "clamp"),
+        
+        // GeoIP
+        GeoIPDBPath:         getEnv("GEOIP_DB_PATH", ""),
+        GeoIPReloadInterval: geoIPReloadInterval,
+        
+        // Bots
+        BotIPRangesPath:       getEnv("BOT_IP_RANGES_PATH", ""),
+        BotMaxEventsPerMinute: botMaxEventsPerMinute,
+        
+        // Privacy
+        PrivacyHashSalt: getEnv("PRIVACY_HASH_SALT", "your-privacy-hash-salt-change-in-production"),
         
         // JWT
         JWTSecret:        getEnv("JWT_SECRET", "your-super-secret-jwt-key-change-in-production"),
@@ -4,23 +4,78 @@ import (
 	"context"
 	"encoding/json"
 	"log"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/segmentio/kafka-go"
+	"github.com/yourusername/event-analytics-service/internal/dlq"
+	"github.com/yourusername/event-analytics-service/internal/geoip"
 	"github.com/yourusername/event-analytics-service/internal/metrics"
 	"github.com/yourusername/event-analytics-service/internal/models"
 	"github.com/yourusername/event-analytics-service/internal/repository"
 )
 
+// Transformer применяет правила проектов к событиям и возвращает те, которые нужно сохранить
+type Transformer interface {
+	Apply(ctx context.Context, events []*models.Event) []*models.Event
+}
+
+// messageReader читает и подтверждает сообщения топика событий (kafka.Reader)
+type messageReader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// deadLetterWriter пишет события и сообщения в dead-letter топик (dlq.Writer)
+type deadLetterWriter interface {
+	PublishEvents(ctx context.Context, events []*models.Event, reason string, cause error) error
+	PublishRaw(ctx context.Context, msg kafka.Message, reason string, cause error) error
+	Close() error
+}
+
+// Пауза между повторами передачи батча, если ни ClickHouse, ни очередь
+// повторов, ни dead-letter топик недоступны
+const (
+	handOffMinDelay = 100 * time.Millisecond
+	handOffMaxDelay = 30 * time.Second
+)
+
+// EventConsumer читает события из Kafka и подтверждает offset'ы только после
+// того, как батч записан в ClickHouse или передан в очередь повторов или
+// dead-letter топик (at-least-once). Kafka подтверждает offset партиции
+// целиком, поэтому сообщения одной партиции всегда обрабатывает один и тот
+// же воркер, по порядку: подтверждение следующего батча не может обогнать
+// необработанный предыдущий.
 type EventConsumer struct {
-	reader    *kafka.Reader
-	eventRepo repository.EventRepository
-	redisRepo *repository.RedisRepository
-	workers   int
-	stopChan  chan struct{}
-	wg        sync.WaitGroup
-	metrics   *metrics.Metrics
+	reader     messageReader
+	client     *kafka.Client
+	topic      string
+	groupID    string
+	eventRepo  repository.EventRepository
+	redisRepo  *repository.RedisRepository
+	geo        *geoip.Resolver
+	transforms Transformer
+	dlq        deadLetterWriter
+	retry      RetryPolicy
+	workers    int
+	stopChan   chan struct{}
+	ctx        context.Context
+	cancel     context.CancelFunc
+	wg         sync.WaitGroup
+	metrics    *metrics.Metrics
+
+	// Состояние для /health
+	healthConfig   HealthConfig
+	mu             sync.RWMutex
+	startedAt      time.Time
+	lastInsertAt   time.Time
+	insertFailedAt time.Time
+	retryDepth     int64
+	partitionLag   map[int]int64
+	lagCheckedAt   time.Time
+	lagErr         error
 }
 
 func NewEventConsumer(
@@ -29,6 +84,11 @@ func NewEventConsumer(
 	groupID string,
 	eventRepo repository.EventRepository,
 	redisRepo *repository.RedisRepository,
+	geo *geoip.Resolver,
+	transforms Transformer,
+	deadLetters *dlq.Writer,
+	retry RetryPolicy,
+	healthConfig HealthConfig,
 	workers int,
 	metrics *metrics.Metrics,
 ) *EventConsumer {
@@ -40,125 +100,330 @@ func NewEventConsumer(
 		MaxBytes:        10e6, // 10MB
 		MaxWait:         1 * time.Second,
 		ReadLagInterval: -1,
-		CommitInterval:  time.Second,
-		StartOffset:     kafka.LastOffset,
+		// Подтвержденные offset'ы отправляются брокеру раз в секунду и при
+		// закрытии reader; после падения часть событий может быть прочитана повторно
+		CommitInterval: time.Second,
+		StartOffset:    kafka.LastOffset,
 	})
 
+	if workers < 1 {
+		workers = 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
 	return &EventConsumer{
-		reader:    reader,
-		eventRepo: eventRepo,
-		redisRepo: redisRepo,
-		workers:   workers,
-		stopChan:  make(chan struct{}),
-		metrics:   metrics,
+		reader:     reader,
+		client:     &kafka.Client{Addr: kafka.TCP(brokers...), Timeout: 10 * time.Second},
+		topic:      topic,
+		groupID:    groupID,
+		eventRepo:  eventRepo,
+		redisRepo:  redisRepo,
+		geo:        geo,
+		transforms: transforms,
+		dlq:        deadLetters,
+		retry:      retry,
+		workers:    workers,
+		stopChan:   make(chan struct{}),
+		ctx:        ctx,
+		cancel:     cancel,
+		metrics:    metrics,
+
+		healthConfig: healthConfig,
+		partitionLag: make(map[int]int64),
 	}
 }
 
 func (c *EventConsumer) Start() {
 	log.Printf("Starting Kafka consumer with %d workers", c.workers)
+	c.mu.Lock()
+	c.startedAt = time.Now()
+	c.mu.Unlock()
+
+	queues := make([]chan kafka.Message, c.workers)
+	for i := range queues {
+		queues[i] = make(chan kafka.Message, 100)
 
-	for i := 0; i < c.workers; i++ {
 		c.wg.Add(1)
-		go c.worker(i)
+		go c.worker(i, queues[i])
+	}
+
+	c.wg.Add(1)
+	go c.fetchLoop(queues)
+
+	if c.retry.enabled() {
+		c.wg.Add(1)
+		go c.retryLoop()
+	}
+
+	c.wg.Add(1)
+	go c.lagLoop()
+}
+
+// fetchLoop читает сообщения и раздает их воркерам по номеру партиции.
+// При остановке закрывает очереди воркеров, чтобы они обработали остаток.
+func (c *EventConsumer) fetchLoop(queues []chan kafka.Message) {
+	defer c.wg.Done()
+	defer func() {
+		for _, queue := range queues {
+			close(queue)
+		}
+	}()
+
+	for {
+		msg, err := c.reader.FetchMessage(c.ctx)
+		if err != nil {
+			if c.ctx.Err() != nil {
+				return
+			}
+			log.Printf("Error fetching message: %v", err)
+			select {
+			case <-c.stopChan:
+				return
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		select {
+		case queues[msg.Partition%len(queues)] <- msg:
+		case <-c.stopChan:
+			return
+		}
 	}
 }
 
-func (c *EventConsumer) worker(id int) {
+func (c *EventConsumer) worker(id int, messages <-chan kafka.Message) {
 	defer c.wg.Done()
 	log.Printf("Worker %d started", id)
 
 	batchSize := 100
-	batch := make([]*models.Event, 0, batchSize)
+	batch := make([]kafka.Message, 0, batchSize)
 	batchTicker := time.NewTicker(100 * time.Millisecond)
 	defer batchTicker.Stop()
 
 	for {
 		select {
-		case <-c.stopChan:
-			// Финальная обработка батча перед остановкой
-			if len(batch) > 0 {
+		case msg, ok := <-messages:
+			if !ok {
+				// Финальная обработка батча перед остановкой
+				if len(batch) > 0 {
+					c.processBatch(batch)
+				}
+				log.Printf("Worker %d stopped", id)
+				return
+			}
+
+			batch = append(batch, msg)
+			c.metrics.Increment("consumer.events.received")
+			c.metrics.IncrementKafkaMessagesConsumed(msg.Topic, strconv.Itoa(msg.Partition))
+
+			if len(batch) >= batchSize {
 				c.processBatch(batch)
+				batch = make([]kafka.Message, 0, batchSize)
 			}
-			log.Printf("Worker %d stopped", id)
-			return
 
 		case <-batchTicker.C:
 			if len(batch) > 0 {
 				c.processBatch(batch)
-				batch = make([]*models.Event, 0, batchSize)
+				batch = make([]kafka.Message, 0, batchSize)
 			}
+		}
+	}
+}
 
-		default:
-			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-			msg, err := c.reader.ReadMessage(ctx)
-			cancel()
+// processBatch сохраняет события батча и подтверждает его сообщения. Если
+// консьюмер остановлен раньше, чем батч удалось передать, сообщения не
+// подтверждаются и будут прочитаны заново после перезапуска.
+func (c *EventConsumer) processBatch(messages []kafka.Message) {
+	startTime := time.Now()
 
-			if err != nil {
-				if err != context.DeadlineExceeded {
-					log.Printf("Worker %d error reading message: %v", id, err)
-				}
-				continue
+	events := make([]*models.Event, 0, len(messages))
+	for _, msg := range messages {
+		event, err := decodeEvent(msg)
+		if err != nil {
+			log.Printf("Failed to unmarshal event %d/%d: %v", msg.Partition, msg.Offset, err)
+			if !c.untilStopped(func() error { return c.deadLetterRaw(msg, err) }) {
+				return
 			}
+			continue
+		}
+		events = append(events, event)
+	}
 
-			var event models.Event
-			if err := json.Unmarshal(msg.Value, &event); err != nil {
-				log.Printf("Worker %d failed to unmarshal event: %v", id, err)
-				c.metrics.Increment("consumer.errors.unmarshal")
-				continue
-			}
+	events = c.prepareBatch(events)
+	if len(events) > 0 {
+		if !c.untilStopped(func() error { return c.storeBatch(events) }) {
+			log.Printf("Consumer stopped before batch of %d events was stored, leaving it uncommitted", len(events))
+			return
+		}
+	}
 
-			// Добавляем метаданные из Kafka
-			event.KafkaMetadata.Offset = msg.Offset
-			event.KafkaMetadata.Partition = msg.Partition
-			event.KafkaMetadata.ConsumedAt = time.Now()
+	c.commit(messages)
+	if len(events) == 0 {
+		return
+	}
 
-			batch = append(batch, &event)
-			c.metrics.Increment("consumer.events.received")
+	// Обновляем метрики
+	duration := time.Since(startTime)
+	c.metrics.Timing("consumer.batch.processing_time", duration)
+	c.metrics.IncrementBy("consumer.events.processed", int64(len(events)))
 
-			if len(batch) >= batchSize {
-				c.processBatch(batch)
-				batch = make([]*models.Event, 0, batchSize)
-			}
-		}
+	log.Printf("Processed batch of %d events in %v", len(events), duration)
+}
+
+func decodeEvent(msg kafka.Message) (*models.Event, error) {
+	var event models.Event
+	if err := json.Unmarshal(msg.Value, &event); err != nil {
+		return nil, err
+	}
+
+	// События, опубликованные до появления received_at
+	if event.ReceivedAt.IsZero() {
+		event.ReceivedAt = event.Timestamp
 	}
+	if event.SampleRate <= 0 {
+		event.SampleRate = 1
+	}
+
+	// Добавляем метаданные из Kafka
+	event.KafkaMetadata.Topic = msg.Topic
+	event.KafkaMetadata.Offset = msg.Offset
+	event.KafkaMetadata.Partition = msg.Partition
+	event.KafkaMetadata.ProducedAt = msg.Time
+	event.KafkaMetadata.ConsumedAt = time.Now()
+
+	return &event, nil
 }
 
-func (c *EventConsumer) processBatch(events []*models.Event) {
-	startTime := time.Now()
+// prepareBatch обогащает события и применяет правила проектов; выполняется
+// один раз, даже если сохранение батча приходится повторять
+func (c *EventConsumer) prepareBatch(events []*models.Event) []*models.Event {
+	c.enrichLocation(events)
+
+	// Правила проекта применяются после обогащения, чтобы фильтры видели итоговые данные
+	if c.transforms != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		events = c.transforms.Apply(ctx, events)
+	}
+
+	return events
+}
 
+// storeBatch записывает батч в ClickHouse, а при ошибке передает его в очередь
+// повторов или dead-letter топик. Ошибка означает, что события никуда не сохранены.
+func (c *EventConsumer) storeBatch(events []*models.Event) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	// Пытаемся вставить батч в ClickHouse
 	if err := c.eventRepo.InsertEventBatch(ctx, events); err != nil {
 		log.Printf("Failed to insert event batch: %v", err)
-		c.metrics.Increment("consumer.errors.insert")
-
-		// Сохраняем в Redis для повторной обработки
-		c.saveFailedBatch(events)
-		return
+		c.metrics.IncrementDBError("insert", "events")
+		c.markInsertFailed()
+		return c.handleInsertFailure(events, err)
 	}
 
+	c.markInserted()
+	c.observeLatency(events)
+
 	// Обновляем кэш в Redis
 	c.updateCache(events)
+	return nil
+}
 
-	// Обновляем метрики
-	duration := time.Since(startTime)
-	c.metrics.Timing("consumer.batch.processing_time", duration)
-	c.metrics.IncrementBy("consumer.events.processed", int64(len(events)))
+// observeLatency учитывает задержку от приема события API до записи в ClickHouse
+func (c *EventConsumer) observeLatency(events []*models.Event) {
+	now := time.Now()
+	for _, event := range events {
+		c.metrics.ObserveIngestionLatency(event.ProjectID, now.Sub(event.ReceivedAt))
+	}
+}
 
-	log.Printf("Processed batch of %d events in %v", len(events), duration)
+// untilStopped повторяет fn с растущей паузой, пока она не выполнится успешно.
+// Возвращает false, если консьюмер остановлен раньше: следующие сообщения
+// партиции нельзя подтверждать, пока не сохранены предыдущие.
+func (c *EventConsumer) untilStopped(fn func() error) bool {
+	delay := handOffMinDelay
+	for {
+		err := fn()
+		if err == nil {
+			return true
+		}
+
+		log.Printf("Batch hand-off failed, retrying in %v: %v", delay, err)
+		select {
+		case <-c.stopChan:
+			return false
+		case <-time.After(delay):
+		}
+
+		if delay *= 2; delay > handOffMaxDelay {
+			delay = handOffMaxDelay
+		}
+	}
 }
 
-func (c *EventConsumer) saveFailedBatch(events []*models.Event) {
-	ctx := context.Background()
-	key := "failed_events:" + time.Now().Format("20060102")
+// commit подтверждает сообщения батча; Kafka запоминает для каждой партиции
+// offset последнего из них
+func (c *EventConsumer) commit(messages []kafka.Message) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := c.reader.CommitMessages(ctx, messages...); err != nil {
+		// Например, партиция ушла другому консьюмеру при ребалансировке:
+		// он прочитает сообщения повторно
+		log.Printf("Failed to commit %d messages: %v", len(messages), err)
+	}
+}
+
+// enrichLocation определяет страну и регион по IP адресу, если они еще не заполнены
+func (c *EventConsumer) enrichLocation(events []*models.Event) {
+	if c.geo == nil {
+		return
+	}
 
 	for _, event := range events {
-		data, _ := json.Marshal(event)
-		c.redisRepo.Client.RPush(ctx, key, data)
+		if event.CountryCode != "" || event.IPAddress == "" {
+			continue
+		}
+		if loc, ok := c.geo.Lookup(event.IPAddress); ok {
+			event.CountryCode = loc.CountryCode
+			event.Region = loc.Region
+		}
+	}
+}
+
+// deadLetter отправляет события, которые не удалось сохранить, в dead-letter
+// топик. Ошибка означает, что события не переданы и батч нельзя подтверждать.
+func (c *EventConsumer) deadLetter(events []*models.Event, reason string, cause error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := c.dlq.PublishEvents(ctx, events, reason, cause); err != nil {
+		log.Printf("Failed to write %d events to dead-letter topic: %v", len(events), err)
+		c.metrics.AddDLQPublishErrors(len(events))
+		return err
 	}
-	c.redisRepo.Client.Expire(ctx, key, 24*time.Hour)
+
+	c.metrics.AddDLQMessages(reason, len(events))
+	return nil
+}
+
+// deadLetterRaw отправляет в dead-letter топик сообщение, которое не удалось разобрать
+func (c *EventConsumer) deadLetterRaw(msg kafka.Message, cause error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := c.dlq.PublishRaw(ctx, msg, dlq.ReasonUnmarshalFailed, cause); err != nil {
+		log.Printf("Failed to write message %d/%d to dead-letter topic: %v", msg.Partition, msg.Offset, err)
+		c.metrics.AddDLQPublishErrors(1)
+		return err
+	}
+
+	c.metrics.AddDLQMessages(dlq.ReasonUnmarshalFailed, 1)
+	return nil
 }
 
 func (c *EventConsumer) updateCache(events []*models.Event) {
@@ -180,7 +445,10 @@ func (c *EventConsumer) updateCache(events []*models.Event) {
 func (c *EventConsumer) Stop() {
 	log.Println("Stopping Kafka consumer...")
 	close(c.stopChan)
+	c.cancel()
 	c.wg.Wait()
+	// Reader при закрытии отправляет брокеру последние подтвержденные offset'ы
 	c.reader.Close()
+	c.dlq.Close()
 	log.Println("Kafka consumer stopped")
 }
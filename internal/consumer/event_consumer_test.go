@@ -0,0 +1,235 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/event-analytics-service/internal/metrics"
+	"github.com/yourusername/event-analytics-service/internal/models"
+	"github.com/yourusername/event-analytics-service/internal/repository"
+)
+
+var testMetrics = metrics.NewMetrics("consumer_test")
+
+// fakeReader выдает заданные сообщения и запоминает подтвержденные
+type fakeReader struct {
+	messages chan kafka.Message
+
+	mu        sync.Mutex
+	committed []kafka.Message
+}
+
+func newFakeReader(messages ...kafka.Message) *fakeReader {
+	r := &fakeReader{messages: make(chan kafka.Message, len(messages))}
+	for _, msg := range messages {
+		r.messages <- msg
+	}
+	return r
+}
+
+func (r *fakeReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	select {
+	case msg := <-r.messages:
+		return msg, nil
+	case <-ctx.Done():
+		return kafka.Message{}, ctx.Err()
+	}
+}
+
+func (r *fakeReader) CommitMessages(ctx context.Context, msgs ...kafka.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.committed = append(r.committed, msgs...)
+	return nil
+}
+
+func (r *fakeReader) Close() error { return nil }
+
+// Committed возвращает подтвержденные offset'ы по партициям в порядке подтверждения
+func (r *fakeReader) Committed() map[int][]int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	offsets := make(map[int][]int64)
+	for _, msg := range r.committed {
+		offsets[msg.Partition] = append(offsets[msg.Partition], msg.Offset)
+	}
+	return offsets
+}
+
+// fakeEventRepo записывает батчи, пока fail не вернет ошибку
+type fakeEventRepo struct {
+	repository.EventRepository
+	fail func(events []*models.Event) error
+
+	mu       sync.Mutex
+	inserted []*models.Event
+}
+
+func (r *fakeEventRepo) InsertEventBatch(ctx context.Context, events []*models.Event) error {
+	if err := r.fail(events); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.inserted = append(r.inserted, events...)
+	return nil
+}
+
+// fakeDLQ принимает события, начиная с попытки failures+1
+type fakeDLQ struct {
+	mu        sync.Mutex
+	failures  int
+	attempts  int
+	published []*models.Event
+}
+
+func (w *fakeDLQ) PublishEvents(ctx context.Context, events []*models.Event, reason string, cause error) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.attempts++
+	if w.attempts <= w.failures {
+		return errors.New("dlq unavailable")
+	}
+	w.published = append(w.published, events...)
+	return nil
+}
+
+func (w *fakeDLQ) PublishRaw(ctx context.Context, msg kafka.Message, reason string, cause error) error {
+	return w.PublishEvents(ctx, nil, reason, cause)
+}
+
+func (w *fakeDLQ) Close() error { return nil }
+
+func (w *fakeDLQ) Published() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.published)
+}
+
+func newTestConsumer(reader *fakeReader, repo *fakeEventRepo, deadLetters *fakeDLQ, workers int) *EventConsumer {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &EventConsumer{
+		reader: reader,
+		// Недоступный Redis: обновление кэша после записи сразу завершается ошибкой
+		redisRepo:    &repository.RedisRepository{Client: redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})},
+		eventRepo:    repo,
+		dlq:          deadLetters,
+		workers:      workers,
+		stopChan:     make(chan struct{}),
+		ctx:          ctx,
+		cancel:       cancel,
+		metrics:      testMetrics,
+		partitionLag: make(map[int]int64),
+	}
+}
+
+func eventMessage(t *testing.T, partition int, offset int64) kafka.Message {
+	t.Helper()
+	value, err := json.Marshal(&models.Event{ID: "evt", ProjectID: "project-1", EventType: models.PageView})
+	require.NoError(t, err)
+	return kafka.Message{Topic: "events", Partition: partition, Offset: offset, Value: value}
+}
+
+func TestBatchCommittedAfterDeadLetter(t *testing.T) {
+	reader := newFakeReader()
+	repo := &fakeEventRepo{fail: func([]*models.Event) error { return errors.New("type mismatch") }}
+	deadLetters := &fakeDLQ{failures: 2}
+	c := newTestConsumer(reader, repo, deadLetters, 1)
+
+	done := make(chan struct{})
+	go func() {
+		c.processBatch([]kafka.Message{eventMessage(t, 0, 1), eventMessage(t, 0, 2)})
+		close(done)
+	}()
+
+	// Пока dead-letter топик недоступен, батч не подтверждается
+	time.Sleep(50 * time.Millisecond)
+	assert.Empty(t, reader.Committed())
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("batch was not handed off")
+	}
+	assert.Equal(t, 2, deadLetters.Published())
+	assert.Equal(t, map[int][]int64{0: {1, 2}}, reader.Committed())
+}
+
+func TestBatchNotCommittedWhenStoppedBeforeHandOff(t *testing.T) {
+	reader := newFakeReader()
+	repo := &fakeEventRepo{fail: func([]*models.Event) error { return errors.New("type mismatch") }}
+	c := newTestConsumer(reader, repo, &fakeDLQ{failures: 1 << 30}, 1)
+
+	done := make(chan struct{})
+	go func() {
+		c.processBatch([]kafka.Message{eventMessage(t, 0, 1)})
+		close(done)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	close(c.stopChan)
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("processBatch did not return after stop")
+	}
+	assert.Empty(t, reader.Committed())
+}
+
+func TestOffsetsCommittedInPartitionOrder(t *testing.T) {
+	var messages []kafka.Message
+	for offset := int64(0); offset < 5; offset++ {
+		messages = append(messages, eventMessage(t, 0, offset), eventMessage(t, 1, offset))
+	}
+	reader := newFakeReader(messages...)
+
+	// Первые попытки записи событий партиции 0 не удаются, и dead-letter
+	// топик тоже сначала недоступен
+	var mu sync.Mutex
+	failures := 0
+	repo := &fakeEventRepo{fail: func(events []*models.Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if events[0].KafkaMetadata.Partition == 0 && failures < 2 {
+			failures++
+			return errors.New("type mismatch")
+		}
+		return nil
+	}}
+	deadLetters := &fakeDLQ{failures: 1}
+	c := newTestConsumer(reader, repo, deadLetters, 2)
+
+	queues := make([]chan kafka.Message, c.workers)
+	for i := range queues {
+		queues[i] = make(chan kafka.Message, 100)
+		c.wg.Add(1)
+		go c.worker(i, queues[i])
+	}
+	c.wg.Add(1)
+	go c.fetchLoop(queues)
+
+	assert.Eventually(t, func() bool {
+		committed := reader.Committed()
+		return len(committed[0]) == 5 && len(committed[1]) == 5
+	}, 3*time.Second, 10*time.Millisecond)
+
+	close(c.stopChan)
+	c.cancel()
+	c.wg.Wait()
+
+	for partition, offsets := range reader.Committed() {
+		assert.Equal(t, []int64{0, 1, 2, 3, 4}, offsets, "partition %d", partition)
+	}
+	assert.Equal(t, 10, len(repo.inserted)+deadLetters.Published())
+}
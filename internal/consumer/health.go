@@ -0,0 +1,243 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Статусы /health консьюмера
+const (
+	HealthStatusOK       = "ok"
+	HealthStatusDegraded = "degraded"
+)
+
+// HealthConfig - как часто считается лаг группы и после каких порогов
+// консьюмер считается деградировавшим; нулевой порог не проверяется
+type HealthConfig struct {
+	LagInterval time.Duration
+	// Суммарный лаг по всем партициям
+	MaxLag int64
+	// Время с последней успешной записи в ClickHouse; проверяется, только
+	// если есть что записывать (лаг, очередь повторов или неудачная запись
+	// после последней успешной), чтобы простой без трафика не считался деградацией
+	MaxInsertAge time.Duration
+}
+
+// Health - состояние консьюмера для /health
+type Health struct {
+	Status       string        `json:"status"`
+	Reasons      []string      `json:"reasons,omitempty"`
+	Lag          int64         `json:"lag"`
+	PartitionLag map[int]int64 `json:"partition_lag"`
+	LagCheckedAt time.Time     `json:"lag_checked_at"`
+	LastInsertAt time.Time     `json:"last_insert_at"`
+	RetryDepth   int64         `json:"retry_depth"`
+}
+
+// Health возвращает последний рассчитанный лаг и проверяет пороги HealthConfig
+func (c *EventConsumer) Health() Health {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	health := Health{
+		Status:       HealthStatusOK,
+		PartitionLag: make(map[int]int64, len(c.partitionLag)),
+		LagCheckedAt: c.lagCheckedAt,
+		LastInsertAt: c.lastInsertAt,
+		RetryDepth:   c.retryDepth,
+	}
+	for partition, lag := range c.partitionLag {
+		health.PartitionLag[partition] = lag
+		health.Lag += lag
+	}
+
+	if c.lagErr != nil {
+		health.Reasons = append(health.Reasons, "lag check failed: "+c.lagErr.Error())
+	}
+	if c.healthConfig.MaxLag > 0 && health.Lag > c.healthConfig.MaxLag {
+		health.Reasons = append(health.Reasons, fmt.Sprintf("lag %d exceeds %d", health.Lag, c.healthConfig.MaxLag))
+	}
+
+	// До первой записи отсчет идет от запуска консьюмера
+	lastInsert := c.lastInsertAt
+	if lastInsert.IsZero() {
+		lastInsert = c.startedAt
+	}
+	// Батчи, ушедшие в очередь повторов или dead-letter топик, подтверждаются,
+	// поэтому при постоянных ошибках записи лаг может оставаться нулевым
+	pending := health.Lag > 0 || c.retryDepth > 0 || c.insertFailedAt.After(lastInsert)
+	if age := time.Since(lastInsert); c.healthConfig.MaxInsertAge > 0 && pending && age > c.healthConfig.MaxInsertAge {
+		health.Reasons = append(health.Reasons, fmt.Sprintf("no successful insert for %s", age.Round(time.Second)))
+	}
+
+	if len(health.Reasons) > 0 {
+		health.Status = HealthStatusDegraded
+	}
+	return health
+}
+
+// HealthHandler отдает Health в JSON. Со strict статус degraded отвечает 503
+// (готовность, алерты); без него ответ всегда 200, чтобы проверка живости не
+// перезапускала консьюмер, который догоняет лаг после простоя.
+func (c *EventConsumer) HealthHandler(strict bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		health := c.Health()
+
+		w.Header().Set("Content-Type", "application/json")
+		if strict && health.Status != HealthStatusOK {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(health)
+	}
+}
+
+// markInserted запоминает время успешной записи в ClickHouse
+func (c *EventConsumer) markInserted() {
+	c.mu.Lock()
+	c.lastInsertAt = time.Now()
+	c.mu.Unlock()
+}
+
+// markInsertFailed запоминает время неудачной записи в ClickHouse
+func (c *EventConsumer) markInsertFailed() {
+	c.mu.Lock()
+	c.insertFailedAt = time.Now()
+	c.mu.Unlock()
+}
+
+// setRetryDepth запоминает число записей в очереди повторов
+func (c *EventConsumer) setRetryDepth(depth int64) {
+	c.mu.Lock()
+	c.retryDepth = depth
+	c.mu.Unlock()
+}
+
+// lagLoop периодически считает лаг группы и экспортирует его по партициям
+func (c *EventConsumer) lagLoop() {
+	defer c.wg.Done()
+
+	interval := c.healthConfig.LagInterval
+	if interval <= 0 {
+		interval = 15 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	c.updateLag()
+
+	for {
+		select {
+		case <-c.stopChan:
+			return
+		case <-ticker.C:
+			c.updateLag()
+		}
+	}
+}
+
+func (c *EventConsumer) updateLag() {
+	ctx, cancel := context.WithTimeout(c.ctx, 10*time.Second)
+	defer cancel()
+
+	lag, err := c.fetchLag(ctx)
+
+	c.mu.Lock()
+	c.lagErr = err
+	if err == nil {
+		c.partitionLag = lag
+		c.lagCheckedAt = time.Now()
+	}
+	c.mu.Unlock()
+
+	if err != nil {
+		if c.ctx.Err() == nil {
+			log.Printf("Failed to compute consumer lag: %v", err)
+		}
+		return
+	}
+
+	for partition, value := range lag {
+		c.metrics.SetKafkaConsumerLag(c.topic, strconv.Itoa(partition), float64(value))
+	}
+}
+
+// fetchLag возвращает лаг группы по партициям топика: разницу между high
+// watermark и подтвержденным offset'ом. Партиции, для которых группа еще
+// ничего не подтвердила, читаются с конца (StartOffset), их лаг считается нулевым.
+func (c *EventConsumer) fetchLag(ctx context.Context) (map[int]int64, error) {
+	metadata, err := c.client.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{c.topic}})
+	if err != nil {
+		return nil, err
+	}
+	if len(metadata.Topics) == 0 {
+		return nil, fmt.Errorf("topic %s not found", c.topic)
+	}
+	topic := metadata.Topics[0]
+	if topic.Error != nil {
+		return nil, topic.Error
+	}
+
+	partitions := make([]int, 0, len(topic.Partitions))
+	requests := make([]kafka.OffsetRequest, 0, len(topic.Partitions))
+	for _, partition := range topic.Partitions {
+		partitions = append(partitions, partition.ID)
+		requests = append(requests, kafka.LastOffsetOf(partition.ID))
+	}
+
+	offsets, err := c.client.ListOffsets(ctx, &kafka.ListOffsetsRequest{
+		Topics: map[string][]kafka.OffsetRequest{c.topic: requests},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	committed, err := c.client.OffsetFetch(ctx, &kafka.OffsetFetchRequest{
+		GroupID: c.groupID,
+		Topics:  map[string][]int{c.topic: partitions},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if committed.Error != nil {
+		return nil, committed.Error
+	}
+
+	commits := make(map[int]int64, len(partitions))
+	for _, partition := range committed.Topics[c.topic] {
+		if partition.Error != nil {
+			return nil, partition.Error
+		}
+		commits[partition.Partition] = partition.CommittedOffset
+	}
+
+	lag := make(map[int]int64, len(partitions))
+	for _, partition := range offsets.Topics[c.topic] {
+		if partition.Error != nil {
+			return nil, partition.Error
+		}
+
+		offset, ok := commits[partition.Partition]
+		if !ok || offset < 0 {
+			lag[partition.Partition] = 0
+			continue
+		}
+		if behind := partition.LastOffset - offset; behind > 0 {
+			lag[partition.Partition] = behind
+		} else {
+			lag[partition.Partition] = 0
+		}
+	}
+	if len(lag) != len(partitions) {
+		return nil, errors.New("incomplete partition offsets")
+	}
+
+	return lag, nil
+}
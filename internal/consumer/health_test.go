@@ -0,0 +1,97 @@
+package consumer
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/event-analytics-service/internal/models"
+)
+
+func newHealthConsumer(maxInsertAge time.Duration) *EventConsumer {
+	c := newTestConsumer(newFakeReader(), &fakeEventRepo{}, &fakeDLQ{}, 1)
+	c.healthConfig = HealthConfig{MaxInsertAge: maxInsertAge}
+	c.startedAt = time.Now().Add(-time.Hour)
+	return c
+}
+
+func TestHealthIdleWithoutPendingWork(t *testing.T) {
+	c := newHealthConsumer(time.Minute)
+
+	// Без трафика давняя запись не считается деградацией
+	health := c.Health()
+	assert.Equal(t, HealthStatusOK, health.Status)
+	assert.Empty(t, health.Reasons)
+}
+
+func TestHealthDegradedWithLag(t *testing.T) {
+	c := newHealthConsumer(time.Minute)
+	c.partitionLag = map[int]int64{0: 3, 1: 2}
+
+	health := c.Health()
+	assert.Equal(t, HealthStatusDegraded, health.Status)
+	assert.Equal(t, int64(5), health.Lag)
+}
+
+func TestHealthDegradedWithRetryQueue(t *testing.T) {
+	c := newHealthConsumer(time.Minute)
+
+	// Лаг нулевой: батчи подтверждаются после передачи в очередь повторов
+	c.setRetryDepth(10)
+	health := c.Health()
+	assert.Equal(t, HealthStatusDegraded, health.Status)
+	assert.Equal(t, int64(10), health.RetryDepth)
+
+	c.setRetryDepth(0)
+	assert.Equal(t, HealthStatusOK, c.Health().Status)
+}
+
+func TestHealthDegradedAfterFailedInsert(t *testing.T) {
+	c := newHealthConsumer(time.Minute)
+	c.eventRepo = &fakeEventRepo{fail: func([]*models.Event) error { return errors.New("type mismatch") }}
+
+	// Неудачная запись уходит в dead-letter топик и подтверждается
+	assert.NoError(t, c.storeBatch([]*models.Event{{ID: "evt-1", ProjectID: "project-1"}}))
+	assert.Equal(t, int64(0), c.Health().Lag)
+	assert.Equal(t, HealthStatusDegraded, c.Health().Status)
+
+	// Успешная запись возвращает статус ok
+	c.eventRepo = &fakeEventRepo{fail: func([]*models.Event) error { return nil }}
+	assert.NoError(t, c.storeBatch([]*models.Event{{ID: "evt-2", ProjectID: "project-1"}}))
+	assert.Equal(t, HealthStatusOK, c.Health().Status)
+}
+
+func TestHealthRecentInsertIsOK(t *testing.T) {
+	c := newHealthConsumer(time.Minute)
+	c.partitionLag = map[int]int64{0: 3}
+	c.setRetryDepth(1)
+	c.markInserted()
+
+	assert.Equal(t, HealthStatusOK, c.Health().Status)
+}
+
+func TestHealthHandler(t *testing.T) {
+	c := newHealthConsumer(time.Minute)
+	c.partitionLag = map[int]int64{0: 3}
+
+	for strict, code := range map[bool]int{false: http.StatusOK, true: http.StatusServiceUnavailable} {
+		w := httptest.NewRecorder()
+		c.HealthHandler(strict)(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+		assert.Equal(t, code, w.Code, "strict=%v", strict)
+
+		var health Health
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &health))
+		assert.Equal(t, HealthStatusDegraded, health.Status)
+		assert.Equal(t, int64(3), health.Lag)
+	}
+
+	// Без деградации обе проверки отвечают 200
+	c.markInserted()
+	w := httptest.NewRecorder()
+	c.HealthHandler(true)(w, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,295 @@
+package consumer
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"log"
+	"math/rand"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/google/uuid"
+	"github.com/yourusername/event-analytics-service/internal/dlq"
+	"github.com/yourusername/event-analytics-service/internal/models"
+)
+
+const (
+	// Сколько записей очереди повторно записывается одним батчем
+	retryBatchSize = 500
+	// Через сколько запись, выданная обработчику, снова становится доступной,
+	// если обработчик не завершил ее (например, упал)
+	retryLease = time.Minute
+)
+
+// Коды исключений ClickHouse, после которых запись имеет смысл повторить:
+// перегрузка, таймауты и сетевые ошибки на стороне сервера
+var transientExceptionCodes = map[int32]bool{
+	159: true, // TIMEOUT_EXCEEDED
+	164: true, // READONLY
+	202: true, // TOO_MANY_SIMULTANEOUS_QUERIES
+	203: true, // NO_FREE_CONNECTION
+	209: true, // SOCKET_TIMEOUT
+	210: true, // NETWORK_ERROR
+	241: true, // MEMORY_LIMIT_EXCEEDED
+	242: true, // TABLE_IS_READ_ONLY
+	252: true, // TOO_MANY_PARTS
+	425: true, // SYSTEM_ERROR
+}
+
+// RetryPolicy - параметры повторной записи событий после временных ошибок ClickHouse
+type RetryPolicy struct {
+	// Максимум попыток записи, включая первую; при значении меньше 2 повторов нет
+	MaxAttempts int
+	// Задержка перед первым повтором, удваивается с каждой попыткой до MaxDelay
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Как часто проверяется очередь повторов
+	PollInterval time.Duration
+}
+
+func (p RetryPolicy) enabled() bool {
+	return p.MaxAttempts > 1 && p.BaseDelay > 0
+}
+
+// Backoff возвращает задержку перед следующей попыткой после attempt неудачных:
+// экспоненциальная задержка со случайным разбросом в ее второй половине, чтобы
+// повторы разных консьюмеров не приходили одновременно
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	maxDelay := p.MaxDelay
+	if maxDelay < p.BaseDelay {
+		maxDelay = p.BaseDelay
+	}
+
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	if delay <= 1 {
+		return delay
+	}
+
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(delay-half)))
+}
+
+// IsTransient сообщает, имеет ли смысл повторить запись после ошибки: сетевые
+// ошибки, таймауты и перегрузка ClickHouse повторяются, ошибки в данных и схеме - нет
+func IsTransient(err error) bool {
+	var exception *clickhouse.Exception
+	if errors.As(err, &exception) {
+		return transientExceptionCodes[exception.Code]
+	}
+
+	var netErr net.Error
+	switch {
+	case errors.As(err, &netErr),
+		errors.Is(err, context.DeadlineExceeded),
+		errors.Is(err, io.EOF),
+		errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.ECONNREFUSED),
+		errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.EPIPE),
+		errors.Is(err, driver.ErrBadConn),
+		errors.Is(err, clickhouse.ErrAcquireConnTimeout):
+		return true
+	}
+
+	return false
+}
+
+// handleInsertFailure ставит события в очередь повторов, если ошибка временная,
+// иначе (или если очередь недоступна) отправляет их в dead-letter топик
+func (c *EventConsumer) handleInsertFailure(events []*models.Event, cause error) error {
+	if !c.retry.enabled() || !IsTransient(cause) {
+		return c.deadLetter(events, dlq.ReasonInsertFailed, cause)
+	}
+
+	now := time.Now()
+	entries := make([]*models.RetryEntry, len(events))
+	for i, event := range events {
+		entries[i] = &models.RetryEntry{
+			ID:            uuid.NewString(),
+			Event:         event,
+			Kafka:         event.KafkaMetadata,
+			Attempts:      1,
+			LastError:     cause.Error(),
+			FirstFailedAt: now,
+			NextAttemptAt: now.Add(c.retry.Backoff(1)),
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := c.redisRepo.PushToRetryQueue(ctx, entries...); err != nil {
+		log.Printf("Failed to queue %d events for retry: %v", len(events), err)
+		return c.deadLetter(events, dlq.ReasonInsertFailed, cause)
+	}
+
+	c.metrics.AddRetryAttempts("queued", len(entries))
+	return nil
+}
+
+// retryLoop периодически повторяет запись событий, время попытки которых наступило
+func (c *EventConsumer) retryLoop() {
+	defer c.wg.Done()
+
+	interval := c.retry.PollInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	c.updateRetryMetrics()
+
+	for {
+		select {
+		case <-c.stopChan:
+			return
+		case <-ticker.C:
+			c.processRetries()
+			c.updateRetryMetrics()
+		}
+	}
+}
+
+// processRetries обрабатывает записи очереди батчами, пока готовые записи не закончатся
+func (c *EventConsumer) processRetries() {
+	for {
+		select {
+		case <-c.stopChan:
+			return
+		default:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		entries, corrupt, err := c.redisRepo.ClaimRetries(ctx, time.Now(), retryLease, retryBatchSize)
+		cancel()
+
+		if err != nil {
+			log.Printf("Failed to read retry queue: %v", err)
+			return
+		}
+		if corrupt > 0 {
+			log.Printf("Moved %d undecodable retry entries to retry:corrupt", corrupt)
+			c.metrics.AddRetryAttempts("corrupt", corrupt)
+		}
+		if len(entries) > 0 {
+			c.retryBatch(entries)
+		}
+
+		if len(entries)+corrupt < retryBatchSize {
+			return
+		}
+	}
+}
+
+func (c *EventConsumer) retryBatch(entries []*models.RetryEntry) {
+	events := make([]*models.Event, len(entries))
+	for i, entry := range entries {
+		entry.Event.KafkaMetadata = entry.Kafka
+		events[i] = entry.Event
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	err := c.eventRepo.InsertEventBatch(ctx, events)
+	cancel()
+
+	// Отдельный контекст: таймаут записи не должен мешать обновить очередь
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err == nil {
+		c.markInserted()
+		c.observeLatency(events)
+		if err := c.redisRepo.CompleteRetries(ctx, retryIDs(entries)...); err != nil {
+			// Записи будут выданы повторно после аренды и вставлены еще раз
+			log.Printf("Failed to remove %d retried events from queue: %v", len(entries), err)
+		}
+		c.updateCache(events)
+		c.metrics.AddRetryAttempts("success", len(entries))
+		log.Printf("Retried batch of %d events", len(entries))
+		return
+	}
+
+	log.Printf("Retry of %d events failed: %v", len(entries), err)
+	c.metrics.IncrementDBError("insert", "events")
+	c.markInsertFailed()
+
+	transient := IsTransient(err)
+	now := time.Now()
+	var again, exhausted []*models.RetryEntry
+	for _, entry := range entries {
+		entry.Attempts++
+		entry.LastError = err.Error()
+
+		if !transient || entry.Attempts >= c.retry.MaxAttempts {
+			exhausted = append(exhausted, entry)
+			continue
+		}
+		entry.NextAttemptAt = now.Add(c.retry.Backoff(entry.Attempts))
+		again = append(again, entry)
+	}
+
+	if len(again) > 0 {
+		if err := c.redisRepo.PushToRetryQueue(ctx, again...); err != nil {
+			log.Printf("Failed to reschedule %d events: %v", len(again), err)
+		}
+		c.metrics.AddRetryAttempts("rescheduled", len(again))
+	}
+
+	if len(exhausted) > 0 {
+		failed := make([]*models.Event, len(exhausted))
+		for i, entry := range exhausted {
+			failed[i] = entry.Event
+		}
+
+		// Постоянная ошибка при повторе означает проблему в данных, а не исчерпанные попытки
+		reason, result := dlq.ReasonRetriesExhausted, "exhausted"
+		if !transient {
+			reason, result = dlq.ReasonInsertFailed, "failed"
+		}
+		if err := c.deadLetter(failed, reason, err); err != nil {
+			// Записи останутся в очереди и будут выданы снова после аренды
+			return
+		}
+
+		if err := c.redisRepo.CompleteRetries(ctx, retryIDs(exhausted)...); err != nil {
+			log.Printf("Failed to remove %d dead-lettered events from queue: %v", len(exhausted), err)
+		}
+		c.metrics.AddRetryAttempts(result, len(exhausted))
+	}
+}
+
+func (c *EventConsumer) updateRetryMetrics() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	depth, oldest, err := c.redisRepo.RetryQueueStats(ctx)
+	if err != nil {
+		return
+	}
+
+	var age time.Duration
+	if !oldest.IsZero() {
+		age = time.Since(oldest)
+	}
+	c.metrics.SetRetryQueueState(depth, age)
+	c.setRetryDepth(depth)
+}
+
+func retryIDs(entries []*models.RetryEntry) []string {
+	ids := make([]string, len(entries))
+	for i, entry := range entries {
+		ids[i] = entry.ID
+	}
+	return ids
+}
@@ -0,0 +1,344 @@
+package dlq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/segmentio/kafka-go"
+	"github.com/yourusername/event-analytics-service/internal/metrics"
+	"github.com/yourusername/event-analytics-service/internal/models"
+)
+
+const (
+	// Сколько событий возвращает List без явного лимита и максимум
+	defaultListLimit = 100
+	maxListLimit     = 1000
+	// Сколько событий повторно отправляется за один вызов Publisher
+	replayChunk = 500
+)
+
+// Publisher отправляет события в основной топик с подтверждением брокера
+type Publisher interface {
+	SendBatchSync(ctx context.Context, events []*models.Event) error
+}
+
+// Manager просматривает, повторно отправляет и удаляет события dead-letter
+// топика. Kafka не умеет удалять отдельные сообщения, поэтому обработанные
+// (отправленные повторно или удаленные) сообщения отмечаются в Redis:
+// множество отмеченных offset'ов и курсор партиции - первый offset, до
+// которого все сообщения обработаны. Отмеченные сообщения не показываются и
+// не отправляются повторно; сами сообщения удаляются по retention топика.
+type Manager struct {
+	client    *kafka.Client
+	brokers   []string
+	topic     string
+	publisher Publisher
+	redis     *redis.Client
+	metrics   *metrics.Metrics
+}
+
+// NewManager создает менеджер dead-letter топика. publisher нужен только для
+// Replay, metrics может быть nil.
+func NewManager(brokers []string, topic string, publisher Publisher, redis *redis.Client, metrics *metrics.Metrics) *Manager {
+	return &Manager{
+		client:    &kafka.Client{Addr: kafka.TCP(brokers...), Timeout: 10 * time.Second},
+		brokers:   brokers,
+		topic:     topic,
+		publisher: publisher,
+		redis:     redis,
+		metrics:   metrics,
+	}
+}
+
+// List возвращает необработанные события, подходящие под фильтр, от старых к новым
+// в каждой партиции
+func (m *Manager) List(ctx context.Context, filter models.DeadLetterFilter) ([]*models.DeadLetter, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+	if limit > maxListLimit {
+		limit = maxListLimit
+	}
+
+	letters := make([]*models.DeadLetter, 0)
+	err := m.scan(ctx, filter, nil, func(letter *models.DeadLetter) (bool, error) {
+		letters = append(letters, letter)
+		return len(letters) < limit, nil
+	})
+
+	return letters, err
+}
+
+// Replay отправляет подходящие события в основной топик и отмечает их
+// обработанными. Сообщения, которые не удалось разобрать, пропускаются.
+// Возвращает число отправленных и пропущенных событий.
+func (m *Manager) Replay(ctx context.Context, filter models.DeadLetterFilter) (int, int, error) {
+	if m.publisher == nil {
+		return 0, 0, errors.New("replay publisher is not configured")
+	}
+
+	replayed, skipped := 0, 0
+	var chunk []*models.DeadLetter
+	starts := make(map[int]int64)
+
+	flush := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+
+		events := make([]*models.Event, len(chunk))
+		for i, letter := range chunk {
+			events[i] = letter.Event
+		}
+		if err := m.publisher.SendBatchSync(ctx, events); err != nil {
+			return err
+		}
+		if err := m.resolve(ctx, chunk, starts); err != nil {
+			return err
+		}
+
+		replayed += len(chunk)
+		m.addResolved("replay", len(chunk))
+		chunk = chunk[:0]
+		return nil
+	}
+
+	err := m.scan(ctx, filter, starts, func(letter *models.DeadLetter) (bool, error) {
+		if letter.Event == nil {
+			skipped++
+			return true, nil
+		}
+
+		chunk = append(chunk, letter)
+		if len(chunk) == replayChunk {
+			if err := flush(); err != nil {
+				return false, err
+			}
+		}
+		return filter.Limit <= 0 || replayed+len(chunk) < filter.Limit, nil
+	})
+	if err == nil {
+		err = flush()
+	}
+
+	return replayed, skipped, err
+}
+
+// Purge отмечает подходящие события обработанными без повторной отправки
+func (m *Manager) Purge(ctx context.Context, filter models.DeadLetterFilter) (int, error) {
+	var letters []*models.DeadLetter
+	starts := make(map[int]int64)
+	err := m.scan(ctx, filter, starts, func(letter *models.DeadLetter) (bool, error) {
+		letters = append(letters, letter)
+		return filter.Limit <= 0 || len(letters) < filter.Limit, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if err := m.resolve(ctx, letters, starts); err != nil {
+		return 0, err
+	}
+
+	m.addResolved("purge", len(letters))
+	return len(letters), nil
+}
+
+// scan читает необработанные сообщения всех партиций от курсора до конца
+// партиции и передает подходящие под фильтр в visit, пока он возвращает true.
+// В starts (если не nil) записывается начало чтения каждой партиции.
+func (m *Manager) scan(ctx context.Context, filter models.DeadLetterFilter, starts map[int]int64, visit func(*models.DeadLetter) (bool, error)) error {
+	ranges, err := m.partitionRanges(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range ranges {
+		cursor, resolved, err := m.state(ctx, r.Partition)
+		if err != nil {
+			return err
+		}
+		if cursor < r.FirstOffset {
+			cursor = r.FirstOffset
+		}
+		if starts != nil {
+			starts[r.Partition] = cursor
+		}
+		if cursor >= r.LastOffset {
+			continue
+		}
+
+		more, err := m.scanPartition(ctx, r.Partition, cursor, r.LastOffset, resolved, filter, visit)
+		if err != nil || !more {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *Manager) scanPartition(ctx context.Context, partition int, from, to int64, resolved map[int64]bool, filter models.DeadLetterFilter, visit func(*models.DeadLetter) (bool, error)) (bool, error) {
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:   m.brokers,
+		Topic:     m.topic,
+		Partition: partition,
+		MinBytes:  1,
+		MaxBytes:  10e6,
+		MaxWait:   500 * time.Millisecond,
+	})
+	defer reader.Close()
+
+	if err := reader.SetOffset(from); err != nil {
+		return false, err
+	}
+
+	for {
+		msg, err := reader.ReadMessage(ctx)
+		if err != nil {
+			return false, fmt.Errorf("read %s/%d: %w", m.topic, partition, err)
+		}
+
+		if !resolved[msg.Offset] {
+			letter := Decode(msg)
+			if filter.Match(letter) {
+				more, err := visit(letter)
+				if err != nil || !more {
+					return false, err
+				}
+			}
+		}
+
+		if msg.Offset+1 >= to {
+			return true, nil
+		}
+	}
+}
+
+// partitionRanges возвращает диапазоны offset'ов партиций топика; если топик
+// еще не создан, список пуст
+func (m *Manager) partitionRanges(ctx context.Context) ([]kafka.PartitionOffsets, error) {
+	metadata, err := m.client.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{m.topic}})
+	if err != nil {
+		return nil, err
+	}
+	if len(metadata.Topics) == 0 {
+		return nil, nil
+	}
+	topic := metadata.Topics[0]
+	if errors.Is(topic.Error, kafka.UnknownTopicOrPartition) {
+		return nil, nil
+	}
+	if topic.Error != nil {
+		return nil, topic.Error
+	}
+
+	requests := make([]kafka.OffsetRequest, 0, 2*len(topic.Partitions))
+	for _, partition := range topic.Partitions {
+		requests = append(requests, kafka.FirstOffsetOf(partition.ID), kafka.LastOffsetOf(partition.ID))
+	}
+
+	offsets, err := m.client.ListOffsets(ctx, &kafka.ListOffsetsRequest{
+		Topics: map[string][]kafka.OffsetRequest{m.topic: requests},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ranges := offsets.Topics[m.topic]
+	for _, r := range ranges {
+		if r.Error != nil {
+			return nil, r.Error
+		}
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].Partition < ranges[j].Partition })
+
+	return ranges, nil
+}
+
+// state возвращает курсор партиции и отмеченные offset'ы после него
+func (m *Manager) state(ctx context.Context, partition int) (int64, map[int64]bool, error) {
+	cursor, err := m.redis.HGet(ctx, m.cursorKey(), strconv.Itoa(partition)).Int64()
+	if err != nil && err != redis.Nil {
+		return 0, nil, err
+	}
+
+	members, err := m.redis.SMembers(ctx, m.resolvedKey(partition)).Result()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	resolved := make(map[int64]bool, len(members))
+	for _, member := range members {
+		if offset, err := strconv.ParseInt(member, 10, 64); err == nil {
+			resolved[offset] = true
+		}
+	}
+
+	return cursor, resolved, nil
+}
+
+// resolve отмечает сообщения обработанными и сдвигает курсоры партиций через
+// непрерывные отмеченные диапазоны, чтобы множества в Redis не росли.
+// starts - начало чтения партиций: сообщения до него уже удалены по retention.
+func (m *Manager) resolve(ctx context.Context, letters []*models.DeadLetter, starts map[int]int64) error {
+	byPartition := make(map[int][]interface{})
+	for _, letter := range letters {
+		byPartition[letter.Partition] = append(byPartition[letter.Partition], strconv.FormatInt(letter.Offset, 10))
+	}
+
+	for partition, offsets := range byPartition {
+		if err := m.redis.SAdd(ctx, m.resolvedKey(partition), offsets...).Err(); err != nil {
+			return err
+		}
+
+		cursor, resolved, err := m.state(ctx, partition)
+		if err != nil {
+			return err
+		}
+		if start := starts[partition]; start > cursor {
+			cursor = start
+		}
+
+		var stale []interface{}
+		for offset := range resolved {
+			if offset < cursor {
+				stale = append(stale, strconv.FormatInt(offset, 10))
+			}
+		}
+		for resolved[cursor] {
+			stale = append(stale, strconv.FormatInt(cursor, 10))
+			cursor++
+		}
+
+		if len(stale) > 0 {
+			pipe := m.redis.TxPipeline()
+			pipe.HSet(ctx, m.cursorKey(), strconv.Itoa(partition), cursor)
+			pipe.SRem(ctx, m.resolvedKey(partition), stale...)
+			if _, err := pipe.Exec(ctx); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (m *Manager) addResolved(action string, count int) {
+	if m.metrics != nil && count > 0 {
+		m.metrics.AddDLQResolved(action, count)
+	}
+}
+
+func (m *Manager) cursorKey() string {
+	return "dlq:cursor:" + m.topic
+}
+
+func (m *Manager) resolvedKey(partition int) string {
+	return "dlq:resolved:" + m.topic + ":" + strconv.Itoa(partition)
+}
@@ -0,0 +1,167 @@
+// Package dlq отправляет события, которые консьюмер не смог сохранить, в
+// dead-letter топик Kafka и позволяет просматривать, повторно отправлять и
+// удалять их.
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/yourusername/event-analytics-service/internal/models"
+)
+
+// Причины, по которым событие попало в dead-letter топик
+const (
+	ReasonInsertFailed     = "insert_failed"
+	ReasonUnmarshalFailed  = "unmarshal_failed"
+	ReasonRetriesExhausted = "retries_exhausted"
+)
+
+// Заголовки сообщений dead-letter топика. project_id и event_type совпадают
+// с заголовками основного топика.
+const (
+	HeaderReason          = "dlq-reason"
+	HeaderError           = "dlq-error"
+	HeaderFailedAt        = "dlq-failed-at"
+	HeaderSourceTopic     = "dlq-source-topic"
+	HeaderSourcePartition = "dlq-source-partition"
+	HeaderSourceOffset    = "dlq-source-offset"
+	HeaderProjectID       = "project_id"
+	HeaderEventType       = "event_type"
+)
+
+// Ограничение длины текста ошибки в заголовке
+const maxErrorLength = 1024
+
+// Writer записывает события в dead-letter топик синхронно, с подтверждением
+// всех реплик: вызывающий должен знать, что событие сохранено.
+type Writer struct {
+	writer *kafka.Writer
+}
+
+func NewWriter(brokers []string, topic string) *Writer {
+	return &Writer{
+		writer: &kafka.Writer{
+			Addr:                   kafka.TCP(brokers...),
+			Topic:                  topic,
+			Balancer:               &kafka.LeastBytes{},
+			BatchTimeout:           10 * time.Millisecond,
+			RequiredAcks:           kafka.RequireAll,
+			AllowAutoTopicCreation: true,
+		},
+	}
+}
+
+// PublishEvents записывает события, прочитанные консьюмером, с причиной и ошибкой
+func (w *Writer) PublishEvents(ctx context.Context, events []*models.Event, reason string, cause error) error {
+	messages := make([]kafka.Message, 0, len(events))
+	failedAt := time.Now()
+
+	for _, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+
+		messages = append(messages, kafka.Message{
+			Key:   []byte(event.UserID),
+			Value: data,
+			Headers: append(failureHeaders(reason, cause, failedAt, event.KafkaMetadata.Topic, event.KafkaMetadata.Partition, event.KafkaMetadata.Offset),
+				kafka.Header{Key: HeaderProjectID, Value: []byte(event.ProjectID)},
+				kafka.Header{Key: HeaderEventType, Value: []byte(event.EventType)},
+			),
+		})
+	}
+
+	return w.writer.WriteMessages(ctx, messages...)
+}
+
+// PublishRaw записывает исходное сообщение, которое не удалось разобрать
+func (w *Writer) PublishRaw(ctx context.Context, msg kafka.Message, reason string, cause error) error {
+	headers := failureHeaders(reason, cause, time.Now(), msg.Topic, msg.Partition, msg.Offset)
+	for _, header := range msg.Headers {
+		if header.Key == HeaderProjectID || header.Key == HeaderEventType {
+			headers = append(headers, header)
+		}
+	}
+
+	return w.writer.WriteMessages(ctx, kafka.Message{
+		Key:     msg.Key,
+		Value:   msg.Value,
+		Headers: headers,
+	})
+}
+
+func (w *Writer) Close() error {
+	return w.writer.Close()
+}
+
+func failureHeaders(reason string, cause error, failedAt time.Time, topic string, partition int, offset int64) []kafka.Header {
+	message := ""
+	if cause != nil {
+		message = cause.Error()
+		if len(message) > maxErrorLength {
+			message = message[:maxErrorLength]
+		}
+	}
+
+	return []kafka.Header{
+		{Key: HeaderReason, Value: []byte(reason)},
+		{Key: HeaderError, Value: []byte(message)},
+		{Key: HeaderFailedAt, Value: []byte(failedAt.UTC().Format(time.RFC3339Nano))},
+		{Key: HeaderSourceTopic, Value: []byte(topic)},
+		{Key: HeaderSourcePartition, Value: []byte(strconv.Itoa(partition))},
+		{Key: HeaderSourceOffset, Value: []byte(strconv.FormatInt(offset, 10))},
+	}
+}
+
+// Decode разбирает сообщение dead-letter топика
+func Decode(msg kafka.Message) *models.DeadLetter {
+	letter := &models.DeadLetter{
+		Partition: msg.Partition,
+		Offset:    msg.Offset,
+		FailedAt:  msg.Time,
+	}
+
+	for _, header := range msg.Headers {
+		value := string(header.Value)
+		switch header.Key {
+		case HeaderReason:
+			letter.Reason = value
+		case HeaderError:
+			letter.Error = value
+		case HeaderFailedAt:
+			if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+				letter.FailedAt = t
+			}
+		case HeaderSourceTopic:
+			letter.SourceTopic = value
+		case HeaderSourcePartition:
+			letter.SourcePartition, _ = strconv.Atoi(value)
+		case HeaderSourceOffset:
+			letter.SourceOffset, _ = strconv.ParseInt(value, 10, 64)
+		case HeaderProjectID:
+			letter.ProjectID = value
+		case HeaderEventType:
+			letter.EventType = value
+		}
+	}
+
+	var event models.Event
+	if err := json.Unmarshal(msg.Value, &event); err == nil {
+		letter.Event = &event
+		if letter.ProjectID == "" {
+			letter.ProjectID = event.ProjectID
+		}
+		if letter.EventType == "" {
+			letter.EventType = string(event.EventType)
+		}
+	} else {
+		letter.Raw = string(msg.Value)
+	}
+
+	return letter
+}
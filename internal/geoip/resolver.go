@@ -0,0 +1,172 @@
+// Package geoip определяет страну и регион по IP адресу с помощью локальной
+// базы в формате MaxMind DB (GeoLite2/GeoIP2 Country или City).
+package geoip
+
+import (
+	"log"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// Location - результат определения местоположения
+type Location struct {
+	CountryCode string // ISO 3166-1 alpha-2, например "DE"
+	Region      string // ISO 3166-2, например "DE-BE"; пусто для баз уровня Country
+}
+
+// Поля записи GeoIP2, которые нам нужны
+type record struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	Subdivisions []struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"subdivisions"`
+}
+
+// Resolver потокобезопасно выполняет поиск и перечитывает базу при изменении файла
+type Resolver struct {
+	path string
+
+	mu      sync.RWMutex
+	reader  *maxminddb.Reader
+	modTime time.Time
+	size    int64
+
+	stopChan chan struct{}
+	stopOnce sync.Once
+}
+
+func NewResolver(path string) (*Resolver, error) {
+	r := &Resolver{
+		path:     path,
+		stopChan: make(chan struct{}),
+	}
+
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Lookup возвращает местоположение IP адреса; ok = false, если адрес
+// некорректный или отсутствует в базе
+func (r *Resolver) Lookup(ipAddress string) (Location, bool) {
+	ip := net.ParseIP(ipAddress)
+	if ip == nil {
+		return Location{}, false
+	}
+
+	var rec record
+
+	r.mu.RLock()
+	err := r.reader.Lookup(ip, &rec)
+	r.mu.RUnlock()
+
+	if err != nil || rec.Country.ISOCode == "" {
+		return Location{}, false
+	}
+
+	loc := Location{CountryCode: rec.Country.ISOCode}
+	if len(rec.Subdivisions) > 0 && rec.Subdivisions[0].ISOCode != "" {
+		loc.Region = rec.Country.ISOCode + "-" + rec.Subdivisions[0].ISOCode
+	}
+
+	return loc, true
+}
+
+// Watch периодически проверяет файл базы и перечитывает его при изменении.
+// Блокируется до вызова Close; при interval <= 0 перечитывание отключено.
+func (r *Resolver) Watch(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stopChan:
+			return
+		case <-ticker.C:
+			changed, err := r.changed()
+			if err != nil {
+				log.Printf("GeoIP: failed to stat %s: %v", r.path, err)
+				continue
+			}
+			if !changed {
+				continue
+			}
+
+			if err := r.load(); err != nil {
+				// Продолжаем работать со старой базой
+				log.Printf("GeoIP: failed to reload %s: %v", r.path, err)
+				continue
+			}
+			log.Printf("GeoIP: database %s reloaded", r.path)
+		}
+	}
+}
+
+func (r *Resolver) Close() error {
+	r.stopOnce.Do(func() { close(r.stopChan) })
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.reader == nil {
+		return nil
+	}
+	err := r.reader.Close()
+	r.reader = nil
+	return err
+}
+
+func (r *Resolver) changed() (bool, error) {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return false, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return !info.ModTime().Equal(r.modTime) || info.Size() != r.size, nil
+}
+
+// load читает базу целиком в память: файл может быть перезаписан на месте,
+// и отображение в память в этом случае небезопасно
+func (r *Resolver) load() error {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		return err
+	}
+
+	reader, err := maxminddb.FromBytes(data)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	old := r.reader
+	r.reader = reader
+	r.modTime = info.ModTime()
+	r.size = info.Size()
+	r.mu.Unlock()
+
+	if old != nil {
+		old.Close()
+	}
+
+	return nil
+}
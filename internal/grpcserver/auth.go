@@ -0,0 +1,131 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/yourusername/event-analytics-service/internal/models"
+	"github.com/yourusername/event-analytics-service/internal/service"
+	"github.com/yourusername/event-analytics-service/proto/analyticspb"
+)
+
+const (
+	apiKeyMetadata       = "x-api-key"
+	deliveryModeMetadata = "x-delivery-mode"
+)
+
+type projectKey struct{}
+
+// Методы приема событий, к которым применяются лимиты проекта
+var trackingMethods = map[string]bool{
+	analyticspb.AnalyticsService_Track_FullMethodName:       true,
+	analyticspb.AnalyticsService_TrackStream_FullMethodName: true,
+}
+
+// UnaryInterceptor определяет проект по API ключу из metadata и проверяет
+// лимиты проекта для методов приема событий
+func (s *Server) UnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := s.authorize(ctx, info.FullMethod, grpc.SetHeader)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// StreamInterceptor - то же для потоковых методов; лимиты проверяются при
+// открытии потока и затем перед каждым следующим батчем (см. TrackStream)
+func (s *Server) StreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	setHeader := func(_ context.Context, md metadata.MD) error {
+		return ss.SetHeader(md)
+	}
+	ctx, err := s.authorize(ss.Context(), info.FullMethod, setHeader)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authorizedStream{ServerStream: ss, ctx: ctx})
+}
+
+func (s *Server) authorize(ctx context.Context, method string, setHeader func(context.Context, metadata.MD) error) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	apiKey := firstValue(md, apiKeyMetadata)
+	if apiKey == "" {
+		return nil, status.Error(codes.Unauthenticated, "API key required")
+	}
+
+	project, err := s.projectService.ValidateAPIKey(ctx, apiKey)
+	if err == nil && !project.Active {
+		err = models.ErrInvalidAPIKey
+	}
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidAPIKey) {
+			return nil, status.Error(codes.Unauthenticated, models.ErrInvalidAPIKey.Error())
+		}
+		return nil, status.Error(codes.Internal, "failed to validate API key")
+	}
+
+	if trackingMethods[method] {
+		if err := s.checkLimits(ctx, project, setHeader); err != nil {
+			return nil, err
+		}
+	}
+
+	mode, err := models.ParseDeliveryMode(firstValue(md, deliveryModeMetadata))
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	ctx = context.WithValue(ctx, projectKey{}, project)
+	if mode != "" {
+		ctx = service.WithDeliveryMode(ctx, mode)
+	}
+	return ctx, nil
+}
+
+// checkLimits повторяет RateLimitMiddleware: при недоступности Redis запросы
+// пропускаются, в мягком режиме квоты превышение только помечается
+func (s *Server) checkLimits(ctx context.Context, project *models.Project, setHeader func(context.Context, metadata.MD) error) error {
+	if limit, err := s.quotaService.CheckRateLimit(ctx, project); err == nil && limit.Exceeded {
+		s.metrics.IncrementRateLimited(project.ID, "requests_per_second")
+		return status.Error(codes.ResourceExhausted, models.ErrRateLimitExceeded.Error())
+	}
+
+	if quota, err := s.quotaService.CheckQuota(ctx, project); err == nil && quota.Exceeded {
+		s.metrics.IncrementRateLimited(project.ID, "monthly_quota")
+		if s.quotaService.QuotaMode(project) == models.QuotaModeHard {
+			return status.Error(codes.ResourceExhausted, models.ErrRateLimitExceeded.Error())
+		}
+		setHeader(ctx, metadata.Pairs("x-quota-exceeded", "true"))
+	}
+
+	return nil
+}
+
+func projectFromContext(ctx context.Context) *models.Project {
+	project, _ := ctx.Value(projectKey{}).(*models.Project)
+	return project
+}
+
+func firstValue(md metadata.MD, key string) string {
+	values := md.Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return strings.TrimSpace(values[0])
+}
+
+// authorizedStream подменяет контекст потока контекстом с проектом
+type authorizedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authorizedStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,324 @@
+// Package grpcserver реализует gRPC API приема событий и статистики
+// (proto/analytics.proto) поверх тех же сервисов, что и HTTP API.
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/yourusername/event-analytics-service/internal/metrics"
+	"github.com/yourusername/event-analytics-service/internal/models"
+	"github.com/yourusername/event-analytics-service/internal/service"
+	"github.com/yourusername/event-analytics-service/proto/analyticspb"
+)
+
+const (
+	// Сколько событий потока отправляется в EventService одним батчем
+	streamBatchSize = 500
+	// Максимальное количество событий в одном потоке TrackStream
+	maxStreamEvents = 100000
+)
+
+type Server struct {
+	analyticspb.UnimplementedAnalyticsServiceServer
+
+	eventService   *service.EventService
+	statsService   *service.StatsService
+	projectService *service.ProjectService
+	quotaService   *service.QuotaService
+	metrics        *metrics.Metrics
+}
+
+func NewServer(
+	eventService *service.EventService,
+	statsService *service.StatsService,
+	projectService *service.ProjectService,
+	quotaService *service.QuotaService,
+	metrics *metrics.Metrics,
+) *Server {
+	return &Server{
+		eventService:   eventService,
+		statsService:   statsService,
+		projectService: projectService,
+		quotaService:   quotaService,
+		metrics:        metrics,
+	}
+}
+
+// GRPCServer создает grpc.Server с проверкой API ключа и зарегистрированным сервисом
+func (s *Server) GRPCServer() *grpc.Server {
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(s.UnaryInterceptor),
+		grpc.StreamInterceptor(s.StreamInterceptor),
+	)
+	analyticspb.RegisterAnalyticsServiceServer(server, s)
+	return server
+}
+
+func (s *Server) Track(ctx context.Context, req *analyticspb.TrackRequest) (*analyticspb.TrackResponse, error) {
+	if req.GetEvent() == nil {
+		return nil, status.Error(codes.InvalidArgument, "event required")
+	}
+
+	event := toEvent(req.GetEvent(), projectFromContext(ctx))
+
+	if err := s.eventService.ProcessEvent(ctx, event); err != nil {
+		if errors.Is(err, models.ErrDuplicateEvent) {
+			// Повторная отправка уже принятого события - отвечаем успехом
+			return &analyticspb.TrackResponse{EventId: event.ID, Duplicate: true}, nil
+		}
+		return nil, trackError(err)
+	}
+
+	return &analyticspb.TrackResponse{
+		EventId:  event.ID,
+		Warnings: toValidationErrors(event.Warnings),
+	}, nil
+}
+
+// TrackStream читает события потока и отправляет их батчами по streamBatchSize.
+// Статус каждого события возвращается после закрытия потока клиентом; при
+// ошибке публикации поток прерывается с кодом Unavailable. Каждый батч, кроме
+// первого (его учел интерцептор при открытии потока), учитывается в лимитах
+// проекта как отдельный запрос: при их превышении поток прерывается с кодом
+// ResourceExhausted. Прерванный поток передает в деталях статуса
+// TrackStreamResponse с результатами уже обработанных событий.
+func (s *Server) TrackStream(stream analyticspb.AnalyticsService_TrackStreamServer) error {
+	ctx := stream.Context()
+	project := projectFromContext(ctx)
+
+	response := &analyticspb.TrackStreamResponse{}
+	batch := make([]*models.Event, 0, streamBatchSize)
+	flushed := false
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		// Поток может идти дольше секунды и пережить исчерпание квоты,
+		// поэтому лимиты проверяются не только при его открытии
+		if flushed {
+			if err := s.checkLimits(ctx, project, grpc.SetHeader); err != nil {
+				return streamError(status.Convert(err), response, len(batch))
+			}
+		}
+		flushed = true
+
+		offset := len(response.Results) - len(batch)
+		results, err := s.eventService.ProcessBatch(ctx, batch)
+		for i, result := range results {
+			response.Results[offset+i] = toEventResult(offset+i, result)
+		}
+		batch = batch[:0]
+
+		if err != nil {
+			return streamError(status.New(codes.Unavailable, models.ErrEventNotDelivered.Error()), response, 0)
+		}
+		return nil
+	}
+
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if len(response.Results) >= maxStreamEvents {
+			// Накопленный батч еще не отправлен, события повторяются клиентом
+			return streamError(status.Newf(codes.ResourceExhausted, "stream exceeds %d events", maxStreamEvents), response, len(batch))
+		}
+
+		if req.GetEvent() == nil {
+			// Батч занимает последние позиции результатов, поэтому
+			// отправляется до записи результата пустого события
+			if err := flush(); err != nil {
+				return err
+			}
+			response.Results = append(response.Results, &analyticspb.EventResult{
+				Index:  int32(len(response.Results)),
+				Status: models.BatchStatusRejected,
+				Error:  models.ErrInvalidEventData.Error(),
+			})
+			continue
+		}
+
+		response.Results = append(response.Results, nil)
+		batch = append(batch, toEvent(req.GetEvent(), project))
+		if len(batch) == streamBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return err
+	}
+
+	return stream.SendAndClose(summarize(response.Results))
+}
+
+// summarize считает итоги потока по результатам событий
+func summarize(results []*analyticspb.EventResult) *analyticspb.TrackStreamResponse {
+	response := &analyticspb.TrackStreamResponse{Results: results}
+	for _, result := range results {
+		switch result.GetStatus() {
+		case models.BatchStatusAccepted:
+			response.Accepted++
+		case models.BatchStatusDuplicate:
+			response.Duplicates++
+		default:
+			response.Rejected++
+		}
+	}
+	return response
+}
+
+// streamError прерывает поток со статусом st и передает в его деталях
+// результаты обработанных событий, чтобы клиент повторил только остальные.
+// pending - число последних событий потока, которые еще не отправлялись.
+func streamError(st *status.Status, response *analyticspb.TrackStreamResponse, pending int) error {
+	partial := summarize(response.Results[:len(response.Results)-pending])
+	if withResults, err := st.WithDetails(partial); err == nil {
+		return withResults.Err()
+	}
+	return st.Err()
+}
+
+func (s *Server) GetStats(ctx context.Context, req *analyticspb.GetStatsRequest) (*analyticspb.GetStatsResponse, error) {
+	statsReq := models.StatsRequest{
+		EventType:   models.EventType(req.GetEventType()),
+		EventName:   req.GetEventName(),
+		StartDate:   req.GetStartDate(),
+		EndDate:     req.GetEndDate(),
+		GroupBy:     req.GetGroupBy(),
+		Country:     req.GetCountry(),
+		Breakdown:   req.GetBreakdown(),
+		IncludeBots: req.GetIncludeBots(),
+	}
+	if err := validateStatsRequest(statsReq); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	stats, err := s.statsService.GetProjectEventStatistics(ctx, projectFromContext(ctx).ID, statsReq)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to get statistics")
+	}
+
+	response := &analyticspb.GetStatsResponse{
+		Statistics: make([]*analyticspb.EventStats, 0, len(stats)),
+	}
+	for _, row := range stats {
+		response.Statistics = append(response.Statistics, &analyticspb.EventStats{
+			TimeBucket: row.TimeBucket,
+			EventType:  row.EventType,
+			EventName:  row.EventName,
+			Dimension:  row.Dimension,
+			Count:      row.Count,
+		})
+	}
+
+	return response, nil
+}
+
+// validateStatsRequest повторяет проверки StatsHandler.GetStatistics и биндинга запроса
+func validateStatsRequest(req models.StatsRequest) error {
+	if (req.EventType == "" && req.EventName == "") || req.StartDate == "" || req.EndDate == "" {
+		return errors.New("event_type or event_name, start_date, end_date are required")
+	}
+	for _, date := range []string{req.StartDate, req.EndDate} {
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			return models.ErrInvalidDateFormat
+		}
+	}
+	if req.Country != "" && len(req.Country) != 2 {
+		return errors.New("country must be a two-letter code")
+	}
+	switch req.Breakdown {
+	case "", "device_type", "browser", "os", "country", "region":
+	default:
+		return errors.New("breakdown must be one of device_type, browser, os, country, region")
+	}
+	return nil
+}
+
+// trackError переводит ошибки EventService в коды gRPC так же, как
+// EventHandler переводит их в HTTP статусы
+func trackError(err error) error {
+	var schemaErr *models.SchemaValidationError
+	switch {
+	case errors.Is(err, models.ErrInvalidAPIKey):
+		return status.Error(codes.Unauthenticated, err.Error())
+	case errors.As(err, &schemaErr):
+		return status.Error(codes.InvalidArgument, schemaErr.Error())
+	case errors.Is(err, models.ErrInvalidEventType),
+		errors.Is(err, models.ErrInvalidEventName),
+		errors.Is(err, models.ErrInvalidTimestamp),
+		errors.Is(err, models.ErrInvalidEventData),
+		errors.Is(err, models.ErrEventTooLarge):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, models.ErrEventNotDelivered):
+		return status.Error(codes.Unavailable, models.ErrEventNotDelivered.Error())
+	case errors.Is(err, models.ErrRateLimitExceeded):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+	return status.Error(codes.Internal, "failed to process event")
+}
+
+// toEvent переводит событие из protobuf в модель; проект берется только из API ключа
+func toEvent(in *analyticspb.Event, project *models.Project) *models.Event {
+	event := &models.Event{
+		ID:          in.GetId(),
+		ProjectID:   project.ID,
+		UserID:      in.GetUserId(),
+		AnonymousID: in.GetAnonymousId(),
+		EventType:   models.EventType(in.GetEventType()),
+		EventName:   in.GetEventName(),
+		PageURL:     in.GetPageUrl(),
+		Referrer:    in.GetReferrer(),
+		UserAgent:   in.GetUserAgent(),
+		IPAddress:   in.GetIpAddress(),
+	}
+	if in.GetMetadata() != nil {
+		event.Metadata = in.GetMetadata().AsMap()
+	}
+	if in.GetTimestamp() != nil {
+		event.Timestamp = in.GetTimestamp().AsTime()
+	}
+	if in.GetSentAt() != nil {
+		event.SentAt = in.GetSentAt().AsTime()
+	}
+	return event
+}
+
+func toEventResult(index int, result models.BatchEventResult) *analyticspb.EventResult {
+	return &analyticspb.EventResult{
+		Index:    int32(index),
+		EventId:  result.EventID,
+		Status:   result.Status,
+		Error:    result.Error,
+		Errors:   toValidationErrors(result.Errors),
+		Warnings: toValidationErrors(result.Warnings),
+	}
+}
+
+func toValidationErrors(errs []models.ValidationError) []*analyticspb.ValidationError {
+	if len(errs) == 0 {
+		return nil
+	}
+	out := make([]*analyticspb.ValidationError, len(errs))
+	for i, err := range errs {
+		out[i] = &analyticspb.ValidationError{Field: err.Field, Message: err.Message}
+	}
+	return out
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/event-analytics-service/internal/dlq"
+	"github.com/yourusername/event-analytics-service/internal/models"
+)
+
+// DLQHandler - административный API dead-letter топика
+type DLQHandler struct {
+	manager *dlq.Manager
+}
+
+// PurgeDeadLettersRequest - фильтр удаляемых событий; удаление без фильтра
+// требует явного all: true
+type PurgeDeadLettersRequest struct {
+	models.DeadLetterFilter
+	All bool `json:"all"`
+}
+
+func NewDLQHandler(manager *dlq.Manager) *DLQHandler {
+	return &DLQHandler{
+		manager: manager,
+	}
+}
+
+func (h *DLQHandler) ListDeadLetters(c *gin.Context) {
+	var filter models.DeadLetterFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	letters, err := h.manager.List(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events": letters,
+		"total":  len(letters),
+	})
+}
+
+func (h *DLQHandler) ReplayDeadLetters(c *gin.Context) {
+	var filter models.DeadLetterFilter
+	if err := c.ShouldBindJSON(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	replayed, skipped, err := h.manager.Replay(c.Request.Context(), filter)
+	if err != nil {
+		// Часть событий могла быть отправлена до ошибки
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":    err.Error(),
+			"replayed": replayed,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"replayed": replayed,
+		"skipped":  skipped,
+	})
+}
+
+func (h *DLQHandler) PurgeDeadLetters(c *gin.Context) {
+	var req PurgeDeadLettersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.DeadLetterFilter.Empty() && !req.All {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "filter required; pass \"all\": true to purge everything"})
+		return
+	}
+
+	purged, err := h.manager.Purge(c.Request.Context(), req.DeadLetterFilter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"purged": purged})
+}
@@ -1,13 +1,37 @@
 package handler
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/yourusername/event-analytics-service/internal/models"
 	"github.com/yourusername/event-analytics-service/internal/service"
 )
 
+const (
+	// Максимальное количество событий в одном batch-запросе
+	maxBatchSize = 1000
+	// Максимальный размер тела batch-запроса
+	maxBatchBodySize = 10 << 20
+	// Заголовок, которым клиент выбирает режим доставки: async или sync
+	deliveryModeHeader = "X-Delivery-Mode"
+)
+
+// Прозрачный GIF 1x1, который отдает TrackPixel
+var pixelGIF = []byte{
+	0x47, 0x49, 0x46, 0x38, 0x39, 0x61, 0x01, 0x00, 0x01, 0x00, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0xff, 0xff, 0xff, 0x21, 0xf9, 0x04, 0x01, 0x00, 0x00, 0x00, 0x00, 0x2c, 0x00, 0x00, 0x00, 0x00,
+	0x01, 0x00, 0x01, 0x00, 0x00, 0x02, 0x02, 0x44, 0x01, 0x00, 0x3b,
+}
+
 type EventHandler struct {
 	eventService *service.EventService
 }
@@ -26,17 +50,278 @@ func (h *EventHandler) TrackEvent(c *gin.Context) {
 		return
 	}
 
+	ctx, err := deliveryContext(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// Добавляем информацию из запроса
+	event.ProjectID = c.GetString("project_id")
 	event.UserAgent = c.GetHeader("User-Agent")
 	event.IPAddress = c.ClientIP()
 
-	if err := h.eventService.ProcessEvent(c.Request.Context(), &event); err != nil {
+	if err := h.eventService.ProcessEvent(ctx, &event); err != nil {
+		if errors.Is(err, models.ErrDuplicateEvent) {
+			// Повторная отправка уже принятого события - отвечаем успехом,
+			// чтобы клиент прекратил ретраи
+			c.JSON(http.StatusOK, gin.H{
+				"message":   "Event already tracked",
+				"event_id":  event.ID,
+				"duplicate": true,
+			})
+			return
+		}
+		if errors.Is(err, models.ErrInvalidAPIKey) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		var schemaErr *models.SchemaValidationError
+		if errors.As(err, &schemaErr) {
+			c.JSON(http.StatusBadRequest, models.ValidationErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: "event does not match schema",
+				Errors:  schemaErr.Errors,
+			})
+			return
+		}
+		if isEventValidationError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, models.ErrEventNotDelivered) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": models.ErrEventNotDelivered.Error()})
+			return
+		}
+		if errors.Is(err, models.ErrRateLimitExceeded) {
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process event"})
 		return
 	}
 
-	c.JSON(http.StatusAccepted, gin.H{
+	response := gin.H{
 		"message":  "Event tracked successfully",
 		"event_id": event.ID,
-	})
+	}
+	if len(event.Warnings) > 0 {
+		response["warnings"] = event.Warnings
+	}
+	if c.GetBool("quota_exceeded") {
+		response["quota_exceeded"] = true
+	}
+
+	c.JSON(http.StatusAccepted, response)
+}
+
+// TrackBatch принимает массив событий (JSON) или NDJSON-поток и возвращает
+// статус по каждому событию, чтобы клиент мог повторить только отклоненные.
+func (h *EventHandler) TrackBatch(c *gin.Context) {
+	ctx, err := deliveryContext(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxBatchBodySize+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+		return
+	}
+	if len(body) > maxBatchBodySize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": models.ErrEventTooLarge.Error()})
+		return
+	}
+
+	var raw []json.RawMessage
+	if strings.Contains(c.ContentType(), "ndjson") {
+		raw, err = splitNDJSON(body)
+	} else {
+		err = json.Unmarshal(body, &raw)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if len(raw) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "batch is empty"})
+		return
+	}
+	if len(raw) > maxBatchSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("batch exceeds %d events", maxBatchSize)})
+		return
+	}
+
+	// Невалидный JSON отклоняем сразу, остальное отдаем в сервис
+	results := make([]models.BatchEventResult, len(raw))
+	events := make([]*models.Event, 0, len(raw))
+	positions := make([]int, 0, len(raw))
+
+	for i, item := range raw {
+		var event models.Event
+		if err := json.Unmarshal(item, &event); err != nil {
+			results[i] = models.BatchEventResult{
+				Index:  i,
+				Status: models.BatchStatusRejected,
+				Error:  models.ErrInvalidEventData.Error(),
+			}
+			continue
+		}
+
+		event.ProjectID = c.GetString("project_id")
+		event.UserAgent = c.GetHeader("User-Agent")
+		event.IPAddress = c.ClientIP()
+
+		events = append(events, &event)
+		positions = append(positions, i)
+	}
+
+	processed, err := h.eventService.ProcessBatch(ctx, events)
+	for j, result := range processed {
+		result.Index = positions[j]
+		results[positions[j]] = result
+	}
+
+	accepted, duplicates, limited := 0, 0, 0
+	for _, result := range results {
+		switch result.Status {
+		case models.BatchStatusAccepted:
+			accepted++
+		case models.BatchStatusDuplicate:
+			duplicates++
+		case models.BatchStatusRejected:
+			if result.Error == models.ErrRateLimitExceeded.Error() {
+				limited++
+			}
+		}
+	}
+
+	status := http.StatusAccepted
+	switch {
+	case err != nil:
+		status = http.StatusServiceUnavailable
+	case accepted+duplicates == 0 && limited > 0:
+		status = http.StatusTooManyRequests
+	case accepted+duplicates == 0:
+		status = http.StatusBadRequest
+	}
+
+	response := gin.H{
+		"accepted":   accepted,
+		"duplicates": duplicates,
+		"rejected":   len(results) - accepted - duplicates,
+		"results":    results,
+	}
+	if c.GetBool("quota_exceeded") {
+		response["quota_exceeded"] = true
+	}
+
+	c.JSON(status, response)
+}
+
+// TrackPixel принимает событие из query-параметров GET-запроса (открытия писем,
+// окружения без JavaScript). Ответ всегда - прозрачный GIF 1x1, даже если ключ
+// неверный, лимит превышен или событие не прошло валидацию: картинка не должна
+// ломаться у получателя письма.
+func (h *EventHandler) TrackPixel(c *gin.Context) {
+	defer servePixel(c)
+
+	projectID := c.GetString("project_id")
+	if projectID == "" || c.GetBool("rate_limited") {
+		return
+	}
+
+	event := models.Event{
+		ID:          c.Query("id"),
+		ProjectID:   projectID,
+		UserID:      c.Query("user_id"),
+		AnonymousID: c.Query("anonymous_id"),
+		EventType:   models.EventType(c.Query("event_type")),
+		EventName:   c.Query("event_name"),
+		PageURL:     c.Query("page_url"),
+		Referrer:    c.Query("referrer"),
+		UserAgent:   c.GetHeader("User-Agent"),
+		IPAddress:   c.ClientIP(),
+	}
+	if event.PageURL == "" {
+		event.PageURL = c.GetHeader("Referer")
+	} else if event.Referrer == "" {
+		event.Referrer = requestReferrer(c, event.PageURL)
+	}
+
+	// metadata передается JSON-объектом или отдельными параметрами meta.<key>
+	if raw := c.Query("metadata"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &event.Metadata); err != nil {
+			return
+		}
+	}
+	for key, values := range c.Request.URL.Query() {
+		name := strings.TrimPrefix(key, "meta.")
+		if name == key || name == "" || len(values) == 0 {
+			continue
+		}
+		if event.Metadata == nil {
+			event.Metadata = make(map[string]interface{})
+		}
+		event.Metadata[name] = values[0]
+	}
+
+	// Ошибки не видны клиенту: результат отражается только в метриках
+	h.eventService.ProcessEvent(c.Request.Context(), &event)
+}
+
+func servePixel(c *gin.Context) {
+	c.Header("Cache-Control", "no-cache, no-store, must-revalidate, private")
+	c.Header("Pragma", "no-cache")
+	c.Header("Expires", "0")
+	c.Data(http.StatusOK, "image/gif", pixelGIF)
+}
+
+// splitNDJSON разбивает тело запроса на отдельные JSON-документы по строкам
+func splitNDJSON(body []byte) ([]json.RawMessage, error) {
+	var raw []json.RawMessage
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxBatchBodySize)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		raw = append(raw, json.RawMessage(append([]byte(nil), line...)))
+	}
+
+	return raw, scanner.Err()
+}
+
+// deliveryContext переносит режим доставки из заголовка запроса в контекст
+func deliveryContext(c *gin.Context) (context.Context, error) {
+	ctx := c.Request.Context()
+
+	mode, err := models.ParseDeliveryMode(c.GetHeader(deliveryModeHeader))
+	if err != nil || mode == "" {
+		return ctx, err
+	}
+	return service.WithDeliveryMode(ctx, mode), nil
+}
+
+// requestReferrer возвращает заголовок Referer, если он ведет с другого сайта.
+// Используется только пикселем: у запросов из JavaScript-трекера Referer -
+// это страница с трекером, а не источник перехода.
+func requestReferrer(c *gin.Context, pageURL string) string {
+	referrer := c.GetHeader("Referer")
+	if service.IsExternalReferrer(referrer, pageURL) {
+		return referrer
+	}
+	return ""
+}
+
+func isEventValidationError(err error) bool {
+	return errors.Is(err, models.ErrInvalidEventType) ||
+		errors.Is(err, models.ErrInvalidEventName) ||
+		errors.Is(err, models.ErrInvalidTimestamp) ||
+		errors.Is(err, models.ErrInvalidEventData) ||
+		errors.Is(err, models.ErrEventTooLarge)
 }
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/event-analytics-service/internal/models"
+	"github.com/yourusername/event-analytics-service/internal/service"
+)
+
+// IdentityHandler принимает identify и alias от собственных SDK; для
+// Segment-совместимых клиентов те же операции выполняет SegmentHandler
+type IdentityHandler struct {
+	identityService *service.IdentityService
+}
+
+func NewIdentityHandler(identityService *service.IdentityService) *IdentityHandler {
+	return &IdentityHandler{
+		identityService: identityService,
+	}
+}
+
+// Identify сохраняет атрибуты пользователя и связывает anonymous_id с user_id
+func (h *IdentityHandler) Identify(c *gin.Context) {
+	var traits models.UserTraits
+	if err := c.ShouldBindJSON(&traits); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	traits.ProjectID = c.GetString("project_id")
+
+	if err := h.identityService.Identify(c.Request.Context(), &traits); err != nil {
+		h.handleError(c, err, "user_id or anonymous_id required")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User identified"})
+}
+
+// Alias связывает предыдущий идентификатор (previous_id) с user_id
+func (h *IdentityHandler) Alias(c *gin.Context) {
+	var alias models.UserAlias
+	if err := c.ShouldBindJSON(&alias); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	alias.ProjectID = c.GetString("project_id")
+
+	if err := h.identityService.Alias(c.Request.Context(), &alias); err != nil {
+		h.handleError(c, err, "previous_id and user_id required and must differ")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Alias created"})
+}
+
+func (h *IdentityHandler) handleError(c *gin.Context, err error, invalidMessage string) {
+	switch {
+	case errors.Is(err, models.ErrInvalidAPIKey):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrInvalidEventData):
+		c.JSON(http.StatusBadRequest, gin.H{"error": invalidMessage})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to store identity"})
+	}
+}
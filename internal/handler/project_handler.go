@@ -1,8 +1,11 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -50,6 +53,11 @@ func (h *ProjectHandler) CreateProject(c *gin.Context) {
 		req.Settings,
 	)
 	if err != nil {
+		if errors.Is(err, models.ErrInvalidPrivacySettings) || errors.Is(err, models.ErrInvalidDeliveryMode) ||
+			errors.Is(err, models.ErrInvalidSamplingSettings) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -130,6 +138,11 @@ func (h *ProjectHandler) UpdateProject(c *gin.Context) {
 
 	project, err := h.projectService.UpdateProject(c.Request.Context(), projectID, userID.(string), updates)
 	if err != nil {
+		if errors.Is(err, models.ErrInvalidPrivacySettings) || errors.Is(err, models.ErrInvalidDeliveryMode) ||
+			errors.Is(err, models.ErrInvalidSamplingSettings) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -222,3 +235,212 @@ func (h *ProjectHandler) GetProjectStats(c *gin.Context) {
 
 	c.JSON(http.StatusOK, stats)
 }
+
+// Максимальное количество шагов воронки
+const maxFunnelSteps = 10
+
+// GetFunnel считает воронку по названиям событий. Шаги передаются параметром
+// steps через запятую или повторяющимся параметром step (если названия содержат запятые).
+func (h *ProjectHandler) GetFunnel(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	projectID := c.Param("id")
+	if projectID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "project id required"})
+		return
+	}
+
+	steps := c.QueryArray("step")
+	if len(steps) == 0 && c.Query("steps") != "" {
+		for _, step := range strings.Split(c.Query("steps"), ",") {
+			if step = strings.TrimSpace(step); step != "" {
+				steps = append(steps, step)
+			}
+		}
+	}
+	if len(steps) < 2 || len(steps) > maxFunnelSteps {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("funnel requires 2 to %d steps", maxFunnelSteps)})
+		return
+	}
+
+	start, err := time.Parse("2006-01-02", c.Query("start_date"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start_date format"})
+		return
+	}
+
+	end, err := time.Parse("2006-01-02", c.Query("end_date"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid end_date format"})
+		return
+	}
+
+	funnel, err := h.projectService.GetFunnel(c.Request.Context(), projectID, userID.(string), steps, start, end)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrProjectAccessDenied):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, models.ErrProjectNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to calculate funnel"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"steps": funnel,
+		"period": gin.H{
+			"start": start.Format("2006-01-02"),
+			"end":   end.Format("2006-01-02"),
+		},
+	})
+}
+
+// GetTrafficSources возвращает сессии и конверсии по источнику, каналу и
+// кампании. Конверсионное событие задается параметром conversion_event
+// (по умолчанию purchase).
+func (h *ProjectHandler) GetTrafficSources(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	projectID := c.Param("id")
+	if projectID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "project id required"})
+		return
+	}
+
+	start, err := time.Parse("2006-01-02", c.Query("start_date"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start_date format"})
+		return
+	}
+
+	end, err := time.Parse("2006-01-02", c.Query("end_date"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid end_date format"})
+		return
+	}
+
+	conversionEvent := c.DefaultQuery("conversion_event", string(models.Purchase))
+
+	sources, err := h.projectService.GetTrafficSources(c.Request.Context(), projectID, userID.(string), conversionEvent, start, end)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrProjectAccessDenied):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, models.ErrProjectNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get traffic sources"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sources":          sources,
+		"conversion_event": conversionEvent,
+		"period": gin.H{
+			"start": start.Format("2006-01-02"),
+			"end":   end.Format("2006-01-02"),
+		},
+	})
+}
+
+// GetIngestionLatency возвращает задержку от приема события API до записи в
+// ClickHouse за период (end_date включительно) по интервалам hour или day
+func (h *ProjectHandler) GetIngestionLatency(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	projectID := c.Param("id")
+	if projectID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "project id required"})
+		return
+	}
+
+	start, err := time.Parse("2006-01-02", c.Query("start_date"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start_date format"})
+		return
+	}
+
+	end, err := time.Parse("2006-01-02", c.Query("end_date"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid end_date format"})
+		return
+	}
+
+	interval := c.DefaultQuery("interval", "hour")
+	if interval != "hour" && interval != "day" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "interval must be hour or day"})
+		return
+	}
+
+	report, err := h.projectService.GetIngestionLatency(c.Request.Context(), projectID, userID.(string), interval, start, end.AddDate(0, 0, 1))
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrProjectAccessDenied):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, models.ErrProjectNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get ingestion latency"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"latency":  report,
+		"interval": interval,
+		"period": gin.H{
+			"start": start.Format("2006-01-02"),
+			"end":   end.Format("2006-01-02"),
+		},
+	})
+}
+
+// GetUserSessions возвращает сессии посетителя; :user_id может быть и
+// анонимным идентификатором
+func (h *ProjectHandler) GetUserSessions(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	projectID := c.Param("id")
+	visitorID := c.Param("user_id")
+	if projectID == "" || visitorID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "project id and user id required"})
+		return
+	}
+
+	sessions, err := h.projectService.GetUserSessions(c.Request.Context(), projectID, userID.(string), visitorID)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrProjectAccessDenied):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, models.ErrProjectNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get user sessions"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user_id":  visitorID,
+		"sessions": sessions,
+	})
+}
@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/event-analytics-service/internal/models"
+	"github.com/yourusername/event-analytics-service/internal/service"
+)
+
+type SchemaHandler struct {
+	schemaService  *service.SchemaService
+	projectService *service.ProjectService
+}
+
+type SaveSchemaRequest struct {
+	Mode   models.SchemaMode             `json:"mode"`
+	Fields map[string]models.SchemaField `json:"fields" binding:"required"`
+}
+
+func NewSchemaHandler(schemaService *service.SchemaService, projectService *service.ProjectService) *SchemaHandler {
+	return &SchemaHandler{
+		schemaService:  schemaService,
+		projectService: projectService,
+	}
+}
+
+func (h *SchemaHandler) ListSchemas(c *gin.Context) {
+	projectID, ok := authorizeProject(c, h.projectService)
+	if !ok {
+		return
+	}
+
+	schemas, err := h.schemaService.ListSchemas(c.Request.Context(), projectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"schemas": schemas,
+		"total":   len(schemas),
+	})
+}
+
+func (h *SchemaHandler) GetSchema(c *gin.Context) {
+	projectID, ok := authorizeProject(c, h.projectService)
+	if !ok {
+		return
+	}
+
+	schema, err := h.schemaService.GetSchema(c.Request.Context(), projectID, c.Param("event_name"))
+	if err != nil {
+		if errors.Is(err, models.ErrSchemaNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, schema)
+}
+
+func (h *SchemaHandler) SaveSchema(c *gin.Context) {
+	projectID, ok := authorizeProject(c, h.projectService)
+	if !ok {
+		return
+	}
+
+	var req SaveSchemaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	schema := &models.EventSchema{
+		ProjectID: projectID,
+		EventName: c.Param("event_name"),
+		Mode:      req.Mode,
+		Fields:    req.Fields,
+	}
+
+	if err := h.schemaService.SaveSchema(c.Request.Context(), schema); err != nil {
+		if errors.Is(err, models.ErrInvalidSchema) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, schema)
+}
+
+func (h *SchemaHandler) DeleteSchema(c *gin.Context) {
+	projectID, ok := authorizeProject(c, h.projectService)
+	if !ok {
+		return
+	}
+
+	err := h.schemaService.DeleteSchema(c.Request.Context(), projectID, c.Param("event_name"))
+	if err != nil {
+		if errors.Is(err, models.ErrSchemaNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "schema deleted successfully"})
+}
+
+// authorizeProject проверяет, что проект из URL принадлежит текущему пользователю
+func authorizeProject(c *gin.Context, projectService *service.ProjectService) (string, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return "", false
+	}
+
+	projectID := c.Param("id")
+	if projectID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "project id required"})
+		return "", false
+	}
+
+	if _, err := projectService.GetProject(c.Request.Context(), projectID, userID.(string)); err != nil {
+		// Чужой проект не отличается от несуществующего
+		if errors.Is(err, models.ErrProjectNotFound) || errors.Is(err, models.ErrProjectAccessDenied) {
+			c.JSON(http.StatusNotFound, gin.H{"error": models.ErrProjectNotFound.Error()})
+			return "", false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get project"})
+		return "", false
+	}
+
+	return projectID, true
+}
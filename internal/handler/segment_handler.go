@@ -0,0 +1,194 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/event-analytics-service/internal/models"
+	"github.com/yourusername/event-analytics-service/internal/service"
+)
+
+// SegmentHandler реализует совместимый с Segment HTTP Tracking API прием
+// событий, чтобы переключиться на сервис без переразметки приложений
+type SegmentHandler struct {
+	eventService    *service.EventService
+	identityService *service.IdentityService
+}
+
+func NewSegmentHandler(eventService *service.EventService, identityService *service.IdentityService) *SegmentHandler {
+	return &SegmentHandler{
+		eventService:    eventService,
+		identityService: identityService,
+	}
+}
+
+func (h *SegmentHandler) Track(c *gin.Context)    { h.handleMessage(c, models.SegmentTrack) }
+func (h *SegmentHandler) Page(c *gin.Context)     { h.handleMessage(c, models.SegmentPage) }
+func (h *SegmentHandler) Screen(c *gin.Context)   { h.handleMessage(c, models.SegmentScreen) }
+func (h *SegmentHandler) Identify(c *gin.Context) { h.handleMessage(c, models.SegmentIdentify) }
+func (h *SegmentHandler) Alias(c *gin.Context)    { h.handleMessage(c, models.SegmentAlias) }
+
+func (h *SegmentHandler) handleMessage(c *gin.Context, messageType string) {
+	var msg models.SegmentMessage
+	if err := c.ShouldBindJSON(&msg); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	ctx, err := deliveryContext(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	msg.Type = messageType
+	h.fillContext(c, &msg, models.SegmentContext{})
+
+	switch messageType {
+	case models.SegmentIdentify, models.SegmentAlias:
+		err = h.storeIdentity(c, &msg)
+	default:
+		var event *models.Event
+		if event, err = msg.ToEvent(); err == nil {
+			event.ProjectID = c.GetString("project_id")
+			err = h.eventService.ProcessEvent(ctx, event)
+		}
+	}
+
+	if err != nil && !errors.Is(err, models.ErrDuplicateEvent) {
+		status := http.StatusInternalServerError
+		if isEventValidationError(err) {
+			status = http.StatusBadRequest
+		} else if errors.Is(err, models.ErrEventNotDelivered) {
+			status = http.StatusServiceUnavailable
+		} else if errors.Is(err, models.ErrRateLimitExceeded) {
+			status = http.StatusTooManyRequests
+		}
+		c.JSON(status, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// Batch принимает смешанный массив сообщений. События отправляются в Kafka
+// одним батчем, identify и alias сохраняются по одному. Невалидные сообщения
+// пропускаются, чтобы SDK не отбрасывал из-за них весь батч.
+func (h *SegmentHandler) Batch(c *gin.Context) {
+	ctx, err := deliveryContext(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxBatchBodySize+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "failed to read request body"})
+		return
+	}
+	if len(body) > maxBatchBodySize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"success": false, "error": models.ErrEventTooLarge.Error()})
+		return
+	}
+
+	var batch models.SegmentBatch
+	if err := json.Unmarshal(body, &batch); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if len(batch.Batch) > maxBatchSize {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "batch is too large"})
+		return
+	}
+
+	projectID := c.GetString("project_id")
+	events := make([]*models.Event, 0, len(batch.Batch))
+	rejected := 0
+	failed := false
+
+	for i := range batch.Batch {
+		msg := &batch.Batch[i]
+		h.fillContext(c, msg, batch.Context)
+		if msg.SentAt.IsZero() {
+			msg.SentAt = batch.SentAt
+		}
+
+		switch msg.Type {
+		case models.SegmentIdentify, models.SegmentAlias:
+			if err := h.storeIdentity(c, msg); err != nil {
+				if isEventValidationError(err) {
+					rejected++
+				} else {
+					failed = true
+				}
+			}
+		default:
+			event, err := msg.ToEvent()
+			if err != nil {
+				rejected++
+				continue
+			}
+			event.ProjectID = projectID
+			events = append(events, event)
+		}
+	}
+
+	if len(events) > 0 {
+		results, err := h.eventService.ProcessBatch(ctx, events)
+		if err != nil {
+			failed = true
+		}
+		for _, result := range results {
+			if result.Status == models.BatchStatusRejected {
+				rejected++
+			}
+		}
+	}
+
+	// 503 заставляет SDK повторить батч; повтор безопасен благодаря messageId
+	if failed {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": "failed to process batch"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "rejected": rejected})
+}
+
+func (h *SegmentHandler) storeIdentity(c *gin.Context, msg *models.SegmentMessage) error {
+	projectID := c.GetString("project_id")
+
+	if msg.Type == models.SegmentAlias {
+		return h.identityService.Alias(c.Request.Context(), &models.UserAlias{
+			ProjectID:  projectID,
+			PreviousID: msg.PreviousID,
+			UserID:     msg.UserID,
+			Timestamp:  msg.Timestamp,
+		})
+	}
+
+	return h.identityService.Identify(c.Request.Context(), &models.UserTraits{
+		ProjectID:   projectID,
+		UserID:      msg.UserID,
+		AnonymousID: msg.AnonymousID,
+		Traits:      msg.Traits,
+		Timestamp:   msg.Timestamp,
+	})
+}
+
+// fillContext дополняет контекст сообщения контекстом батча и данными запроса
+func (h *SegmentHandler) fillContext(c *gin.Context, msg *models.SegmentMessage, batchContext models.SegmentContext) {
+	if msg.Context.IP == "" {
+		msg.Context.IP = batchContext.IP
+	}
+	if msg.Context.IP == "" {
+		msg.Context.IP = c.ClientIP()
+	}
+	if msg.Context.UserAgent == "" {
+		msg.Context.UserAgent = batchContext.UserAgent
+	}
+	if msg.Context.UserAgent == "" {
+		msg.Context.UserAgent = c.GetHeader("User-Agent")
+	}
+}
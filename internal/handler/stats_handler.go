@@ -27,8 +27,8 @@ func (h *StatsHandler) GetStatistics(c *gin.Context) {
 	}
 
 	// Валидация обязательных полей
-	if req.EventType == "" || req.StartDate == "" || req.EndDate == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "event_type, start_date, end_date are required"})
+	if (req.EventType == "" && req.EventName == "") || req.StartDate == "" || req.EndDate == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "event_type or event_name, start_date, end_date are required"})
 		return
 	}
 
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/event-analytics-service/internal/models"
+	"github.com/yourusername/event-analytics-service/internal/service"
+)
+
+// Максимальное количество событий в dry-run запросе
+const maxDryRunEvents = 100
+
+type TransformHandler struct {
+	transformService *service.TransformService
+	projectService   *service.ProjectService
+}
+
+type SaveTransformsRequest struct {
+	Rules []models.TransformRule `json:"rules" binding:"required"`
+}
+
+// DryRunTransformsRequest - события для проверки правил; без rules
+// используются сохраненные правила проекта
+type DryRunTransformsRequest struct {
+	Rules  []models.TransformRule `json:"rules"`
+	Events []*models.Event        `json:"events" binding:"required"`
+}
+
+func NewTransformHandler(transformService *service.TransformService, projectService *service.ProjectService) *TransformHandler {
+	return &TransformHandler{
+		transformService: transformService,
+		projectService:   projectService,
+	}
+}
+
+func (h *TransformHandler) GetTransforms(c *gin.Context) {
+	projectID, ok := authorizeProject(c, h.projectService)
+	if !ok {
+		return
+	}
+
+	transforms, err := h.transformService.GetTransforms(c.Request.Context(), projectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, transforms)
+}
+
+func (h *TransformHandler) SaveTransforms(c *gin.Context) {
+	projectID, ok := authorizeProject(c, h.projectService)
+	if !ok {
+		return
+	}
+
+	var req SaveTransformsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	transforms := &models.ProjectTransforms{
+		ProjectID: projectID,
+		Rules:     req.Rules,
+	}
+
+	if err := h.transformService.SaveTransforms(c.Request.Context(), transforms); err != nil {
+		if errors.Is(err, models.ErrInvalidTransform) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, transforms)
+}
+
+// DryRunTransforms показывает, как правила изменят переданные события, ничего не сохраняя
+func (h *TransformHandler) DryRunTransforms(c *gin.Context) {
+	projectID, ok := authorizeProject(c, h.projectService)
+	if !ok {
+		return
+	}
+
+	var req DryRunTransformsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(req.Events) > maxDryRunEvents {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("dry run accepts at most %d events", maxDryRunEvents)})
+		return
+	}
+	for i, event := range req.Events {
+		if event == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("event %d is null", i)})
+			return
+		}
+	}
+
+	results, err := h.transformService.DryRun(c.Request.Context(), projectID, req.Rules, req.Events)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidTransform) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	dropped := 0
+	for _, result := range results {
+		if result.Dropped {
+			dropped++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"results": results,
+		"dropped": dropped,
+	})
+}
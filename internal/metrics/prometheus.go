@@ -1,6 +1,8 @@
 package metrics
 
 import (
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -18,11 +20,35 @@ type Metrics struct {
 	eventsProcessed     *prometheus.CounterVec
 	eventsFailed        *prometheus.CounterVec
 	eventProcessingTime *prometheus.HistogramVec
+	eventsRateLimited   *prometheus.CounterVec
+	botEvents           *prometheus.CounterVec
+	eventsSampledOut    *prometheus.CounterVec
+	eventsTransformDrop *prometheus.CounterVec
+	ingestionLatency    *prometheus.HistogramVec
 
 	// Kafka метрики
 	kafkaMessagesPublished *prometheus.CounterVec
 	kafkaMessagesConsumed  *prometheus.CounterVec
 	kafkaConsumerLag       *prometheus.GaugeVec
+	kafkaPublishErrors     *prometheus.CounterVec
+
+	// Spool метрики
+	spoolMessages        prometheus.Gauge
+	spoolBytes           prometheus.Gauge
+	spoolOldestAge       prometheus.Gauge
+	spoolMessagesWritten *prometheus.CounterVec
+	spoolReplayed        prometheus.Counter
+	spoolDropped         *prometheus.CounterVec
+
+	// Dead-letter метрики
+	dlqMessages      *prometheus.CounterVec
+	dlqPublishErrors prometheus.Counter
+	dlqResolved      *prometheus.CounterVec
+
+	// Retry метрики
+	retryQueueDepth     prometheus.Gauge
+	retryQueueOldestAge prometheus.Gauge
+	retryAttempts       *prometheus.CounterVec
 
 	// Cache метрики
 	cacheHits   *prometheus.CounterVec
@@ -38,6 +64,7 @@ type Metrics struct {
 	uniqueUsers    *prometheus.GaugeVec
 
 	// Custom counters for generic metrics
+	customMu         sync.Mutex
 	customCounters   map[string]prometheus.Counter
 	customGauges     map[string]prometheus.Gauge
 	customHistograms map[string]prometheus.Histogram
@@ -116,6 +143,52 @@ func NewMetrics(serviceName string) *Metrics {
 		[]string{"event_type"},
 	)
 
+	m.eventsRateLimited = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:        "events_rate_limited_total",
+			Help:        "Total number of ingestion requests that hit rate limit or monthly quota",
+			ConstLabels: prometheus.Labels{"service": serviceName},
+		},
+		[]string{"project_id", "limit_type"},
+	)
+
+	m.botEvents = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:        "events_bot_total",
+			Help:        "Total number of events classified as bot traffic",
+			ConstLabels: prometheus.Labels{"service": serviceName},
+		},
+		[]string{"reason"},
+	)
+
+	m.eventsSampledOut = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:        "events_sampled_out_total",
+			Help:        "Total number of accepted events dropped by project sampling",
+			ConstLabels: prometheus.Labels{"service": serviceName},
+		},
+		[]string{"event_type", "project_id"},
+	)
+
+	m.eventsTransformDrop = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:        "events_transform_dropped_total",
+			Help:        "Total number of consumed events dropped by project transform rules",
+			ConstLabels: prometheus.Labels{"service": serviceName},
+		},
+		[]string{"project_id"},
+	)
+
+	m.ingestionLatency = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:        "event_ingestion_latency_seconds",
+			Help:        "Time from event receipt by the API to its insert into ClickHouse",
+			Buckets:     []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60, 300},
+			ConstLabels: prometheus.Labels{"service": serviceName},
+		},
+		[]string{"project_id"},
+	)
+
 	// Kafka
 	m.kafkaMessagesPublished = promauto.NewCounterVec(
 		prometheus.CounterOpts{
@@ -144,6 +217,119 @@ func NewMetrics(serviceName string) *Metrics {
 		[]string{"topic", "partition"},
 	)
 
+	m.kafkaPublishErrors = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:        "kafka_publish_errors_total",
+			Help:        "Total number of Kafka messages the broker failed to accept",
+			ConstLabels: prometheus.Labels{"service": serviceName},
+		},
+		[]string{"topic", "mode"},
+	)
+
+	// Spool
+	m.spoolMessages = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name:        "producer_spool_messages",
+			Help:        "Number of messages waiting in the local spool",
+			ConstLabels: prometheus.Labels{"service": serviceName},
+		},
+	)
+
+	m.spoolBytes = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name:        "producer_spool_bytes",
+			Help:        "Size of the local spool on disk in bytes",
+			ConstLabels: prometheus.Labels{"service": serviceName},
+		},
+	)
+
+	m.spoolOldestAge = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name:        "producer_spool_oldest_age_seconds",
+			Help:        "Age of the oldest message waiting in the local spool",
+			ConstLabels: prometheus.Labels{"service": serviceName},
+		},
+	)
+
+	m.spoolMessagesWritten = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:        "producer_spool_written_total",
+			Help:        "Total number of messages written to the local spool",
+			ConstLabels: prometheus.Labels{"service": serviceName},
+		},
+		[]string{"reason"},
+	)
+
+	m.spoolReplayed = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name:        "producer_spool_replayed_total",
+			Help:        "Total number of spooled messages delivered to Kafka",
+			ConstLabels: prometheus.Labels{"service": serviceName},
+		},
+	)
+
+	m.spoolDropped = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:        "producer_spool_dropped_total",
+			Help:        "Total number of messages lost because they could not be spooled",
+			ConstLabels: prometheus.Labels{"service": serviceName},
+		},
+		[]string{"reason"},
+	)
+
+	// Dead-letter
+	m.dlqMessages = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:        "dlq_messages_total",
+			Help:        "Total number of events sent to the dead-letter topic",
+			ConstLabels: prometheus.Labels{"service": serviceName},
+		},
+		[]string{"reason"},
+	)
+
+	m.dlqPublishErrors = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name:        "dlq_publish_errors_total",
+			Help:        "Total number of events that could not be written to the dead-letter topic",
+			ConstLabels: prometheus.Labels{"service": serviceName},
+		},
+	)
+
+	m.dlqResolved = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:        "dlq_resolved_total",
+			Help:        "Total number of dead-lettered events replayed or purged",
+			ConstLabels: prometheus.Labels{"service": serviceName},
+		},
+		[]string{"action"},
+	)
+
+	// Retry
+	m.retryQueueDepth = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name:        "consumer_retry_queue_depth",
+			Help:        "Number of events waiting in the retry queue",
+			ConstLabels: prometheus.Labels{"service": serviceName},
+		},
+	)
+
+	m.retryQueueOldestAge = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name:        "consumer_retry_queue_oldest_age_seconds",
+			Help:        "Time since the first failure of the oldest event in the retry queue",
+			ConstLabels: prometheus.Labels{"service": serviceName},
+		},
+	)
+
+	m.retryAttempts = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:        "consumer_retry_attempts_total",
+			Help:        "Total number of event insert retries by result",
+			ConstLabels: prometheus.Labels{"service": serviceName},
+		},
+		[]string{"result"},
+	)
+
 	// Cache
 	m.cacheHits = promauto.NewCounterVec(
 		prometheus.CounterOpts{
@@ -240,6 +426,26 @@ func (m *Metrics) ObserveEventProcessing(eventType string, duration time.Duratio
 	m.eventProcessingTime.WithLabelValues(eventType).Observe(duration.Seconds())
 }
 
+func (m *Metrics) IncrementRateLimited(projectID, limitType string) {
+	m.eventsRateLimited.WithLabelValues(projectID, limitType).Inc()
+}
+
+func (m *Metrics) IncrementEventsSampledOut(eventType, projectID string) {
+	m.eventsSampledOut.WithLabelValues(eventType, projectID).Inc()
+}
+
+func (m *Metrics) AddEventsTransformDropped(projectID string, count int) {
+	m.eventsTransformDrop.WithLabelValues(projectID).Add(float64(count))
+}
+
+func (m *Metrics) ObserveIngestionLatency(projectID string, latency time.Duration) {
+	m.ingestionLatency.WithLabelValues(projectID).Observe(latency.Seconds())
+}
+
+func (m *Metrics) IncrementBotEvents(reason string) {
+	m.botEvents.WithLabelValues(reason).Inc()
+}
+
 func (m *Metrics) IncrementCacheHit(cacheName string) {
 	m.cacheHits.WithLabelValues(cacheName).Inc()
 }
@@ -260,6 +466,10 @@ func (m *Metrics) IncrementKafkaMessagesPublished(topic string) {
 	m.kafkaMessagesPublished.WithLabelValues(topic).Inc()
 }
 
+func (m *Metrics) AddKafkaMessagesPublished(topic string, count int) {
+	m.kafkaMessagesPublished.WithLabelValues(topic).Add(float64(count))
+}
+
 func (m *Metrics) IncrementKafkaMessagesConsumed(topic, partition string) {
 	m.kafkaMessagesConsumed.WithLabelValues(topic, partition).Inc()
 }
@@ -268,8 +478,62 @@ func (m *Metrics) SetKafkaConsumerLag(topic, partition string, lag float64) {
 	m.kafkaConsumerLag.WithLabelValues(topic, partition).Set(lag)
 }
 
+func (m *Metrics) AddKafkaPublishErrors(topic, mode string, count int) {
+	m.kafkaPublishErrors.WithLabelValues(topic, mode).Add(float64(count))
+}
+
+func (m *Metrics) SetSpoolState(messages, bytes int64, oldestAge time.Duration) {
+	m.spoolMessages.Set(float64(messages))
+	m.spoolBytes.Set(float64(bytes))
+	m.spoolOldestAge.Set(oldestAge.Seconds())
+}
+
+func (m *Metrics) AddSpoolWritten(reason string, count int) {
+	m.spoolMessagesWritten.WithLabelValues(reason).Add(float64(count))
+}
+
+func (m *Metrics) AddSpoolReplayed(count int) {
+	m.spoolReplayed.Add(float64(count))
+}
+
+func (m *Metrics) AddSpoolDropped(reason string, count int) {
+	m.spoolDropped.WithLabelValues(reason).Add(float64(count))
+}
+
+func (m *Metrics) AddDLQMessages(reason string, count int) {
+	m.dlqMessages.WithLabelValues(reason).Add(float64(count))
+}
+
+func (m *Metrics) AddDLQPublishErrors(count int) {
+	m.dlqPublishErrors.Add(float64(count))
+}
+
+func (m *Metrics) AddDLQResolved(action string, count int) {
+	m.dlqResolved.WithLabelValues(action).Add(float64(count))
+}
+
+func (m *Metrics) SetRetryQueueState(depth int64, oldestAge time.Duration) {
+	m.retryQueueDepth.Set(float64(depth))
+	m.retryQueueOldestAge.Set(oldestAge.Seconds())
+}
+
+func (m *Metrics) AddRetryAttempts(result string, count int) {
+	m.retryAttempts.WithLabelValues(result).Add(float64(count))
+}
+
 // Generic metric methods
+
+// customMetricName приводит имя вида "consumer.events.received" к допустимому
+// в Prometheus "consumer_events_received"
+func customMetricName(name string) string {
+	return strings.NewReplacer(".", "_", "-", "_").Replace(name)
+}
+
 func (m *Metrics) Increment(name string) {
+	name = customMetricName(name)
+	m.customMu.Lock()
+	defer m.customMu.Unlock()
+
 	counter, exists := m.customCounters[name]
 	if !exists {
 		counter = promauto.NewCounter(prometheus.CounterOpts{
@@ -282,6 +546,10 @@ func (m *Metrics) Increment(name string) {
 }
 
 func (m *Metrics) IncrementBy(name string, value int64) {
+	name = customMetricName(name)
+	m.customMu.Lock()
+	defer m.customMu.Unlock()
+
 	counter, exists := m.customCounters[name]
 	if !exists {
 		counter = promauto.NewCounter(prometheus.CounterOpts{
@@ -294,6 +562,10 @@ func (m *Metrics) IncrementBy(name string, value int64) {
 }
 
 func (m *Metrics) Timing(name string, duration time.Duration) {
+	name = customMetricName(name)
+	m.customMu.Lock()
+	defer m.customMu.Unlock()
+
 	histogram, exists := m.customHistograms[name]
 	if !exists {
 		histogram = promauto.NewHistogram(prometheus.HistogramOpts{
@@ -307,6 +579,10 @@ func (m *Metrics) Timing(name string, duration time.Duration) {
 }
 
 func (m *Metrics) Observe(name string, value float64) {
+	name = customMetricName(name)
+	m.customMu.Lock()
+	defer m.customMu.Unlock()
+
 	histogram, exists := m.customHistograms[name]
 	if !exists {
 		histogram = promauto.NewHistogram(prometheus.HistogramOpts{
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 	"time"
@@ -8,17 +9,20 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/yourusername/event-analytics-service/internal/models"
+	"github.com/yourusername/event-analytics-service/internal/service"
 )
 
 type AuthMiddleware struct {
-	jwtSecret     []byte
-	tokenDuration time.Duration
+	jwtSecret      []byte
+	tokenDuration  time.Duration
+	projectService *service.ProjectService
 }
 
-func NewAuthMiddleware(secret string, duration time.Duration) *AuthMiddleware {
+func NewAuthMiddleware(secret string, duration time.Duration, projectService *service.ProjectService) *AuthMiddleware {
 	return &AuthMiddleware{
-		jwtSecret:     []byte(secret),
-		tokenDuration: duration,
+		jwtSecret:      []byte(secret),
+		tokenDuration:  duration,
+		projectService: projectService,
 	}
 }
 
@@ -76,23 +80,78 @@ func (m *AuthMiddleware) ValidateToken() gin.HandlerFunc {
 
 func (m *AuthMiddleware) ValidateAPIKey() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		apiKey := c.GetHeader("X-API-Key")
+		apiKey := apiKeyFromRequest(c)
 		if apiKey == "" {
-			apiKey = c.Query("api_key")
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "API key required"})
+			c.Abort()
+			return
 		}
 
-		if apiKey == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "API key required"})
+		project, err := m.resolveProject(c, apiKey)
+		if err != nil {
+			if errors.Is(err, models.ErrInvalidAPIKey) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": models.ErrInvalidAPIKey.Error()})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to validate API key"})
+			}
 			c.Abort()
 			return
 		}
 
-		// Здесь будет проверка API ключа в БД
-		c.Set("api_key", apiKey)
+		// Проект определяется только по ключу, project_id из тела запроса игнорируется
+		setProject(c, apiKey, project)
+		c.Next()
+	}
+}
+
+// ResolveAPIKey определяет проект по API ключу, но не прерывает запрос при
+// отсутствии или ошибке ключа: project_id в контексте просто не заполняется.
+// Нужен для эндпоинтов, которые должны отвечать одинаково при любой ошибке.
+func (m *AuthMiddleware) ResolveAPIKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		apiKey := apiKeyFromRequest(c)
+		if apiKey != "" {
+			if project, err := m.resolveProject(c, apiKey); err == nil {
+				setProject(c, apiKey, project)
+			}
+		}
 		c.Next()
 	}
 }
 
+// apiKeyFromRequest читает ключ из X-API-Key, параметра api_key или Basic
+// авторизации (ключ в имени пользователя, как write key в Segment)
+func apiKeyFromRequest(c *gin.Context) string {
+	apiKey := c.GetHeader("X-API-Key")
+	if apiKey == "" {
+		apiKey = c.Query("api_key")
+	}
+	if apiKey == "" {
+		if username, _, ok := c.Request.BasicAuth(); ok {
+			apiKey = username
+		}
+	}
+	return apiKey
+}
+
+// resolveProject возвращает активный проект по ключу или ErrInvalidAPIKey
+func (m *AuthMiddleware) resolveProject(c *gin.Context, apiKey string) (*models.Project, error) {
+	project, err := m.projectService.ValidateAPIKey(c.Request.Context(), apiKey)
+	if err != nil {
+		return nil, err
+	}
+	if !project.Active {
+		return nil, models.ErrInvalidAPIKey
+	}
+	return project, nil
+}
+
+func setProject(c *gin.Context, apiKey string, project *models.Project) {
+	c.Set("api_key", apiKey)
+	c.Set("project", project)
+	c.Set("project_id", project.ID)
+}
+
 func (m *AuthMiddleware) RequireRole(roles ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userRole, exists := c.Get("user_role")
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/event-analytics-service/internal/metrics"
+	"github.com/yourusername/event-analytics-service/internal/models"
+	"github.com/yourusername/event-analytics-service/internal/service"
+)
+
+// RateLimitMiddleware ограничивает число запросов в секунду и месячную квоту
+// событий проекта. Должен стоять после ValidateAPIKey. При недоступности
+// Redis запросы пропускаются.
+func RateLimitMiddleware(quotaService *service.QuotaService, m *metrics.Metrics) gin.HandlerFunc {
+	return rateLimit(quotaService, m, rejectRateLimited)
+}
+
+// SoftRateLimitMiddleware проверяет те же лимиты, но вместо ответа 429
+// помечает запрос флагом "rate_limited" и передает его дальше
+func SoftRateLimitMiddleware(quotaService *service.QuotaService, m *metrics.Metrics) gin.HandlerFunc {
+	return rateLimit(quotaService, m, markRateLimited)
+}
+
+// rateLimit проверяет лимиты проекта; reject вызывается при их превышении
+// и сам решает, прерывать ли запрос
+func rateLimit(quotaService *service.QuotaService, m *metrics.Metrics, reject func(*gin.Context, *models.RateLimitStatus)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, exists := c.Get("project")
+		if !exists {
+			c.Next()
+			return
+		}
+		project := value.(*models.Project)
+		ctx := c.Request.Context()
+
+		if limit, err := quotaService.CheckRateLimit(ctx, project); err == nil {
+			setRateLimitHeaders(c, "X-RateLimit", limit)
+			if limit.Exceeded {
+				m.IncrementRateLimited(project.ID, "requests_per_second")
+				reject(c, limit)
+				return
+			}
+		}
+
+		if quota, err := quotaService.CheckQuota(ctx, project); err == nil {
+			setRateLimitHeaders(c, "X-Quota", quota)
+			if quota.Exceeded {
+				m.IncrementRateLimited(project.ID, "monthly_quota")
+				if quotaService.QuotaMode(project) == models.QuotaModeHard {
+					reject(c, quota)
+					return
+				}
+
+				// Мягкий режим: события принимаются, превышение помечается
+				c.Header("X-Quota-Exceeded", "true")
+				c.Set("quota_exceeded", true)
+			}
+		}
+
+		c.Next()
+	}
+}
+
+func setRateLimitHeaders(c *gin.Context, prefix string, status *models.RateLimitStatus) {
+	c.Header(prefix+"-Limit", strconv.FormatInt(status.Limit, 10))
+	c.Header(prefix+"-Remaining", strconv.FormatInt(status.Remaining, 10))
+	c.Header(prefix+"-Reset", strconv.FormatInt(status.Reset.Unix(), 10))
+}
+
+func rejectRateLimited(c *gin.Context, status *models.RateLimitStatus) {
+	retryAfter := int64(math.Ceil(time.Until(status.Reset).Seconds()))
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+
+	c.Header("Retry-After", strconv.FormatInt(retryAfter, 10))
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": models.ErrRateLimitExceeded.Error()})
+	c.Abort()
+}
+
+func markRateLimited(c *gin.Context, status *models.RateLimitStatus) {
+	c.Set("rate_limited", true)
+	c.Next()
+}
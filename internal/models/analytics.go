@@ -22,11 +22,42 @@ type UserSession struct {
 // FunnelStep represents a step in conversion funnel
 type FunnelStep struct {
 	StepName   string  `json:"step_name"`
-	EventType  string  `json:"event_type"`
+	EventName  string  `json:"event_name"`
 	UserCount  int64   `json:"user_count"`
 	Conversion float64 `json:"conversion_rate"`
 }
 
+// TrafficSource - сессии и конверсии по источнику, каналу и кампании
+type TrafficSource struct {
+	Source         string  `json:"source"`
+	Medium         string  `json:"medium"`
+	Campaign       string  `json:"campaign"`
+	Sessions       int64   `json:"sessions"`
+	Conversions    int64   `json:"conversions"`
+	ConversionRate float64 `json:"conversion_rate"`
+}
+
+// IngestionLatency - задержка приема событий в секундах: от приема API
+// (received_at) до записи в ClickHouse (processed_at), и 95-й перцентиль
+// этапов: запись в Kafka, ожидание в Kafka, обработка консьюмером
+type IngestionLatency struct {
+	TimeBucket string  `json:"time_bucket,omitempty"`
+	Events     int64   `json:"events"`
+	P50        float64 `json:"p50"`
+	P95        float64 `json:"p95"`
+	P99        float64 `json:"p99"`
+	Max        float64 `json:"max"`
+	PublishP95 float64 `json:"publish_p95"`
+	QueueP95   float64 `json:"queue_p95"`
+	ProcessP95 float64 `json:"process_p95"`
+}
+
+// IngestionLatencyReport - задержка приема за период и по интервалам
+type IngestionLatencyReport struct {
+	Total   IngestionLatency   `json:"total"`
+	Buckets []IngestionLatency `json:"buckets"`
+}
+
 // ProjectStats represents project statistics
 type ProjectStats struct {
 	UniqueUsers int64     `json:"unique_users"`
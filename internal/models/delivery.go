@@ -0,0 +1,37 @@
+package models
+
+import "fmt"
+
+// DeliveryMode определяет, когда API отвечает клиенту на прием события
+type DeliveryMode string
+
+const (
+	// Ответ сразу после постановки события в буфер producer
+	DeliveryModeAsync DeliveryMode = "async"
+	// Ответ только после подтверждения записи брокером Kafka
+	DeliveryModeSync DeliveryMode = "sync"
+)
+
+// ParseDeliveryMode разбирает режим из заголовка запроса или настроек проекта
+func ParseDeliveryMode(value string) (DeliveryMode, error) {
+	switch mode := DeliveryMode(value); mode {
+	case "", DeliveryModeAsync, DeliveryModeSync:
+		return mode, nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrInvalidDeliveryMode, value)
+}
+
+// DeliveryMode возвращает режим доставки по умолчанию для событий проекта,
+// хранится в Settings["delivery_mode"]
+func (p *Project) DeliveryMode() (DeliveryMode, error) {
+	raw, ok := p.Settings["delivery_mode"]
+	if !ok || raw == nil {
+		return "", nil
+	}
+
+	value, ok := raw.(string)
+	if !ok {
+		return "", fmt.Errorf("%w: expected string", ErrInvalidDeliveryMode)
+	}
+	return ParseDeliveryMode(value)
+}
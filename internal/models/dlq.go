@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+// DeadLetter - событие из dead-letter топика, которое консьюмер не смог сохранить
+type DeadLetter struct {
+	Partition int       `json:"partition"`
+	Offset    int64     `json:"offset"`
+	Reason    string    `json:"reason"`
+	Error     string    `json:"error,omitempty"`
+	FailedAt  time.Time `json:"failed_at"`
+
+	// Откуда событие было прочитано консьюмером
+	SourceTopic     string `json:"source_topic,omitempty"`
+	SourcePartition int    `json:"source_partition"`
+	SourceOffset    int64  `json:"source_offset"`
+
+	ProjectID string `json:"project_id,omitempty"`
+	EventType string `json:"event_type,omitempty"`
+
+	// Event не заполнено, если сообщение не удалось разобрать; тогда
+	// исходное содержимое находится в Raw
+	Event *Event `json:"event,omitempty"`
+	Raw   string `json:"raw,omitempty"`
+}
+
+// DeadLetterFilter отбирает события dead-letter топика; пустые поля не проверяются
+type DeadLetterFilter struct {
+	ProjectID string    `json:"project_id" form:"project_id"`
+	EventType string    `json:"event_type" form:"event_type"`
+	Reason    string    `json:"reason" form:"reason"`
+	Since     time.Time `json:"since" form:"since" time_format:"2006-01-02T15:04:05Z07:00"`
+	Until     time.Time `json:"until" form:"until" time_format:"2006-01-02T15:04:05Z07:00"`
+	Limit     int       `json:"limit" form:"limit" binding:"omitempty,min=0"`
+}
+
+// Empty сообщает, что фильтр отбирает все события
+func (f DeadLetterFilter) Empty() bool {
+	return f.ProjectID == "" && f.EventType == "" && f.Reason == "" && f.Since.IsZero() && f.Until.IsZero()
+}
+
+// Match проверяет событие по фильтру (без учета Limit)
+func (f DeadLetterFilter) Match(letter *DeadLetter) bool {
+	if f.ProjectID != "" && letter.ProjectID != f.ProjectID {
+		return false
+	}
+	if f.EventType != "" && letter.EventType != f.EventType {
+		return false
+	}
+	if f.Reason != "" && letter.Reason != f.Reason {
+		return false
+	}
+	if !f.Since.IsZero() && letter.FailedAt.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && letter.FailedAt.After(f.Until) {
+		return false
+	}
+	return true
+}
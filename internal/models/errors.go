@@ -18,11 +18,31 @@ var (
 	ErrProjectLimitReached = errors.New("project limit reached")
 	ErrInvalidAPIKey       = errors.New("invalid API key")
 
+	// Privacy errors
+	ErrInvalidPrivacySettings = errors.New("invalid privacy settings")
+
+	// Sampling errors
+	ErrInvalidSamplingSettings = errors.New("invalid sampling settings")
+
 	// Event errors
 	ErrInvalidEventType  = errors.New("invalid event type")
+	ErrInvalidEventName  = errors.New("invalid event name")
 	ErrInvalidEventData  = errors.New("invalid event data")
 	ErrEventTooLarge     = errors.New("event too large")
 	ErrRateLimitExceeded = errors.New("rate limit exceeded")
+	ErrDuplicateEvent    = errors.New("duplicate event")
+	ErrInvalidTimestamp  = errors.New("event timestamp outside acceptance window")
+
+	// Delivery errors
+	ErrInvalidDeliveryMode = errors.New("invalid delivery mode")
+	ErrEventNotDelivered   = errors.New("event was not acknowledged by broker")
+
+	// Schema errors
+	ErrSchemaNotFound = errors.New("event schema not found")
+	ErrInvalidSchema  = errors.New("invalid event schema")
+
+	// Transform errors
+	ErrInvalidTransform = errors.New("invalid transform rules")
 
 	// Database errors
 	ErrDatabaseConnection = errors.New("database connection error")
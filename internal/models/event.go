@@ -19,14 +19,70 @@ type Event struct {
 	ProjectID string                 `json:"project_id" db:"project_id"`
 	UserID    string                 `json:"user_id" db:"user_id"`
 	EventType EventType              `json:"event_type" db:"event_type"`
+	EventName string                 `json:"event_name" db:"event_name"`
 	PageURL   string                 `json:"page_url" db:"page_url"`
 	Metadata  map[string]interface{} `json:"metadata" db:"metadata"`
 	UserAgent string                 `json:"user_agent" db:"user_agent"`
 	IPAddress string                 `json:"ip_address" db:"ip_address"`
 	Timestamp time.Time              `json:"timestamp" db:"timestamp"`
 
+	// Идентификатор устройства до входа пользователя. Если user_id не
+	// передан, событие записывается под anonymous_id; связь anonymous_id ->
+	// user_id сохраняется вызовом identify
+	AnonymousID string `json:"anonymous_id,omitempty" db:"anonymous_id"`
+
+	// sent_at - время отправки по часам клиента; по разнице с received_at
+	// (временем приема, его выставляет только сервер) корректируется timestamp
+	SentAt     time.Time `json:"sent_at" db:"-"`
+	ReceivedAt time.Time `json:"received_at" db:"received_at"`
+
+	// Заполняются из User-Agent при приеме события
+	DeviceType string `json:"device_type,omitempty" db:"device_type"`
+	Browser    string `json:"browser,omitempty" db:"browser"`
+	OS         string `json:"os,omitempty" db:"os"`
+
+	// Событие бота, краулера или сервиса мониторинга; такие события
+	// по умолчанию не учитываются в статистике
+	IsBot bool `json:"is_bot" db:"is_bot"`
+
+	// Доля выборки, с которой событие было сохранено (1 - без выборки);
+	// агрегаты умножают каждое событие на 1 / sample_rate
+	SampleRate float64 `json:"sample_rate,omitempty" db:"sample_rate"`
+
+	// Источник перехода: referrer из события (у пикселя - и из заголовка Referer), метки
+	// UTM и идентификаторы кликов рекламных систем из page_url
+	Referrer    string `json:"referrer,omitempty" db:"referrer"`
+	UTMSource   string `json:"utm_source,omitempty" db:"utm_source"`
+	UTMMedium   string `json:"utm_medium,omitempty" db:"utm_medium"`
+	UTMCampaign string `json:"utm_campaign,omitempty" db:"utm_campaign"`
+	UTMTerm     string `json:"utm_term,omitempty" db:"utm_term"`
+	UTMContent  string `json:"utm_content,omitempty" db:"utm_content"`
+	GCLID       string `json:"gclid,omitempty" db:"gclid"`
+	FBCLID      string `json:"fbclid,omitempty" db:"fbclid"`
+	MSCLKID     string `json:"msclkid,omitempty" db:"msclkid"`
+
+	// Заполняются консьюмером по IP адресу из базы GeoIP
+	CountryCode string `json:"country_code,omitempty" db:"country_code"`
+	Region      string `json:"region,omitempty" db:"region"`
+
 	// Kafka metadata
 	KafkaMetadata KafkaMetadata `json:"-" db:"-"`
+
+	// Предупреждения проверки схемы (режим warn), возвращаются клиенту
+	Warnings []ValidationError `json:"-" db:"-"`
+}
+
+// Что делать с событием, время которого после коррекции выходит за окно приема
+const (
+	TimestampPolicyClamp  = "clamp"  // время сдвигается на границу окна
+	TimestampPolicyReject = "reject" // событие отклоняется
+)
+
+// TimestampWindow - допустимое отклонение времени события от времени приема
+type TimestampWindow struct {
+	MaxPast   time.Duration
+	MaxFuture time.Duration
+	Policy    string
 }
 
 type KafkaMetadata struct {
@@ -39,13 +95,37 @@ type KafkaMetadata struct {
 
 type StatsRequest struct {
 	EventType EventType `form:"event_type"`
+	EventName string    `form:"event_name"`
 	StartDate string    `form:"start_date"`
 	EndDate   string    `form:"end_date"`
 	GroupBy   string    `form:"group_by"` // hour, day, month
+	Country   string    `form:"country" binding:"omitempty,len=2"`
+	Breakdown string    `form:"breakdown" binding:"omitempty,oneof=device_type browser os country region"`
+	// События ботов исключаются, если не запрошены явно
+	IncludeBots bool `form:"include_bots"`
 }
 
 type EventStats struct {
 	TimeBucket string `json:"time_bucket" db:"time_bucket"`
 	EventType  string `json:"event_type" db:"event_type"`
+	EventName  string `json:"event_name" db:"event_name"`
+	Dimension  string `json:"dimension,omitempty" db:"dimension"`
 	Count      int64  `json:"count" db:"count"`
 }
+
+// Результат обработки одного события в batch-запросе
+type BatchEventResult struct {
+	Index   int    `json:"index"`
+	EventID string `json:"event_id,omitempty"`
+	Status  string `json:"status"` // accepted, duplicate, rejected
+	Error   string `json:"error,omitempty"`
+
+	Errors   []ValidationError `json:"errors,omitempty"`
+	Warnings []ValidationError `json:"warnings,omitempty"`
+}
+
+const (
+	BatchStatusAccepted  = "accepted"
+	BatchStatusDuplicate = "duplicate"
+	BatchStatusRejected  = "rejected"
+)
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// UserTraits - атрибуты пользователя из вызова identify
+type UserTraits struct {
+	ProjectID   string                 `json:"project_id" db:"project_id"`
+	UserID      string                 `json:"user_id" db:"user_id"`
+	AnonymousID string                 `json:"anonymous_id" db:"anonymous_id"`
+	Traits      map[string]interface{} `json:"traits" db:"traits"`
+	Timestamp   time.Time              `json:"timestamp" db:"timestamp"`
+}
+
+// UserAlias связывает предыдущий идентификатор пользователя с новым
+type UserAlias struct {
+	ProjectID  string    `json:"project_id" db:"project_id"`
+	PreviousID string    `json:"previous_id" db:"previous_id"`
+	UserID     string    `json:"user_id" db:"user_id"`
+	Timestamp  time.Time `json:"timestamp" db:"timestamp"`
+}
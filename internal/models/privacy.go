@@ -0,0 +1,55 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Режимы обработки IP адреса
+const (
+	IPModeKeep     = ""         // IP сохраняется как есть
+	IPModeTruncate = "truncate" // обнуляется последний октет IPv4 и последние 80 бит IPv6
+	IPModeHash     = "hash"     // IP заменяется хэшем
+)
+
+// PrivacySettings - правила обработки персональных данных проекта,
+// хранятся в Settings["privacy"]
+type PrivacySettings struct {
+	IPMode       string   `json:"ip_mode"`
+	DropKeys     []string `json:"drop_keys"`
+	HashKeys     []string `json:"hash_keys"`
+	RedactEmails bool     `json:"redact_emails"`
+	RedactPhones bool     `json:"redact_phones"`
+}
+
+// Enabled сообщает, нужно ли вообще обрабатывать события проекта
+func (p PrivacySettings) Enabled() bool {
+	return p.IPMode != IPModeKeep || len(p.DropKeys) > 0 || len(p.HashKeys) > 0 ||
+		p.RedactEmails || p.RedactPhones
+}
+
+// Privacy разбирает настройки приватности проекта
+func (p *Project) Privacy() (PrivacySettings, error) {
+	var settings PrivacySettings
+
+	raw, ok := p.Settings["privacy"]
+	if !ok || raw == nil {
+		return settings, nil
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return settings, fmt.Errorf("%w: %v", ErrInvalidPrivacySettings, err)
+	}
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return settings, fmt.Errorf("%w: %v", ErrInvalidPrivacySettings, err)
+	}
+
+	switch settings.IPMode {
+	case IPModeKeep, IPModeTruncate, IPModeHash:
+	default:
+		return settings, fmt.Errorf("%w: unknown ip_mode %q", ErrInvalidPrivacySettings, settings.IPMode)
+	}
+
+	return settings, nil
+}
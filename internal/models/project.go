@@ -8,14 +8,76 @@ import (
 type JSON map[string]interface{}
 
 type Project struct {
-	ID        string    `json:"id" db:"id"`
-	Name      string    `json:"name" db:"name"`
-	APIKey    string    `json:"api_key" db:"api_key"`
-	UserID    string    `json:"user_id" db:"user_id"`
-	Settings  JSON      `json:"settings" db:"settings"`
-	Active    bool      `json:"active" db:"active"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	ID          string    `json:"id" db:"id"`
+	Name        string    `json:"name" db:"name"`
+	APIKey      string    `json:"api_key" db:"api_key"`
+	UserID      string    `json:"user_id" db:"user_id"`
+	Settings    JSON      `json:"settings" db:"settings"`
+	Active      bool      `json:"active" db:"active"`
+	PlanType    string    `json:"plan_type" db:"plan_type"`
+	EventsLimit int64     `json:"events_limit" db:"events_limit"`
+	EventsCount int64     `json:"events_count" db:"events_count"` // события текущего месяца, синхронизируются из Redis
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Тарифные планы
+const (
+	PlanFree       = "free"
+	PlanPro        = "pro"
+	PlanEnterprise = "enterprise"
+)
+
+// Режимы квоты: hard - события сверх квоты отклоняются, soft - принимаются с пометкой
+const (
+	QuotaModeHard = "hard"
+	QuotaModeSoft = "soft"
+)
+
+// Plan описывает лимиты тарифного плана
+type Plan struct {
+	Name              string
+	RequestsPerSecond int
+	MonthlyEvents     int64
+}
+
+var plans = map[string]Plan{
+	PlanFree:       {Name: PlanFree, RequestsPerSecond: 10, MonthlyEvents: 1000000},
+	PlanPro:        {Name: PlanPro, RequestsPerSecond: 100, MonthlyEvents: 50000000},
+	PlanEnterprise: {Name: PlanEnterprise, RequestsPerSecond: 1000, MonthlyEvents: 1000000000},
+}
+
+// GetPlan возвращает лимиты плана; неизвестный план считается бесплатным
+func GetPlan(planType string) Plan {
+	if plan, ok := plans[planType]; ok {
+		return plan
+	}
+	return plans[PlanFree]
+}
+
+// MonthlyQuota возвращает месячную квоту событий проекта: events_limit
+// проекта, а если он не задан - квоту тарифного плана
+func (p *Project) MonthlyQuota() int64 {
+	if p.EventsLimit > 0 {
+		return p.EventsLimit
+	}
+	return GetPlan(p.PlanType).MonthlyEvents
+}
+
+// QuotaMode возвращает режим квоты из настроек проекта или defaultMode
+func (p *Project) QuotaMode(defaultMode string) string {
+	if mode, ok := p.Settings["quota_mode"].(string); ok && (mode == QuotaModeHard || mode == QuotaModeSoft) {
+		return mode
+	}
+	return defaultMode
+}
+
+// RateLimitStatus - состояние лимита запросов или квоты для заголовков ответа
+type RateLimitStatus struct {
+	Limit     int64
+	Remaining int64
+	Reset     time.Time
+	Exceeded  bool
 }
 
 type APIKey struct {
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// RetryEntry - событие в очереди повторной записи консьюмера
+type RetryEntry struct {
+	ID    string `json:"id"`
+	Event *Event `json:"event"`
+	// KafkaMetadata события не сериализуется вместе с ним, поэтому хранится отдельно
+	Kafka KafkaMetadata `json:"kafka"`
+
+	// Количество неудачных попыток записи, включая первую
+	Attempts      int       `json:"attempts"`
+	LastError     string    `json:"last_error,omitempty"`
+	FirstFailedAt time.Time `json:"first_failed_at"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+}
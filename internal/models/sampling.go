@@ -0,0 +1,50 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// SamplingDefaultKey - ключ доли выборки для типов событий, не указанных явно
+const SamplingDefaultKey = "*"
+
+// SamplingSettings - доля сохраняемых событий по типу события, хранится в
+// Settings["sampling"], например {"button_click": 0.1, "*": 1}
+type SamplingSettings map[string]float64
+
+// Rate возвращает долю выборки для типа события; 1 - без выборки
+func (s SamplingSettings) Rate(eventType EventType) float64 {
+	if rate, ok := s[string(eventType)]; ok {
+		return rate
+	}
+	if rate, ok := s[SamplingDefaultKey]; ok {
+		return rate
+	}
+	return 1
+}
+
+// Sampling разбирает настройки выборки проекта
+func (p *Project) Sampling() (SamplingSettings, error) {
+	raw, ok := p.Settings["sampling"]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSamplingSettings, err)
+	}
+
+	var settings SamplingSettings
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSamplingSettings, err)
+	}
+
+	for eventType, rate := range settings {
+		if rate <= 0 || rate > 1 {
+			return nil, fmt.Errorf("%w: rate for %q must be in (0, 1]", ErrInvalidSamplingSettings, eventType)
+		}
+	}
+
+	return settings, nil
+}
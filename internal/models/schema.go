@@ -0,0 +1,61 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// SchemaMode определяет, что делать с событием, не прошедшим проверку схемы
+type SchemaMode string
+
+const (
+	SchemaModeStrict SchemaMode = "strict" // событие отклоняется
+	SchemaModeWarn   SchemaMode = "warn"   // событие принимается с предупреждениями
+	SchemaModeOff    SchemaMode = "off"    // проверка отключена
+)
+
+// FieldType - допустимый тип значения в Metadata
+type FieldType string
+
+const (
+	FieldTypeString  FieldType = "string"
+	FieldTypeNumber  FieldType = "number"
+	FieldTypeInteger FieldType = "integer"
+	FieldTypeBoolean FieldType = "boolean"
+	FieldTypeObject  FieldType = "object"
+	FieldTypeArray   FieldType = "array"
+)
+
+// SchemaField описывает один ключ Metadata
+type SchemaField struct {
+	Type     FieldType `json:"type"`
+	Required bool      `json:"required"`
+}
+
+// EventSchema описывает Metadata события с определенным названием в рамках проекта
+type EventSchema struct {
+	ID        string                 `json:"id" db:"id"`
+	ProjectID string                 `json:"project_id" db:"project_id"`
+	EventName string                 `json:"event_name" db:"event_name"`
+	Mode      SchemaMode             `json:"mode" db:"mode"`
+	Fields    map[string]SchemaField `json:"fields" db:"fields"`
+	CreatedAt time.Time              `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time              `json:"updated_at" db:"updated_at"`
+}
+
+// SchemaValidationError возвращается, когда событие не соответствует схеме в strict режиме
+type SchemaValidationError struct {
+	Errors []ValidationError
+}
+
+func (e *SchemaValidationError) Error() string {
+	fields := make([]string, 0, len(e.Errors))
+	for _, fe := range e.Errors {
+		fields = append(fields, fe.Field+": "+fe.Message)
+	}
+	return "event does not match schema: " + strings.Join(fields, "; ")
+}
+
+func (e *SchemaValidationError) Unwrap() error {
+	return ErrInvalidEventData
+}
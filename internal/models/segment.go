@@ -0,0 +1,130 @@
+package models
+
+import (
+	"time"
+)
+
+// Типы сообщений Segment HTTP Tracking API
+const (
+	SegmentTrack    = "track"
+	SegmentPage     = "page"
+	SegmentScreen   = "screen"
+	SegmentIdentify = "identify"
+	SegmentAlias    = "alias"
+)
+
+// Название события для просмотров экранов мобильных приложений, чтобы
+// отличать их от просмотров страниц
+const SegmentScreenEventName = "screen_view"
+
+// SegmentMessage - сообщение в формате Segment HTTP Tracking API
+type SegmentMessage struct {
+	Type        string                 `json:"type"`
+	MessageID   string                 `json:"messageId"`
+	UserID      string                 `json:"userId"`
+	AnonymousID string                 `json:"anonymousId"`
+	PreviousID  string                 `json:"previousId"`
+	Event       string                 `json:"event"`
+	Name        string                 `json:"name"`
+	Properties  map[string]interface{} `json:"properties"`
+	Traits      map[string]interface{} `json:"traits"`
+	Context     SegmentContext         `json:"context"`
+	Timestamp   time.Time              `json:"timestamp"`
+	SentAt      time.Time              `json:"sentAt"`
+}
+
+type SegmentContext struct {
+	IP        string `json:"ip"`
+	UserAgent string `json:"userAgent"`
+	Page      struct {
+		URL      string `json:"url"`
+		Referrer string `json:"referrer"`
+	} `json:"page"`
+	Campaign struct {
+		Name    string `json:"name"`
+		Source  string `json:"source"`
+		Medium  string `json:"medium"`
+		Term    string `json:"term"`
+		Content string `json:"content"`
+	} `json:"campaign"`
+}
+
+// SegmentBatch - тело запроса /batch
+type SegmentBatch struct {
+	Batch   []SegmentMessage `json:"batch"`
+	Context SegmentContext   `json:"context"`
+	SentAt  time.Time        `json:"sentAt"`
+}
+
+// DistinctID возвращает userId, а для анонимных пользователей - anonymousId
+func (m *SegmentMessage) DistinctID() string {
+	if m.UserID != "" {
+		return m.UserID
+	}
+	return m.AnonymousID
+}
+
+// ToEvent преобразует track, page и screen в событие. Название события track
+// становится event_name, имя страницы или экрана сохраняется в metadata["name"].
+func (m *SegmentMessage) ToEvent() (*Event, error) {
+	event := &Event{
+		ID:          m.MessageID,
+		UserID:      m.DistinctID(),
+		AnonymousID: m.AnonymousID,
+		Metadata:    make(map[string]interface{}, len(m.Properties)+1),
+		UserAgent:   m.Context.UserAgent,
+		IPAddress:   m.Context.IP,
+		Timestamp:   m.Timestamp,
+		SentAt:      m.SentAt,
+	}
+	for key, value := range m.Properties {
+		event.Metadata[key] = value
+	}
+
+	// Атрибуция: context.campaign заполняется SDK из UTM меток страницы
+	event.Referrer, _ = m.Properties["referrer"].(string)
+	if event.Referrer == "" {
+		event.Referrer = m.Context.Page.Referrer
+	}
+	event.UTMSource = m.Context.Campaign.Source
+	event.UTMMedium = m.Context.Campaign.Medium
+	event.UTMCampaign = m.Context.Campaign.Name
+	event.UTMTerm = m.Context.Campaign.Term
+	event.UTMContent = m.Context.Campaign.Content
+
+	if m.Name != "" {
+		if _, ok := event.Metadata["name"]; !ok {
+			event.Metadata["name"] = m.Name
+		}
+	}
+
+	switch m.Type {
+	case SegmentTrack:
+		if m.Event == "" {
+			return nil, ErrInvalidEventData
+		}
+		event.EventType = Custom
+		event.EventName = m.Event
+	case SegmentPage:
+		event.EventType = PageView
+		event.PageURL, _ = m.Properties["url"].(string)
+		if event.PageURL == "" {
+			event.PageURL = m.Context.Page.URL
+		}
+	case SegmentScreen:
+		event.EventType = PageView
+		event.EventName = SegmentScreenEventName
+		event.PageURL = m.Name
+	default:
+		return nil, ErrInvalidEventType
+	}
+
+	if event.UserID == "" {
+		return nil, ErrInvalidEventData
+	}
+	if len(event.Metadata) == 0 {
+		event.Metadata = nil
+	}
+
+	return event, nil
+}
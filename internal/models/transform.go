@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// TransformAction - действие правила трансформации
+type TransformAction string
+
+const (
+	TransformRenameEventType TransformAction = "rename_event_type" // event_type from -> to
+	TransformRenameEventName TransformAction = "rename_event_name" // event_name from -> to
+	TransformRenameKey       TransformAction = "rename_key"        // metadata[from] -> metadata[to]
+	TransformDropKey         TransformAction = "drop_key"          // удалить metadata[key]
+	TransformDeriveKey       TransformAction = "derive_key"        // metadata[key] из source (+ pattern/replacement) или value
+	TransformRewriteURL      TransformAction = "rewrite_url"       // page_url: замена pattern на replacement
+	TransformDropEvent       TransformAction = "drop_event"        // событие не сохраняется
+)
+
+// TransformFilter ограничивает события, к которым применяется правило.
+// Пустые поля не проверяются; metadata сравнивается по строковому значению.
+type TransformFilter struct {
+	EventType  EventType         `json:"event_type,omitempty"`
+	EventName  string            `json:"event_name,omitempty"`
+	URLPattern string            `json:"url_pattern,omitempty"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+}
+
+// TransformRule - одно правило; правила применяются по порядку, каждое
+// следующее видит результат предыдущих
+type TransformRule struct {
+	Action      TransformAction  `json:"action"`
+	Filter      *TransformFilter `json:"filter,omitempty"`
+	From        string           `json:"from,omitempty"`
+	To          string           `json:"to,omitempty"`
+	Key         string           `json:"key,omitempty"`
+	Source      string           `json:"source,omitempty"` // page_url, referrer, event_name, user_id или metadata.<key>
+	Pattern     string           `json:"pattern,omitempty"`
+	Replacement string           `json:"replacement,omitempty"`
+	Value       interface{}      `json:"value,omitempty"`
+}
+
+// ProjectTransforms - набор правил трансформации событий проекта
+type ProjectTransforms struct {
+	ProjectID string          `json:"project_id" db:"project_id"`
+	Rules     []TransformRule `json:"rules" db:"rules"`
+	UpdatedAt time.Time       `json:"updated_at" db:"updated_at"`
+}
+
+// TransformResult - результат применения правил к событию в режиме dry-run
+type TransformResult struct {
+	Index   int    `json:"index"`
+	Dropped bool   `json:"dropped"`
+	Event   *Event `json:"event,omitempty"`
+}
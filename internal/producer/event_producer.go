@@ -3,18 +3,51 @@ package producer
 import (
 	"context"
 	"encoding/json"
+	"log"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/segmentio/kafka-go"
+	"github.com/yourusername/event-analytics-service/internal/metrics"
 	"github.com/yourusername/event-analytics-service/internal/models"
 )
 
+const (
+	// Сколько сообщений может ждать отправки в асинхронном writer; сверх
+	// этого новые сообщения сразу пишутся в spool
+	maxInFlightMessages = 10000
+	// Таймаут одной попытки воспроизведения spool
+	spoolReplayTimeout = 30 * time.Second
+)
+
 type EventProducer struct {
-	writer *kafka.Writer
-	topic  string
+	writer  *kafka.Writer
+	topic   string
+	metrics *metrics.Metrics
+
+	// Синхронный writer для режима доставки с подтверждением брокера
+	syncWriter *kafka.Writer
+
+	// Локальная очередь на время недоступности Kafka; nil - события при
+	// ошибке записи теряются
+	spool          *Spool
+	replayWriter   *kafka.Writer
+	replayInterval time.Duration
+	inFlight       int64
+
+	stopChan chan struct{}
+	wg       sync.WaitGroup
 }
 
-func NewEventProducer(brokers []string, topic string) *EventProducer {
+func NewEventProducer(
+	brokers []string,
+	topic string,
+	requiredAcks kafka.RequiredAcks,
+	spool *Spool,
+	replayInterval time.Duration,
+	metrics *metrics.Metrics,
+) *EventProducer {
 	writer := kafka.NewWriter(kafka.WriterConfig{
 		Brokers:      brokers,
 		Topic:        topic,
@@ -24,58 +57,245 @@ func NewEventProducer(brokers []string, topic string) *EventProducer {
 		Async:        true,
 	})
 
-	return &EventProducer{
-		writer: writer,
-		topic:  topic,
+	p := &EventProducer{
+		writer:  writer,
+		topic:   topic,
+		metrics: metrics,
+		syncWriter: &kafka.Writer{
+			Addr:         kafka.TCP(brokers...),
+			Topic:        topic,
+			Balancer:     &kafka.LeastBytes{},
+			BatchTimeout: time.Millisecond,
+			RequiredAcks: requiredAcks,
+		},
+		spool:          spool,
+		replayInterval: replayInterval,
+		stopChan:       make(chan struct{}),
 	}
+
+	// В асинхронном режиме ошибки доставки приходят только сюда
+	writer.Completion = p.onCompletion
+
+	if spool != nil {
+		// Воспроизведение идет синхронно, чтобы сообщение удалялось из spool
+		// только после подтверждения от Kafka
+		p.replayWriter = &kafka.Writer{
+			Addr:         kafka.TCP(brokers...),
+			Topic:        topic,
+			Balancer:     &kafka.LeastBytes{},
+			BatchTimeout: 10 * time.Millisecond,
+			RequiredAcks: kafka.RequireAll,
+		}
+
+		p.wg.Add(1)
+		go p.replayLoop()
+	}
+
+	return p
 }
 
+// SendEvent ставит событие в асинхронный writer и не ждет ответа брокера
 func (p *EventProducer) SendEvent(ctx context.Context, event *models.Event) error {
-	// Добавляем метаданные для Kafka
-	event.KafkaMetadata = models.KafkaMetadata{
-		ProducedAt: time.Now(),
-		Partition:  0, // будет установлено Kafka
+	return p.SendBatch(ctx, []*models.Event{event})
+}
+
+func (p *EventProducer) SendBatch(ctx context.Context, events []*models.Event) error {
+	messages, err := buildMessages(events)
+	if err != nil {
+		return err
 	}
 
-	data, err := json.Marshal(event)
+	return p.publish(ctx, messages)
+}
+
+// SendEventSync возвращается только после подтверждения записи брокером
+// с настроенным RequiredAcks. Недоставленное событие не попадает в spool:
+// клиент получает ошибку и сам решает, повторять ли отправку.
+func (p *EventProducer) SendEventSync(ctx context.Context, event *models.Event) error {
+	return p.SendBatchSync(ctx, []*models.Event{event})
+}
+
+func (p *EventProducer) SendBatchSync(ctx context.Context, events []*models.Event) error {
+	messages, err := buildMessages(events)
 	if err != nil {
 		return err
 	}
 
-	msg := kafka.Message{
-		Key:   []byte(event.UserID), // Партицирование по UserID
-		Value: data,
-		Headers: []kafka.Header{
-			{Key: "event_type", Value: []byte(event.EventType)},
-			{Key: "project_id", Value: []byte(event.ProjectID)},
-		},
-		Time: time.Now(),
+	if err := p.syncWriter.WriteMessages(ctx, messages...); err != nil {
+		p.metrics.AddKafkaPublishErrors(p.topic, "sync", len(messages))
+		return err
 	}
 
-	return p.writer.WriteMessages(ctx, msg)
+	p.metrics.AddKafkaMessagesPublished(p.topic, len(messages))
+	return nil
 }
 
-func (p *EventProducer) SendBatch(ctx context.Context, events []*models.Event) error {
+func buildMessages(events []*models.Event) ([]kafka.Message, error) {
 	messages := make([]kafka.Message, 0, len(events))
+	now := time.Now()
 
 	for _, event := range events {
+		// Добавляем метаданные для Kafka
+		event.KafkaMetadata = models.KafkaMetadata{
+			ProducedAt: now,
+			Partition:  0, // будет установлено Kafka
+		}
+
 		data, err := json.Marshal(event)
 		if err != nil {
-			continue
+			return nil, err
 		}
 
 		messages = append(messages, kafka.Message{
-			Key:   []byte(event.UserID),
+			Key:   []byte(event.UserID), // Партицирование по UserID
 			Value: data,
 			Headers: []kafka.Header{
 				{Key: "event_type", Value: []byte(event.EventType)},
+				{Key: "project_id", Value: []byte(event.ProjectID)},
 			},
+			Time: now,
 		})
 	}
 
-	return p.writer.WriteMessages(ctx, messages...)
+	return messages, nil
+}
+
+// publish ставит сообщения в асинхронный writer. Если writer отказал сразу
+// или в нем слишком много неотправленных сообщений, сообщения пишутся в
+// spool; ошибка возвращается, только если их не удалось сохранить.
+func (p *EventProducer) publish(ctx context.Context, messages []kafka.Message) error {
+	count := int64(len(messages))
+
+	if p.spool != nil && atomic.LoadInt64(&p.inFlight)+count > maxInFlightMessages {
+		return p.spoolMessages(messages, "buffer_full")
+	}
+
+	atomic.AddInt64(&p.inFlight, count)
+	if err := p.writer.WriteMessages(ctx, messages...); err != nil {
+		// Сообщения не попали в буфер writer, Completion для них не вызовется
+		atomic.AddInt64(&p.inFlight, -count)
+		p.metrics.AddKafkaPublishErrors(p.topic, "async", len(messages))
+		if p.spool == nil {
+			return err
+		}
+		log.Printf("Kafka write failed, spooling %d messages: %v", len(messages), err)
+		return p.spoolMessages(messages, "write_error")
+	}
+
+	return nil
+}
+
+// onCompletion вызывается writer после попытки доставки каждого батча
+func (p *EventProducer) onCompletion(messages []kafka.Message, err error) {
+	atomic.AddInt64(&p.inFlight, -int64(len(messages)))
+	if err == nil {
+		p.metrics.AddKafkaMessagesPublished(p.topic, len(messages))
+		return
+	}
+
+	p.metrics.AddKafkaPublishErrors(p.topic, "async", len(messages))
+
+	if p.spool == nil {
+		log.Printf("Kafka delivery failed, %d messages lost: %v", len(messages), err)
+		p.metrics.AddSpoolDropped("disabled", len(messages))
+		return
+	}
+
+	log.Printf("Kafka delivery failed, spooling %d messages: %v", len(messages), err)
+	p.spoolMessages(messages, "delivery_error")
+}
+
+func (p *EventProducer) spoolMessages(messages []kafka.Message, reason string) error {
+	if err := p.spool.Append(messages); err != nil {
+		log.Printf("Failed to spool %d messages: %v", len(messages), err)
+		p.metrics.AddSpoolDropped(reason, len(messages))
+		return err
+	}
+
+	p.metrics.AddSpoolWritten(reason, len(messages))
+	return nil
+}
+
+// replayLoop периодически отправляет накопленные в spool сообщения в Kafka
+func (p *EventProducer) replayLoop() {
+	defer p.wg.Done()
+
+	interval := p.replayInterval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	p.updateSpoolMetrics()
+
+	for {
+		select {
+		case <-p.stopChan:
+			return
+		case <-ticker.C:
+			p.replaySpool()
+			p.updateSpoolMetrics()
+		}
+	}
+}
+
+// replaySpool отправляет сегменты spool, пока они не закончатся или Kafka
+// не вернет ошибку
+func (p *EventProducer) replaySpool() {
+	if err := p.spool.Sync(); err != nil {
+		log.Printf("Failed to sync spool: %v", err)
+	}
+
+	for {
+		select {
+		case <-p.stopChan:
+			return
+		default:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), spoolReplayTimeout)
+		sent, err := p.spool.Replay(ctx, p.replayWriter.WriteMessages)
+		cancel()
+
+		if sent > 0 {
+			p.metrics.AddSpoolReplayed(sent)
+			p.metrics.AddKafkaMessagesPublished(p.topic, sent)
+		}
+		if err != nil {
+			log.Printf("Spool replay paused after %d messages: %v", sent, err)
+			return
+		}
+		if sent == 0 {
+			return
+		}
+	}
+}
+
+func (p *EventProducer) updateSpoolMetrics() {
+	messages, bytes, oldest := p.spool.Stats()
+
+	var age time.Duration
+	if !oldest.IsZero() {
+		age = time.Since(oldest)
+	}
+	p.metrics.SetSpoolState(messages, bytes, age)
 }
 
 func (p *EventProducer) Close() error {
-	return p.writer.Close()
+	close(p.stopChan)
+	p.wg.Wait()
+
+	// Writer дожидается отправки буфера; недоставленное уходит в spool
+	err := p.writer.Close()
+	p.syncWriter.Close()
+
+	if p.spool != nil {
+		p.replayWriter.Close()
+		if spoolErr := p.spool.Close(); err == nil {
+			err = spoolErr
+		}
+	}
+
+	return err
 }
@@ -0,0 +1,479 @@
+package producer
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+const (
+	// Размер сегмента, после которого запись продолжается в новый файл
+	spoolSegmentSize = 64 << 20
+	// Сколько сообщений отправляется в Kafka за один вызов при воспроизведении
+	spoolReplayChunk = 500
+
+	spoolSegmentPrefix = "segment-"
+	spoolSegmentSuffix = ".log"
+	// Суффикс сегмента, в середине которого встретилась поврежденная запись:
+	// такой файл не удаляется, а откладывается для ручного разбора
+	spoolCorruptSuffix = ".corrupt"
+
+	// Заголовок записи: длина и CRC32 содержимого
+	spoolRecordHeader = 8
+	// Ограничение на размер одной записи, защищает от чтения мусора
+	spoolMaxRecordSize = 16 << 20
+)
+
+var ErrSpoolFull = errors.New("spool is full")
+
+// errTruncatedRecord - запись оборвана концом файла (падение процесса во время записи)
+var errTruncatedRecord = errors.New("truncated record")
+
+// spoolFile - файл активного сегмента
+type spoolFile interface {
+	io.WriteCloser
+	Truncate(size int64) error
+	Sync() error
+}
+
+// spoolRecord - сообщение Kafka в том виде, в котором оно хранится на диске
+type spoolRecord struct {
+	Key       []byte         `json:"key"`
+	Value     []byte         `json:"value"`
+	Headers   []kafka.Header `json:"headers"`
+	Time      time.Time      `json:"time"`
+	SpooledAt time.Time      `json:"spooled_at"`
+}
+
+type spoolSegment struct {
+	path     string
+	size     int64 // длина корректно записанных записей от начала файла
+	records  int64
+	firstAt  time.Time
+	replayed int64 // уже отправленные в Kafka записи начала сегмента
+}
+
+// Spool - локальная очередь сообщений на диске на время недоступности Kafka.
+// Сообщения дописываются в конец текущего сегмента (append-only файлы с
+// записями вида [длина][crc32][json]), воспроизводятся от самого старого
+// сегмента, полностью отправленный сегмент удаляется.
+//
+// Запись идет без fsync на каждое сообщение: данные переживают падение
+// процесса, но не потерю питания до очередного Sync. Сегмент, который не
+// удалось прочитать до конца, не удаляется, а переименовывается в *.corrupt.
+type Spool struct {
+	dir      string
+	maxBytes int64
+
+	mu       sync.Mutex
+	segments []*spoolSegment // от старых к новым, последний - активный
+	active   spoolFile
+	bytes    int64
+	records  int64
+	seq      int64
+}
+
+// NewSpool открывает каталог очереди и подхватывает сегменты, оставшиеся
+// от предыдущего запуска
+func NewSpool(dir string, maxBytes int64) (*Spool, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	s := &Spool{
+		dir:      dir,
+		maxBytes: maxBytes,
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, spoolSegmentPrefix+"*"+spoolSegmentSuffix))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		segment, complete, err := scanSegment(path)
+		if err != nil {
+			return nil, err
+		}
+		if segment.records == 0 {
+			if complete {
+				os.Remove(path)
+			} else {
+				quarantineSegment(path)
+			}
+			continue
+		}
+		s.segments = append(s.segments, segment)
+		s.bytes += segment.size
+		s.records += segment.records
+	}
+
+	if len(s.segments) > 0 {
+		fmt.Sscanf(filepath.Base(s.segments[len(s.segments)-1].path), spoolSegmentPrefix+"%d"+spoolSegmentSuffix, &s.seq)
+		log.Printf("Spool: found %d pending messages in %d segments", s.records, len(s.segments))
+	}
+
+	return s, nil
+}
+
+// Append дописывает сообщения в очередь. Если очередь превысила maxBytes,
+// сообщения не сохраняются и возвращается ErrSpoolFull.
+func (s *Spool) Append(messages []kafka.Message) error {
+	now := time.Now()
+	buf := make([]byte, 0, 1024*len(messages))
+
+	for _, msg := range messages {
+		payload, err := json.Marshal(spoolRecord{
+			Key:       msg.Key,
+			Value:     msg.Value,
+			Headers:   msg.Headers,
+			Time:      msg.Time,
+			SpooledAt: now,
+		})
+		if err != nil {
+			return err
+		}
+
+		var header [spoolRecordHeader]byte
+		binary.BigEndian.PutUint32(header[0:4], uint32(len(payload)))
+		binary.BigEndian.PutUint32(header[4:8], crc32.ChecksumIEEE(payload))
+		buf = append(buf, header[:]...)
+		buf = append(buf, payload...)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.maxBytes > 0 && s.bytes+int64(len(buf)) > s.maxBytes {
+		return ErrSpoolFull
+	}
+
+	segment, err := s.activeSegment()
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.active.Write(buf); err != nil {
+		// Файл открыт с O_APPEND: без отката следующие записи легли бы после
+		// оборванной. Если откатить не удалось, сегмент больше не пишется, а
+		// его хвост после size не читается при воспроизведении.
+		if terr := s.active.Truncate(segment.size); terr != nil {
+			log.Printf("Spool: failed to truncate %s after write error: %v", segment.path, terr)
+			s.active.Close()
+			s.active = nil
+		}
+		return err
+	}
+
+	if segment.records == 0 {
+		segment.firstAt = now
+	}
+	segment.size += int64(len(buf))
+	segment.records += int64(len(messages))
+	s.bytes += int64(len(buf))
+	s.records += int64(len(messages))
+
+	return nil
+}
+
+// Stats возвращает число сообщений и байт в очереди и время, когда было
+// сохранено самое старое из неотправленных сообщений
+func (s *Spool) Stats() (records, bytes int64, oldest time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, segment := range s.segments {
+		if segment.records > segment.replayed {
+			oldest = segment.firstAt
+			break
+		}
+	}
+
+	return s.records, s.bytes, oldest
+}
+
+// Replay отправляет сообщения самого старого сегмента через write и удаляет
+// сегмент, когда он отправлен целиком. Возвращает число отправленных
+// сообщений; при ошибке write воспроизведение прерывается, а уже отправленная
+// часть сегмента при следующем вызове пропускается. Если сегмент не удалось
+// прочитать до конца, после отправки прочитанного он откладывается в *.corrupt.
+func (s *Spool) Replay(ctx context.Context, write func(context.Context, ...kafka.Message) error) (int, error) {
+	segment, err := s.oldestSegment()
+	if err != nil || segment == nil {
+		return 0, err
+	}
+
+	file, err := os.Open(segment.path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	// Читаются только записи, учтенные в size: после оборванной записи,
+	// которую не удалось откатить, в файле может остаться мусор
+	s.mu.Lock()
+	size := segment.size
+	s.mu.Unlock()
+
+	reader := bufio.NewReader(io.LimitReader(file, size))
+	complete := info.Size() == size
+	sent := 0
+	var index int64
+	chunk := make([]kafka.Message, 0, spoolReplayChunk)
+
+	flush := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+		// Консьюмер берет produced_at из времени сообщения, поэтому оно должно
+		// быть временем фактической записи в Kafka, а не приема API: иначе
+		// ожидание в spool попадает в этап ожидания в Kafka
+		now := time.Now()
+		for i := range chunk {
+			chunk[i].Time = now
+		}
+		if err := write(ctx, chunk...); err != nil {
+			return err
+		}
+
+		s.mu.Lock()
+		segment.replayed += int64(len(chunk))
+		s.records -= int64(len(chunk))
+		s.mu.Unlock()
+
+		sent += len(chunk)
+		chunk = chunk[:0]
+		return nil
+	}
+
+	for {
+		record, _, err := readRecord(reader)
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				log.Printf("Spool: corrupted record in %s: %v", segment.path, err)
+				complete = false
+			}
+			break
+		}
+
+		index++
+		if index <= segment.replayed {
+			continue
+		}
+
+		chunk = append(chunk, kafka.Message{
+			Key:     record.Key,
+			Value:   record.Value,
+			Headers: record.Headers,
+		})
+		if len(chunk) == spoolReplayChunk {
+			if err := flush(); err != nil {
+				return sent, err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return sent, err
+	}
+
+	if !complete {
+		log.Printf("Spool: %s was not read to the end, moving it aside", segment.path)
+		quarantineSegment(segment.path)
+	}
+	s.removeSegment(segment)
+	return sent, nil
+}
+
+// Sync сбрасывает активный сегмент на диск
+func (s *Spool) Sync() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.active == nil {
+		return nil
+	}
+	return s.active.Sync()
+}
+
+func (s *Spool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.active == nil {
+		return nil
+	}
+	s.active.Sync()
+	err := s.active.Close()
+	s.active = nil
+	return err
+}
+
+// activeSegment возвращает сегмент для записи, создавая новый, если
+// текущего нет или он заполнен. Вызывается под мьютексом.
+func (s *Spool) activeSegment() (*spoolSegment, error) {
+	if s.active != nil {
+		segment := s.segments[len(s.segments)-1]
+		if segment.size < spoolSegmentSize {
+			return segment, nil
+		}
+		s.active.Sync()
+		s.active.Close()
+		s.active = nil
+	}
+
+	s.seq++
+	path := filepath.Join(s.dir, fmt.Sprintf("%s%020d%s", spoolSegmentPrefix, s.seq, spoolSegmentSuffix))
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	segment := &spoolSegment{path: path}
+	s.active = file
+	s.segments = append(s.segments, segment)
+	return segment, nil
+}
+
+// oldestSegment возвращает самый старый сегмент для воспроизведения.
+// Если остался только активный сегмент, он закрывается, чтобы новые
+// сообщения писались в следующий.
+func (s *Spool) oldestSegment() (*spoolSegment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.segments) == 0 {
+		return nil, nil
+	}
+
+	segment := s.segments[0]
+	if len(s.segments) == 1 && s.active != nil {
+		if segment.records == 0 {
+			return nil, nil
+		}
+		if err := s.active.Sync(); err != nil {
+			return nil, err
+		}
+		s.active.Close()
+		s.active = nil
+	}
+
+	return segment, nil
+}
+
+func (s *Spool) removeSegment(segment *spoolSegment) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.Remove(segment.path); err != nil && !os.IsNotExist(err) {
+		log.Printf("Spool: failed to remove %s: %v", segment.path, err)
+	}
+
+	for i, candidate := range s.segments {
+		if candidate == segment {
+			s.segments = append(s.segments[:i], s.segments[i+1:]...)
+			break
+		}
+	}
+
+	// Записи, которые не удалось прочитать, тоже уходят из учета
+	s.records -= segment.records - segment.replayed
+	s.bytes -= segment.size
+}
+
+// scanSegment считает записи сегмента. Запись, оборванная концом файла при
+// падении процесса, отрезается. При другой ошибке файл не меняется: size
+// покрывает только записи до поврежденной, а complete равен false.
+func scanSegment(path string) (segment *spoolSegment, complete bool, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, false, err
+	}
+	defer file.Close()
+
+	segment = &spoolSegment{path: path}
+	reader := bufio.NewReader(file)
+
+	for {
+		record, n, err := readRecord(reader)
+		if err != nil {
+			switch {
+			case errors.Is(err, io.EOF):
+				return segment, true, nil
+			case errors.Is(err, errTruncatedRecord):
+				log.Printf("Spool: truncating torn record at the end of %s", path)
+				if err := os.Truncate(path, segment.size); err != nil {
+					return nil, false, err
+				}
+				return segment, true, nil
+			default:
+				log.Printf("Spool: corrupted record in %s after %d records: %v", path, segment.records, err)
+				return segment, false, nil
+			}
+		}
+
+		if segment.records == 0 {
+			segment.firstAt = record.SpooledAt
+		}
+		segment.records++
+		segment.size += n
+	}
+}
+
+// quarantineSegment откладывает сегмент с поврежденными записями, чтобы их
+// можно было разобрать вручную
+func quarantineSegment(path string) {
+	if err := os.Rename(path, path+spoolCorruptSuffix); err != nil {
+		log.Printf("Spool: failed to move aside %s: %v", path, err)
+	}
+}
+
+// readRecord читает одну запись; io.EOF означает корректный конец файла
+func readRecord(reader *bufio.Reader) (*spoolRecord, int64, error) {
+	var header [spoolRecordHeader]byte
+	if _, err := io.ReadFull(reader, header[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, 0, fmt.Errorf("%w header", errTruncatedRecord)
+		}
+		return nil, 0, err
+	}
+
+	length := binary.BigEndian.Uint32(header[0:4])
+	if length > spoolMaxRecordSize {
+		return nil, 0, fmt.Errorf("record size %d exceeds limit", length)
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return nil, 0, errTruncatedRecord
+	}
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:8]) {
+		return nil, 0, errors.New("checksum mismatch")
+	}
+
+	var record spoolRecord
+	if err := json.Unmarshal(payload, &record); err != nil {
+		return nil, 0, err
+	}
+
+	return &record, int64(spoolRecordHeader + length), nil
+}
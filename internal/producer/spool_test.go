@@ -0,0 +1,109 @@
+package producer
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tornFile записывает половину данных и возвращает ENOSPC, пока включен fail
+type tornFile struct {
+	spoolFile
+	fail         bool
+	truncateFail bool
+}
+
+func (f *tornFile) Write(p []byte) (int, error) {
+	if !f.fail {
+		return f.spoolFile.Write(p)
+	}
+	f.fail = false
+	n, _ := f.spoolFile.Write(p[:len(p)/2])
+	return n, syscall.ENOSPC
+}
+
+func (f *tornFile) Truncate(size int64) error {
+	if f.truncateFail {
+		return errors.New("truncate failed")
+	}
+	return f.spoolFile.Truncate(size)
+}
+
+func message(value string) []kafka.Message {
+	return []kafka.Message{{Value: []byte(value)}}
+}
+
+// replayAll воспроизводит spool до конца и возвращает значения сообщений
+func replayAll(t *testing.T, spool *Spool) []string {
+	t.Helper()
+
+	var values []string
+	write := func(ctx context.Context, msgs ...kafka.Message) error {
+		for _, msg := range msgs {
+			values = append(values, string(msg.Value))
+		}
+		return nil
+	}
+	for {
+		records, _, _ := spool.Stats()
+		if records == 0 {
+			return values
+		}
+		_, err := spool.Replay(context.Background(), write)
+		require.NoError(t, err)
+	}
+}
+
+func TestSpoolRollsBackTornWrite(t *testing.T) {
+	dir := t.TempDir()
+	spool, err := NewSpool(dir, 0)
+	require.NoError(t, err)
+
+	require.NoError(t, spool.Append(message("first")))
+	file := &tornFile{spoolFile: spool.active, fail: true}
+	spool.active = file
+
+	// Запись оборвалась посреди сегмента, следующие проходят успешно
+	assert.ErrorIs(t, spool.Append(message("lost")), syscall.ENOSPC)
+	require.NoError(t, spool.Append(message("second")))
+	require.NoError(t, spool.Append(message("third")))
+
+	records, _, _ := spool.Stats()
+	assert.Equal(t, int64(3), records)
+
+	// Сообщения после оборванной записи переживают и перезапуск
+	require.NoError(t, spool.Close())
+	spool, err = NewSpool(dir, 0)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"first", "second", "third"}, replayAll(t, spool))
+
+	corrupt, _ := filepath.Glob(filepath.Join(dir, "*"+spoolCorruptSuffix))
+	assert.Empty(t, corrupt)
+}
+
+func TestSpoolRotatesWhenTornWriteCannotBeRolledBack(t *testing.T) {
+	dir := t.TempDir()
+	spool, err := NewSpool(dir, 0)
+	require.NoError(t, err)
+
+	require.NoError(t, spool.Append(message("first")))
+	spool.active = &tornFile{spoolFile: spool.active, fail: true, truncateFail: true}
+
+	assert.Error(t, spool.Append(message("lost")))
+	require.NoError(t, spool.Append(message("second")))
+
+	// Хвост первого сегмента не читается, сам сегмент не удаляется
+	assert.Equal(t, []string{"first", "second"}, replayAll(t, spool))
+
+	corrupt, _ := filepath.Glob(filepath.Join(dir, "*"+spoolCorruptSuffix))
+	assert.Len(t, corrupt, 1)
+	segments, _ := filepath.Glob(filepath.Join(dir, "*"+spoolSegmentSuffix))
+	assert.Empty(t, segments)
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/ClickHouse/clickhouse-go/v2"
@@ -19,27 +20,22 @@ func NewClickHouseRepository(conn clickhouse.Conn) EventRepository {
 	}
 }
 
+// Колонки, которые заполняются при вставке события
+const eventInsertColumns = `
+    id, project_id, user_id, anonymous_id, event_type, event_name, page_url,
+    referrer, utm_source, utm_medium, utm_campaign, utm_term, utm_content,
+    gclid, fbclid, msclkid,
+    metadata, user_agent, ip_address, device_type, browser, os,
+    country_code, region, is_bot, sample_rate, timestamp, received_at,
+    produced_at, consumed_at, kafka_topic, kafka_partition, kafka_offset
+`
+
 func (r *clickHouseRepo) InsertEvent(ctx context.Context, event *models.Event) error {
-	query := `
-        INSERT INTO events (
-            id, project_id, user_id, event_type, page_url, 
-            metadata, user_agent, ip_address, timestamp
-        ) VALUES (
-            ?, ?, ?, ?, ?, ?, ?, ?, ?
-        )
-    `
+	values := eventInsertValues(event)
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ")
+	query := `INSERT INTO events (` + eventInsertColumns + `) VALUES (` + placeholders + `)`
 
-	return r.conn.Exec(ctx, query,
-		event.ID,
-		event.ProjectID,
-		event.UserID,
-		event.EventType,
-		event.PageURL,
-		event.Metadata,
-		event.UserAgent,
-		event.IPAddress,
-		event.Timestamp,
-	)
+	return r.conn.Exec(ctx, query, values...)
 }
 
 func (r *clickHouseRepo) InsertEventBatch(ctx context.Context, events []*models.Event) error {
@@ -47,24 +43,13 @@ func (r *clickHouseRepo) InsertEventBatch(ctx context.Context, events []*models.
 		return nil
 	}
 
-	batch, err := r.conn.PrepareBatch(ctx, "INSERT INTO events")
+	batch, err := r.conn.PrepareBatch(ctx, "INSERT INTO events ("+eventInsertColumns+")")
 	if err != nil {
 		return err
 	}
 
 	for _, event := range events {
-		err := batch.Append(
-			event.ID,
-			event.ProjectID,
-			event.UserID,
-			event.EventType,
-			event.PageURL,
-			event.Metadata,
-			event.UserAgent,
-			event.IPAddress,
-			event.Timestamp,
-		)
-		if err != nil {
+		if err := batch.Append(eventInsertValues(event)...); err != nil {
 			return err
 		}
 	}
@@ -72,53 +57,74 @@ func (r *clickHouseRepo) InsertEventBatch(ctx context.Context, events []*models.
 	return batch.Send()
 }
 
-func (r *clickHouseRepo) GetStats(ctx context.Context, filter models.StatsRequest) ([]models.EventStats, error) {
-	var timeFormat string
-	switch filter.GroupBy {
-	case "hour":
-		timeFormat = "toStartOfHour(timestamp)"
-	case "day":
-		timeFormat = "toStartOfDay(timestamp)"
-	case "month":
-		timeFormat = "toStartOfMonth(timestamp)"
-	default:
-		timeFormat = "toStartOfDay(timestamp)"
+func eventInsertValues(event *models.Event) []interface{} {
+	return []interface{}{
+		event.ID,
+		event.ProjectID,
+		event.UserID,
+		event.AnonymousID,
+		event.EventType,
+		event.EventName,
+		event.PageURL,
+		event.Referrer,
+		event.UTMSource,
+		event.UTMMedium,
+		event.UTMCampaign,
+		event.UTMTerm,
+		event.UTMContent,
+		event.GCLID,
+		event.FBCLID,
+		event.MSCLKID,
+		event.Metadata,
+		event.UserAgent,
+		event.IPAddress,
+		event.DeviceType,
+		event.Browser,
+		event.OS,
+		event.CountryCode,
+		event.Region,
+		event.IsBot,
+		event.SampleRate,
+		event.Timestamp,
+		event.ReceivedAt,
+		orReceivedAt(event.KafkaMetadata.ProducedAt, event),
+		orReceivedAt(event.KafkaMetadata.ConsumedAt, event),
+		event.KafkaMetadata.Topic,
+		uint16(event.KafkaMetadata.Partition),
+		uint64(event.KafkaMetadata.Offset),
 	}
+}
 
-	query := fmt.Sprintf(`
-        SELECT 
-            %s as time_bucket,
-            event_type,
-            count() as count
-        FROM events
-        WHERE event_type = ?
-        AND timestamp BETWEEN ? AND ?
-        GROUP BY time_bucket, event_type
-        ORDER BY time_bucket ASC
-    `, timeFormat)
-
-	startDate, _ := time.Parse("2006-01-02", filter.StartDate)
-	endDate, _ := time.Parse("2006-01-02", filter.EndDate)
-
-	rows, err := r.conn.Query(ctx, query, filter.EventType, startDate, endDate)
-	if err != nil {
-		return nil, err
+// orReceivedAt подставляет received_at вместо незаполненного времени этапа:
+// событие записано не через Kafka
+func orReceivedAt(t time.Time, event *models.Event) time.Time {
+	if t.IsZero() {
+		return event.ReceivedAt
 	}
-	defer rows.Close()
+	return t
+}
 
-	var stats []models.EventStats
-	for rows.Next() {
-		var stat models.EventStats
-		if err := rows.Scan(&stat.TimeBucket, &stat.EventType, &stat.Count); err != nil {
-			return nil, err
-		}
-		stats = append(stats, stat)
-	}
+// Измерения, по которым можно разбивать статистику
+var breakdownColumns = map[string]string{
+	"device_type": "device_type",
+	"browser":     "browser",
+	"os":          "os",
+	// country_code имеет тип FixedString(2): пустое значение - это нулевые байты
+	"country": "replaceAll(toString(country_code), '\\0', '')",
+	"region":  "region",
+}
 
-	return stats, nil
+func (r *clickHouseRepo) GetStats(ctx context.Context, filter models.StatsRequest) ([]models.EventStats, error) {
+	return r.queryStats(ctx, "", filter)
 }
 
 func (r *clickHouseRepo) GetStatsByProject(ctx context.Context, projectID string, filter models.StatsRequest) ([]models.EventStats, error) {
+	return r.queryStats(ctx, projectID, filter)
+}
+
+// queryStats строит запрос статистики по времени с необязательной разбивкой
+// по измерению; пустой projectID означает все проекты
+func (r *clickHouseRepo) queryStats(ctx context.Context, projectID string, filter models.StatsRequest) ([]models.EventStats, error) {
 	var timeFormat string
 	switch filter.GroupBy {
 	case "hour":
@@ -131,23 +137,30 @@ func (r *clickHouseRepo) GetStatsByProject(ctx context.Context, projectID string
 		timeFormat = "toStartOfDay(timestamp)"
 	}
 
-	query := fmt.Sprintf(`
-        SELECT 
-            %s as time_bucket,
-            event_type,
-            count() as count
-        FROM events
-        WHERE project_id = ?
-        AND event_type = ?
-        AND timestamp BETWEEN ? AND ?
-        GROUP BY time_bucket, event_type
-        ORDER BY time_bucket ASC
-    `, timeFormat)
+	dimension := "''"
+	if column, ok := breakdownColumns[filter.Breakdown]; ok {
+		dimension = column
+	}
 
 	startDate, _ := time.Parse("2006-01-02", filter.StartDate)
 	endDate, _ := time.Parse("2006-01-02", filter.EndDate)
 
-	rows, err := r.conn.Query(ctx, query, projectID, filter.EventType, startDate, endDate)
+	where, args := eventFilter(projectID, filter, startDate, endDate)
+
+	query := fmt.Sprintf(`
+        SELECT 
+            toString(%s) as time_bucket,
+            toString(event_type) as event_type,
+            event_name,
+            toString(%s) as dimension,
+            %s as count
+        FROM events FINAL
+        WHERE %s
+        GROUP BY time_bucket, event_type, event_name, dimension
+        ORDER BY time_bucket ASC, event_name ASC, dimension ASC
+    `, timeFormat, dimension, sampledCount, where)
+
+	rows, err := r.conn.Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -156,9 +169,11 @@ func (r *clickHouseRepo) GetStatsByProject(ctx context.Context, projectID string
 	var stats []models.EventStats
 	for rows.Next() {
 		var stat models.EventStats
-		if err := rows.Scan(&stat.TimeBucket, &stat.EventType, &stat.Count); err != nil {
+		var count uint64
+		if err := rows.Scan(&stat.TimeBucket, &stat.EventType, &stat.EventName, &stat.Dimension, &count); err != nil {
 			return nil, err
 		}
+		stat.Count = int64(count)
 		stats = append(stats, stat)
 	}
 
@@ -167,51 +182,51 @@ func (r *clickHouseRepo) GetStatsByProject(ctx context.Context, projectID string
 
 func (r *clickHouseRepo) GetEventsByUser(ctx context.Context, userID string, limit, offset int) ([]models.Event, error) {
 	query := `
-        SELECT id, project_id, user_id, event_type, page_url, metadata, user_agent, ip_address, timestamp
-        FROM events
+        SELECT ` + eventSelectColumns + `
+        FROM events FINAL
         WHERE user_id = ?
         ORDER BY timestamp DESC
         LIMIT ? OFFSET ?
     `
 
-	rows, err := r.conn.Query(ctx, query, userID, limit, offset)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var events []models.Event
-	for rows.Next() {
-		var event models.Event
-		if err := rows.Scan(
-			&event.ID,
-			&event.ProjectID,
-			&event.UserID,
-			&event.EventType,
-			&event.PageURL,
-			&event.Metadata,
-			&event.UserAgent,
-			&event.IPAddress,
-			&event.Timestamp,
-		); err != nil {
-			return nil, err
-		}
-		events = append(events, event)
-	}
-
-	return events, nil
+	return r.queryEvents(ctx, query, userID, limit, offset)
 }
 
 func (r *clickHouseRepo) GetEventsByType(ctx context.Context, eventType models.EventType, start, end time.Time) ([]models.Event, error) {
 	query := `
-        SELECT id, project_id, user_id, event_type, page_url, metadata, user_agent, ip_address, timestamp
-        FROM events
+        SELECT ` + eventSelectColumns + `
+        FROM events FINAL
         WHERE event_type = ?
         AND timestamp BETWEEN ? AND ?
         ORDER BY timestamp DESC
     `
 
-	rows, err := r.conn.Query(ctx, query, eventType, start, end)
+	return r.queryEvents(ctx, query, eventType, start, end)
+}
+
+// GetEvents возвращает события по фильтру статистики (тип, название, страна, период)
+func (r *clickHouseRepo) GetEvents(ctx context.Context, filter models.StatsRequest) ([]models.Event, error) {
+	startDate, _ := time.Parse("2006-01-02", filter.StartDate)
+	endDate, _ := time.Parse("2006-01-02", filter.EndDate)
+	where, args := eventFilter("", filter, startDate, endDate)
+
+	query := `
+        SELECT ` + eventSelectColumns + `
+        FROM events FINAL
+        WHERE ` + where + `
+        ORDER BY timestamp DESC
+    `
+
+	return r.queryEvents(ctx, query, args...)
+}
+
+const eventSelectColumns = `
+    id, project_id, user_id, anonymous_id, event_type, event_name, page_url,
+    metadata, user_agent, ip_address, is_bot, sample_rate, timestamp
+`
+
+func (r *clickHouseRepo) queryEvents(ctx context.Context, query string, args ...interface{}) ([]models.Event, error) {
+	rows, err := r.conn.Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -224,11 +239,15 @@ func (r *clickHouseRepo) GetEventsByType(ctx context.Context, eventType models.E
 			&event.ID,
 			&event.ProjectID,
 			&event.UserID,
+			&event.AnonymousID,
 			&event.EventType,
+			&event.EventName,
 			&event.PageURL,
 			&event.Metadata,
 			&event.UserAgent,
 			&event.IPAddress,
+			&event.IsBot,
+			&event.SampleRate,
 			&event.Timestamp,
 		); err != nil {
 			return nil, err
@@ -236,24 +255,83 @@ func (r *clickHouseRepo) GetEventsByType(ctx context.Context, eventType models.E
 		events = append(events, event)
 	}
 
-	return events, nil
+	return events, rows.Err()
 }
 
+// eventFilter строит условие WHERE по фильтру статистики; пустой projectID
+// означает все проекты, пустые тип и название события не фильтруются,
+// события ботов исключаются без IncludeBots
+func eventFilter(projectID string, filter models.StatsRequest, start, end time.Time) (string, []interface{}) {
+	conditions := []string{"timestamp BETWEEN ? AND ?"}
+	args := []interface{}{start, end}
+
+	if projectID != "" {
+		conditions = append(conditions, "project_id = ?")
+		args = append(args, projectID)
+	}
+	if filter.EventType != "" {
+		conditions = append(conditions, "event_type = ?")
+		args = append(args, filter.EventType)
+	}
+	if filter.EventName != "" {
+		conditions = append(conditions, "event_name = ?")
+		args = append(args, filter.EventName)
+	}
+	if filter.Country != "" {
+		conditions = append(conditions, "country_code = ?")
+		args = append(args, strings.ToUpper(filter.Country))
+	}
+	if !filter.IncludeBots {
+		conditions = append(conditions, "NOT is_bot")
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+// sampledCount - число событий с поправкой на выборку: каждое сохраненное
+// событие представляет 1 / sample_rate исходных
+const sampledCount = `toUInt64(round(sum(1 / sample_rate)))`
+
+// personJoin присоединяет к событиям постоянный user_id из user_aliases
+// (первый параметр - project_id); personID дает его, а для событий без
+// связи - исходный user_id. Так анонимные события до входа и события после
+// него относятся к одному человеку. Связь разрешается на один шаг.
+const personJoin = `
+        LEFT JOIN (
+            SELECT previous_id, argMax(user_id, timestamp) AS canonical_id
+            FROM user_aliases
+            WHERE project_id = ?
+            GROUP BY previous_id
+        ) AS aliases ON aliases.previous_id = events.user_id
+`
+
+const personID = `if(aliases.canonical_id != '', aliases.canonical_id, events.user_id)`
+
 func (r *clickHouseRepo) GetTopPages(ctx context.Context, projectID string, limit int) ([]models.PageStat, error) {
 	query := `
         SELECT 
             page_url,
-            count() as views,
-            uniq(user_id) as unique_users
-        FROM events
-        WHERE project_id = ?
-        AND event_type = 'page_view'
+            toUInt64(round(sum(person_views))) as views,
+            toUInt64(round(sum(person_weight))) as unique_users
+        FROM (
+            SELECT
+                page_url,
+                ` + personID + ` AS person_id,
+                sum(1 / sample_rate) AS person_views,
+                max(1 / sample_rate) AS person_weight
+            FROM events FINAL
+            ` + personJoin + `
+            WHERE events.project_id = ?
+            AND event_type = 'page_view'
+            AND NOT is_bot
+            GROUP BY page_url, person_id
+        )
         GROUP BY page_url
         ORDER BY views DESC
         LIMIT ?
     `
 
-	rows, err := r.conn.Query(ctx, query, projectID, limit)
+	rows, err := r.conn.Query(ctx, query, projectID, projectID, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -271,20 +349,35 @@ func (r *clickHouseRepo) GetTopPages(ctx context.Context, projectID string, limi
 	return stats, nil
 }
 
-func (r *clickHouseRepo) GetUserSessions(ctx context.Context, userID string, sessionTimeout time.Duration) ([]models.UserSession, error) {
+// GetUserSessions возвращает сессии человека: userID может быть как
+// постоянным, так и анонимным идентификатором, события под всеми связанными
+// идентификаторами объединяются
+func (r *clickHouseRepo) GetUserSessions(ctx context.Context, projectID, userID string, sessionTimeout time.Duration) ([]models.UserSession, error) {
 	query := `
+        WITH (
+            SELECT argMax(user_id, timestamp)
+            FROM user_aliases
+            WHERE project_id = ? AND previous_id = ?
+        ) AS alias_id
         SELECT 
             groupArray(page_url) as pages,
             min(timestamp) as session_start,
             max(timestamp) as session_end,
             count() as event_count
-        FROM events
-        WHERE user_id = ?
+        FROM events FINAL
+        ` + personJoin + `
+        WHERE events.project_id = ?
+        AND ` + personID + ` = if(alias_id != '', alias_id, ?)
         GROUP BY toStartOfInterval(timestamp, INTERVAL ? MINUTE)
         ORDER BY session_start
     `
 
-	rows, err := r.conn.Query(ctx, query, userID, int(sessionTimeout.Minutes()))
+	rows, err := r.conn.Query(ctx, query,
+		projectID, userID,
+		projectID,
+		projectID, userID,
+		int(sessionTimeout.Minutes()),
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -317,25 +410,32 @@ func (r *clickHouseRepo) GetUserSessions(ctx context.Context, userID string, ses
 	return sessions, nil
 }
 
-func (r *clickHouseRepo) GetFunnelAnalysis(ctx context.Context, projectID string, steps []models.EventType, start, end time.Time) ([]models.FunnelStep, error) {
+func (r *clickHouseRepo) GetFunnelAnalysis(ctx context.Context, projectID string, steps []string, start, end time.Time) ([]models.FunnelStep, error) {
 	var funnelSteps []models.FunnelStep
 
 	for i, step := range steps {
 		query := `
-            SELECT COUNT(DISTINCT user_id)
-            FROM events
-            WHERE project_id = ?
-            AND event_type = ?
-            AND timestamp BETWEEN ? AND ?
+            SELECT toUInt64(round(sum(person_weight)))
+            FROM (
+                SELECT
+                    ` + personID + ` AS person_id,
+                    max(1 / sample_rate) AS person_weight
+                FROM events FINAL
+                ` + personJoin + `
+                WHERE events.project_id = ?
+                AND event_name = ?
+                AND timestamp BETWEEN ? AND ?
+                AND NOT is_bot
+                GROUP BY person_id
+            )
         `
 
-		var userCount int64
-		err := r.conn.QueryRow(ctx, query, projectID, step, start, end).Scan(&userCount)
+		var userCount uint64
+		err := r.conn.QueryRow(ctx, query, projectID, projectID, step, start, end).Scan(&userCount)
 		if err != nil {
 			return nil, err
 		}
 
-		stepName := string(step)
 		conversion := 0.0
 
 		if i > 0 && funnelSteps[i-1].UserCount > 0 {
@@ -345,9 +445,9 @@ func (r *clickHouseRepo) GetFunnelAnalysis(ctx context.Context, projectID string
 		}
 
 		funnelSteps = append(funnelSteps, models.FunnelStep{
-			StepName:   stepName,
-			EventType:  string(step),
-			UserCount:  userCount,
+			StepName:   step,
+			EventName:  step,
+			UserCount:  int64(userCount),
 			Conversion: conversion,
 		})
 	}
@@ -355,6 +455,189 @@ func (r *clickHouseRepo) GetFunnelAnalysis(ctx context.Context, projectID string
 	return funnelSteps, nil
 }
 
+// GetTrafficSources делит события пользователей на сессии (перерыв больше
+// sessionTimeout начинает новую) и относит каждую сессию к источнику ее
+// первого события: UTM метки, идентификатор клика, внешний referrer или
+// прямой заход. Сессия считается конверсионной, если в ней есть событие
+// conversionEvent.
+func (r *clickHouseRepo) GetTrafficSources(ctx context.Context, projectID, conversionEvent string, sessionTimeout time.Duration, start, end time.Time) ([]models.TrafficSource, error) {
+	query := `
+        SELECT
+            source,
+            medium,
+            campaign,
+            toUInt64(round(sum(weight))) AS sessions,
+            toUInt64(round(sumIf(weight, converted))) AS conversions
+        FROM (
+            SELECT
+                person_id,
+                session_index,
+                argMin(src, timestamp) AS source,
+                argMin(med, timestamp) AS medium,
+                argMin(utm_campaign, timestamp) AS campaign,
+                max(event_name = ?) AS converted,
+                min(1 / sample_rate) AS weight
+            FROM (
+                SELECT
+                    person_id,
+                    timestamp,
+                    event_name,
+                    utm_campaign,
+                    sample_rate,
+                    multiIf(
+                        utm_source != '', utm_source,
+                        gclid != '', 'google',
+                        msclkid != '', 'bing',
+                        fbclid != '', 'facebook',
+                        external_referrer, domainWithoutWWW(referrer),
+                        '(direct)'
+                    ) AS src,
+                    multiIf(
+                        utm_medium != '', utm_medium,
+                        gclid != '' OR msclkid != '', 'cpc',
+                        fbclid != '', 'paid_social',
+                        external_referrer, 'referral',
+                        '(none)'
+                    ) AS med,
+                    sum(new_session) OVER (PARTITION BY person_id ORDER BY timestamp ROWS UNBOUNDED PRECEDING) AS session_index
+                FROM (
+                    SELECT
+                        ` + personID + ` AS person_id,
+                        timestamp,
+                        event_name,
+                        sample_rate,
+                        utm_source,
+                        utm_medium,
+                        utm_campaign,
+                        gclid,
+                        fbclid,
+                        msclkid,
+                        referrer,
+                        referrer != '' AND domainWithoutWWW(referrer) != domainWithoutWWW(page_url) AS external_referrer,
+                        dateDiff('second',
+                            lagInFrame(timestamp, 1, toDateTime64(0, 3)) OVER (PARTITION BY person_id ORDER BY timestamp ROWS BETWEEN 1 PRECEDING AND CURRENT ROW),
+                            timestamp
+                        ) > ? AS new_session
+                    FROM events FINAL
+                    ` + personJoin + `
+                    WHERE events.project_id = ?
+                    AND timestamp BETWEEN ? AND ?
+                    AND NOT is_bot
+                )
+            )
+            GROUP BY person_id, session_index
+        )
+        GROUP BY source, medium, campaign
+        ORDER BY sessions DESC
+    `
+
+	rows, err := r.conn.Query(ctx, query, conversionEvent, int64(sessionTimeout.Seconds()), projectID, projectID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sources []models.TrafficSource
+	for rows.Next() {
+		var source models.TrafficSource
+		var sessions, conversions uint64
+		if err := rows.Scan(&source.Source, &source.Medium, &source.Campaign, &sessions, &conversions); err != nil {
+			return nil, err
+		}
+		source.Sessions = int64(sessions)
+		source.Conversions = int64(conversions)
+		if sessions > 0 {
+			source.ConversionRate = float64(conversions) / float64(sessions) * 100
+		}
+		sources = append(sources, source)
+	}
+
+	return sources, rows.Err()
+}
+
+// GetIngestionLatency считает задержку приема событий проекта, записанных
+// консьюмером за [start, end): от received_at до processed_at и по этапам.
+// received_at ставит API при приеме, а не клиент, поэтому этапы total и
+// publish измеряются по часам сервера. Задержки отрицательные из-за
+// расхождения часов считаются нулевыми. Повторно записанные события (FINAL)
+// учитываются один раз.
+func (r *clickHouseRepo) GetIngestionLatency(ctx context.Context, projectID, interval string, start, end time.Time) (*models.IngestionLatencyReport, error) {
+	bucket := "toStartOfHour(processed_at)"
+	if interval == "day" {
+		bucket = "toStartOfDay(processed_at)"
+	}
+
+	total, err := r.ingestionLatency(ctx, "''", projectID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.IngestionLatencyReport{Buckets: []models.IngestionLatency{}}
+	if len(total) > 0 {
+		report.Total = total[0]
+	}
+
+	report.Buckets, err = r.ingestionLatency(ctx, "toString("+bucket+")", projectID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+func (r *clickHouseRepo) ingestionLatency(ctx context.Context, bucket, projectID string, start, end time.Time) ([]models.IngestionLatency, error) {
+	query := `
+        SELECT
+            ` + bucket + ` AS time_bucket,
+            count() AS events,
+            quantiles(0.5, 0.95, 0.99)(total) AS total_q,
+            max(total) AS total_max,
+            quantile(0.95)(publish) AS publish_p95,
+            quantile(0.95)(queue) AS queue_p95,
+            quantile(0.95)(process) AS process_p95
+        FROM (
+            SELECT
+                processed_at,
+                greatest(dateDiff('millisecond', received_at, processed_at), 0) / 1000 AS total,
+                greatest(dateDiff('millisecond', received_at, produced_at), 0) / 1000 AS publish,
+                greatest(dateDiff('millisecond', produced_at, consumed_at), 0) / 1000 AS queue,
+                greatest(dateDiff('millisecond', consumed_at, processed_at), 0) / 1000 AS process
+            FROM events FINAL
+            WHERE project_id = ?
+            AND kafka_topic != ''
+            AND processed_at >= ? AND processed_at < ?
+        )
+        GROUP BY time_bucket
+        ORDER BY time_bucket
+    `
+
+	rows, err := r.conn.Query(ctx, query, projectID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]models.IngestionLatency, 0)
+	for rows.Next() {
+		var latency models.IngestionLatency
+		var events uint64
+		var quantiles []float64
+		if err := rows.Scan(
+			&latency.TimeBucket, &events, &quantiles, &latency.Max,
+			&latency.PublishP95, &latency.QueueP95, &latency.ProcessP95,
+		); err != nil {
+			return nil, err
+		}
+		latency.Events = int64(events)
+		if len(quantiles) == 3 {
+			latency.P50, latency.P95, latency.P99 = quantiles[0], quantiles[1], quantiles[2]
+		}
+		result = append(result, latency)
+	}
+
+	return result, rows.Err()
+}
+
 func (r *clickHouseRepo) Ping(ctx context.Context) error {
 	return r.conn.Ping(ctx)
 }
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/event-analytics-service/internal/models"
+)
+
+// recordingConn запоминает запросы и отвечает на них ошибкой
+type recordingConn struct {
+	driver.Conn
+	queries []string
+}
+
+func (c *recordingConn) Query(ctx context.Context, query string, args ...any) (driver.Rows, error) {
+	c.queries = append(c.queries, query)
+	return nil, errors.New("not connected")
+}
+
+// insertRow сопоставляет колонки вставки с их значениями для события
+func insertRow(t *testing.T, event *models.Event) map[string]interface{} {
+	t.Helper()
+
+	columns := strings.Split(eventInsertColumns, ",")
+	values := eventInsertValues(event)
+	require.Len(t, values, len(columns))
+
+	row := make(map[string]interface{}, len(columns))
+	for i, column := range columns {
+		row[strings.TrimSpace(column)] = values[i]
+	}
+	return row
+}
+
+func TestEventInsertValues(t *testing.T) {
+	receivedAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	event := &models.Event{
+		ID:         "evt-1",
+		ProjectID:  "project-1",
+		EventType:  models.PageView,
+		Timestamp:  receivedAt.Add(-time.Second),
+		ReceivedAt: receivedAt,
+		SampleRate: 1,
+	}
+	event.KafkaMetadata.Topic = "events"
+	event.KafkaMetadata.Partition = 3
+	event.KafkaMetadata.Offset = 42
+	event.KafkaMetadata.ProducedAt = receivedAt.Add(time.Second)
+	event.KafkaMetadata.ConsumedAt = receivedAt.Add(2 * time.Second)
+
+	row := insertRow(t, event)
+	assert.Equal(t, "evt-1", row["id"])
+	assert.Equal(t, "project-1", row["project_id"])
+	assert.Equal(t, receivedAt.Add(-time.Second), row["timestamp"])
+	assert.Equal(t, receivedAt, row["received_at"])
+	assert.Equal(t, receivedAt.Add(time.Second), row["produced_at"])
+	assert.Equal(t, receivedAt.Add(2*time.Second), row["consumed_at"])
+	assert.Equal(t, "events", row["kafka_topic"])
+	assert.Equal(t, uint16(3), row["kafka_partition"])
+	assert.Equal(t, uint64(42), row["kafka_offset"])
+	assert.NotContains(t, row, "processed_at")
+}
+
+func TestEventInsertValuesWithoutKafka(t *testing.T) {
+	receivedAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// Событие записано не через Kafka: время этапов равно received_at
+	row := insertRow(t, &models.Event{ID: "evt-1", ReceivedAt: receivedAt})
+	assert.Equal(t, receivedAt, row["produced_at"])
+	assert.Equal(t, receivedAt, row["consumed_at"])
+	assert.Equal(t, "", row["kafka_topic"])
+}
+
+func TestIngestionLatencyQueryUsesFinal(t *testing.T) {
+	conn := &recordingConn{}
+	repo := NewClickHouseRepository(conn)
+
+	_, err := repo.GetIngestionLatency(context.Background(), "project-1", "hour", time.Now().Add(-time.Hour), time.Now())
+	assert.Error(t, err)
+	if assert.Len(t, conn.queries, 1) {
+		assert.Contains(t, conn.queries[0], "FROM events FINAL")
+	}
+}
@@ -20,11 +20,16 @@ type EventRepository interface {
 	// Детальные запросы
 	GetEventsByUser(ctx context.Context, userID string, limit, offset int) ([]models.Event, error)
 	GetEventsByType(ctx context.Context, eventType models.EventType, start, end time.Time) ([]models.Event, error)
+	GetEvents(ctx context.Context, filter models.StatsRequest) ([]models.Event, error)
 
 	// Аналитические запросы
 	GetTopPages(ctx context.Context, projectID string, limit int) ([]models.PageStat, error)
-	GetUserSessions(ctx context.Context, userID string, sessionTimeout time.Duration) ([]models.UserSession, error)
-	GetFunnelAnalysis(ctx context.Context, projectID string, steps []models.EventType, start, end time.Time) ([]models.FunnelStep, error)
+	GetUserSessions(ctx context.Context, projectID, userID string, sessionTimeout time.Duration) ([]models.UserSession, error)
+	GetTrafficSources(ctx context.Context, projectID, conversionEvent string, sessionTimeout time.Duration, start, end time.Time) ([]models.TrafficSource, error)
+	GetFunnelAnalysis(ctx context.Context, projectID string, steps []string, start, end time.Time) ([]models.FunnelStep, error)
+
+	// Мониторинг приема
+	GetIngestionLatency(ctx context.Context, projectID, interval string, start, end time.Time) (*models.IngestionLatencyReport, error)
 
 	// Вспомогательные
 	Ping(ctx context.Context) error
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/yourusername/event-analytics-service/internal/models"
+)
+
+// IdentityRepository хранит атрибуты пользователей и связи идентификаторов
+type IdentityRepository interface {
+	InsertTraits(ctx context.Context, traits *models.UserTraits) error
+	InsertAlias(ctx context.Context, alias *models.UserAlias) error
+}
+
+type identityRepository struct {
+	conn clickhouse.Conn
+}
+
+func NewIdentityRepository(conn clickhouse.Conn) IdentityRepository {
+	return &identityRepository{
+		conn: conn,
+	}
+}
+
+func (r *identityRepository) InsertTraits(ctx context.Context, traits *models.UserTraits) error {
+	data, err := json.Marshal(traits.Traits)
+	if err != nil {
+		return err
+	}
+
+	query := `
+        INSERT INTO user_traits (project_id, user_id, anonymous_id, traits, timestamp)
+        VALUES (?, ?, ?, ?, ?)
+    `
+
+	return r.conn.Exec(ctx, query,
+		traits.ProjectID,
+		traits.UserID,
+		traits.AnonymousID,
+		string(data),
+		traits.Timestamp,
+	)
+}
+
+func (r *identityRepository) InsertAlias(ctx context.Context, alias *models.UserAlias) error {
+	query := `
+        INSERT INTO user_aliases (project_id, previous_id, user_id, timestamp)
+        VALUES (?, ?, ?, ?)
+    `
+
+	return r.conn.Exec(ctx, query,
+		alias.ProjectID,
+		alias.PreviousID,
+		alias.UserID,
+		alias.Timestamp,
+	)
+}
@@ -21,6 +21,7 @@ type ProjectRepository interface {
 	GenerateAPIKey(ctx context.Context, projectID string) (string, error)
 	ValidateAPIKey(ctx context.Context, apiKey string) (*models.Project, error)
 	GetProjectStats(ctx context.Context, projectID string, start, end time.Time) (*models.ProjectStats, error)
+	UpdateEventsCount(ctx context.Context, projectID string, count int64) error
 }
 
 type projectRepository struct {
@@ -35,8 +36,8 @@ func NewProjectRepository(db *sql.DB) ProjectRepository {
 
 func (r *projectRepository) CreateProject(ctx context.Context, project *models.Project) error {
 	query := `
-        INSERT INTO projects (id, name, api_key, user_id, settings, active, created_at, updated_at)
-        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+        INSERT INTO projects (id, name, api_key, user_id, settings, active, plan_type, events_limit, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
     `
 
 	if project.ID == "" {
@@ -48,6 +49,12 @@ func (r *projectRepository) CreateProject(ctx context.Context, project *models.P
 	if project.Settings == nil {
 		project.Settings = models.JSON{}
 	}
+	if project.PlanType == "" {
+		project.PlanType = models.PlanFree
+	}
+	if project.EventsLimit == 0 {
+		project.EventsLimit = models.GetPlan(project.PlanType).MonthlyEvents
+	}
 	now := time.Now()
 	project.CreatedAt = now
 	project.UpdatedAt = now
@@ -65,6 +72,8 @@ func (r *projectRepository) CreateProject(ctx context.Context, project *models.P
 		project.UserID,
 		settingsJSON,
 		project.Active,
+		project.PlanType,
+		project.EventsLimit,
 		project.CreatedAt,
 		project.UpdatedAt,
 	)
@@ -83,7 +92,7 @@ func (r *projectRepository) CreateProject(ctx context.Context, project *models.P
 
 func (r *projectRepository) GetProjectByID(ctx context.Context, id string) (*models.Project, error) {
 	query := `
-        SELECT id, name, api_key, user_id, settings, active, created_at, updated_at
+        SELECT id, name, api_key, user_id, settings, active, plan_type, events_limit, events_count, created_at, updated_at
         FROM projects
         WHERE id = $1 AND active = true
     `
@@ -98,6 +107,9 @@ func (r *projectRepository) GetProjectByID(ctx context.Context, id string) (*mod
 		&project.UserID,
 		&settings,
 		&project.Active,
+		&project.PlanType,
+		&project.EventsLimit,
+		&project.EventsCount,
 		&project.CreatedAt,
 		&project.UpdatedAt,
 	)
@@ -121,7 +133,7 @@ func (r *projectRepository) GetProjectByID(ctx context.Context, id string) (*mod
 
 func (r *projectRepository) GetProjectByAPIKey(ctx context.Context, apiKey string) (*models.Project, error) {
 	query := `
-        SELECT id, name, api_key, user_id, settings, active, created_at, updated_at
+        SELECT id, name, api_key, user_id, settings, active, plan_type, events_limit, events_count, created_at, updated_at
         FROM projects
         WHERE api_key = $1 AND active = true
     `
@@ -136,6 +148,9 @@ func (r *projectRepository) GetProjectByAPIKey(ctx context.Context, apiKey strin
 		&project.UserID,
 		&settings,
 		&project.Active,
+		&project.PlanType,
+		&project.EventsLimit,
+		&project.EventsCount,
 		&project.CreatedAt,
 		&project.UpdatedAt,
 	)
@@ -156,7 +171,7 @@ func (r *projectRepository) GetProjectByAPIKey(ctx context.Context, apiKey strin
 
 func (r *projectRepository) GetProjectsByUserID(ctx context.Context, userID string, limit, offset int) ([]*models.Project, error) {
 	query := `
-        SELECT id, name, api_key, user_id, settings, active, created_at, updated_at
+        SELECT id, name, api_key, user_id, settings, active, plan_type, events_limit, events_count, created_at, updated_at
         FROM projects
         WHERE user_id = $1 AND active = true
         ORDER BY created_at DESC
@@ -181,6 +196,9 @@ func (r *projectRepository) GetProjectsByUserID(ctx context.Context, userID stri
 			&project.UserID,
 			&settings,
 			&project.Active,
+			&project.PlanType,
+			&project.EventsLimit,
+			&project.EventsCount,
 			&project.CreatedAt,
 			&project.UpdatedAt,
 		)
@@ -265,6 +283,14 @@ func (r *projectRepository) GenerateAPIKey(ctx context.Context, projectID string
 	return newKey, nil
 }
 
+// UpdateEventsCount записывает число событий проекта за текущий месяц
+func (r *projectRepository) UpdateEventsCount(ctx context.Context, projectID string, count int64) error {
+	query := `UPDATE projects SET events_count = $1, updated_at = $2 WHERE id = $3`
+
+	_, err := r.db.ExecContext(ctx, query, count, time.Now(), projectID)
+	return err
+}
+
 func (r *projectRepository) ValidateAPIKey(ctx context.Context, apiKey string) (*models.Project, error) {
 	return r.GetProjectByAPIKey(ctx, apiKey)
 }
@@ -58,44 +58,236 @@ func (r *RedisRepository) GetCachedStats(ctx context.Context, key string) ([]mod
 	return stats, nil
 }
 
-// Очередь для Retry
-func (r *RedisRepository) PushToRetryQueue(ctx context.Context, event *models.Event) error {
-	data, err := json.Marshal(event)
-	if err != nil {
-		return err
+// Очередь для Retry. Записи хранятся в hash по ID, расписание - в sorted set
+// по времени следующей попытки, время первой ошибки - в отдельном sorted set
+// для метрики возраста очереди. Записи, которые не удалось разобрать,
+// переносятся в список retry:corrupt для ручного разбора.
+const (
+	retryScheduleKey = "retry:schedule"
+	retryEntriesKey  = "retry:entries"
+	retryFirstKey    = "retry:first"
+	retryCorruptKey  = "retry:corrupt"
+)
+
+// claimRetriesScript забирает записи, время которых наступило, и сдвигает их
+// попытку на время аренды: если обработчик упадет, запись снова станет доступна
+var claimRetriesScript = redis.NewScript(`
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[3])
+local entries = {}
+for _, id in ipairs(ids) do
+	local data = redis.call('HGET', KEYS[2], id)
+	if data then
+		redis.call('ZADD', KEYS[1], ARGV[2], id)
+		table.insert(entries, id)
+		table.insert(entries, data)
+	else
+		redis.call('ZREM', KEYS[1], id)
+		redis.call('ZREM', KEYS[3], id)
+	end
+end
+return entries
+`)
+
+// PushToRetryQueue добавляет записи или обновляет существующие с тем же ID
+func (r *RedisRepository) PushToRetryQueue(ctx context.Context, entries ...*models.RetryEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	pipe := r.Client.TxPipeline()
+	for _, entry := range entries {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+
+		pipe.HSet(ctx, retryEntriesKey, entry.ID, data)
+		pipe.ZAdd(ctx, retryScheduleKey, &redis.Z{Score: float64(entry.NextAttemptAt.UnixMilli()), Member: entry.ID})
+		pipe.ZAddNX(ctx, retryFirstKey, &redis.Z{Score: float64(entry.FirstFailedAt.UnixMilli()), Member: entry.ID})
 	}
 
-	return r.Client.LPush(ctx, "retry_queue", data).Err()
+	_, err := pipe.Exec(ctx)
+	return err
 }
 
-func (r *RedisRepository) PopFromRetryQueue(ctx context.Context) (*models.Event, error) {
-	data, err := r.Client.RPop(ctx, "retry_queue").Bytes()
+// ClaimRetries возвращает до limit записей, время попытки которых наступило к now.
+// Записи остаются в очереди до CompleteRetries или PushToRetryQueue; если ни
+// то ни другое не вызвано, через lease они будут выданы снова. Записи, которые
+// не удалось разобрать, переносятся в retry:corrupt; их число возвращается
+// вторым значением.
+func (r *RedisRepository) ClaimRetries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*models.RetryEntry, int, error) {
+	result, err := claimRetriesScript.Run(ctx, r.Client,
+		[]string{retryScheduleKey, retryEntriesKey, retryFirstKey},
+		now.UnixMilli(), now.Add(lease).UnixMilli(), limit,
+	).StringSlice()
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
-	var event models.Event
-	if err := json.Unmarshal(data, &event); err != nil {
-		return nil, err
+	// Скрипт возвращает пары ID - запись
+	entries := make([]*models.RetryEntry, 0, len(result)/2)
+	var corruptIDs []string
+	var corrupt []interface{}
+	for i := 0; i+1 < len(result); i += 2 {
+		var entry models.RetryEntry
+		if err := json.Unmarshal([]byte(result[i+1]), &entry); err != nil || entry.Event == nil {
+			corruptIDs = append(corruptIDs, result[i])
+			corrupt = append(corrupt, result[i+1])
+			continue
+		}
+		entries = append(entries, &entry)
+	}
+
+	if len(corrupt) == 0 {
+		return entries, 0, nil
+	}
+
+	// Без переноса такие записи выдавались бы снова после каждой аренды
+	pipe := r.Client.TxPipeline()
+	pipe.RPush(ctx, retryCorruptKey, corrupt...)
+	pipe.HDel(ctx, retryEntriesKey, corruptIDs...)
+	for _, id := range corruptIDs {
+		pipe.ZRem(ctx, retryScheduleKey, id)
+		pipe.ZRem(ctx, retryFirstKey, id)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		// Выданные записи вернутся в работу после аренды
+		return nil, 0, err
+	}
+
+	return entries, len(corrupt), nil
+}
+
+// CompleteRetries удаляет записи из очереди
+func (r *RedisRepository) CompleteRetries(ctx context.Context, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	members := make([]interface{}, len(ids))
+	for i, id := range ids {
+		members[i] = id
+	}
+
+	pipe := r.Client.TxPipeline()
+	pipe.HDel(ctx, retryEntriesKey, ids...)
+	pipe.ZRem(ctx, retryScheduleKey, members...)
+	pipe.ZRem(ctx, retryFirstKey, members...)
+
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// RetryQueueStats возвращает размер очереди и время первой ошибки самой старой записи
+func (r *RedisRepository) RetryQueueStats(ctx context.Context) (int64, time.Time, error) {
+	pipe := r.Client.Pipeline()
+	depth := pipe.ZCard(ctx, retryScheduleKey)
+	oldest := pipe.ZRangeWithScores(ctx, retryFirstKey, 0, 0)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, time.Time{}, err
+	}
+
+	var oldestAt time.Time
+	if scores := oldest.Val(); len(scores) > 0 {
+		oldestAt = time.UnixMilli(int64(scores[0].Score))
 	}
 
-	return &event, nil
+	return depth.Val(), oldestAt, nil
 }
 
-// Rate limiting
-func (r *RedisRepository) CheckRateLimit(ctx context.Context, projectID string, limit int, window time.Duration) (bool, error) {
-	key := fmt.Sprintf("ratelimit:%s", projectID)
+// Дедупликация событий по ID
+// MarkEventSeen возвращает true, если событие с таким ID уже встречалось в окне ttl
+func (r *RedisRepository) MarkEventSeen(ctx context.Context, projectID, eventID string, ttl time.Duration) (bool, error) {
+	key := fmt.Sprintf("dedup:%s:%s", projectID, eventID)
+
+	ok, err := r.Client.SetNX(ctx, key, 1, ttl).Result()
+	if err != nil {
+		return false, err
+	}
+
+	return !ok, nil
+}
+
+// ForgetEvent удаляет отметку о событии, чтобы клиент мог повторить отправку
+func (r *RedisRepository) ForgetEvent(ctx context.Context, projectID, eventID string) error {
+	return r.Client.Del(ctx, fmt.Sprintf("dedup:%s:%s", projectID, eventID)).Err()
+}
+
+// Rate limiting (фиксированное окно: отдельный счетчик на каждое окно)
+func (r *RedisRepository) CheckRateLimit(ctx context.Context, projectID string, limit int, window time.Duration) (*models.RateLimitStatus, error) {
+	windowStart := time.Now().Truncate(window)
+	key := fmt.Sprintf("ratelimit:%s:%d", projectID, windowStart.Unix())
 
 	pipe := r.Client.Pipeline()
 	incr := pipe.Incr(ctx, key)
-	pipe.Expire(ctx, key, window)
+	pipe.Expire(ctx, key, window+time.Second)
 
 	_, err := pipe.Exec(ctx)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
-	return incr.Val() <= int64(limit), nil
+	remaining := int64(limit) - incr.Val()
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return &models.RateLimitStatus{
+		Limit:     int64(limit),
+		Remaining: remaining,
+		Reset:     windowStart.Add(window),
+		Exceeded:  incr.Val() > int64(limit),
+	}, nil
+}
+
+// IncrementUserRate считает события пользователя в текущем окне для
+// эвристики обнаружения ботов
+func (r *RedisRepository) IncrementUserRate(ctx context.Context, projectID, userID string, window time.Duration) (int64, error) {
+	windowStart := time.Now().Truncate(window)
+	key := fmt.Sprintf("userrate:%s:%s:%d", projectID, userID, windowStart.Unix())
+
+	pipe := r.Client.Pipeline()
+	incr := pipe.Incr(ctx, key)
+	pipe.Expire(ctx, key, window+time.Second)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+
+	return incr.Val(), nil
+}
+
+// Месячное потребление событий проектом
+func (r *RedisRepository) GetMonthlyUsage(ctx context.Context, projectID string, month time.Time) (int64, error) {
+	count, err := r.Client.Get(ctx, usageKey(projectID, month)).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return count, err
+}
+
+// IncrementMonthlyUsage учитывает count событий в текущем месяце и
+// возвращает новое значение счетчика
+func (r *RedisRepository) IncrementMonthlyUsage(ctx context.Context, projectID string, count int64) (int64, error) {
+	key := usageKey(projectID, time.Now())
+
+	pipe := r.Client.Pipeline()
+	used := pipe.IncrBy(ctx, key, count)
+	pipe.Expire(ctx, key, 62*24*time.Hour)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return used.Val(), nil
+}
+
+// DecrementMonthlyUsage возвращает count событий в квоту текущего месяца
+func (r *RedisRepository) DecrementMonthlyUsage(ctx context.Context, projectID string, count int64) error {
+	return r.Client.DecrBy(ctx, usageKey(projectID, time.Now()), count).Err()
+}
+
+func usageKey(projectID string, month time.Time) string {
+	return fmt.Sprintf("usage:%s:%s", projectID, month.UTC().Format("200601"))
 }
 
 // Real-time агрегации
@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/event-analytics-service/internal/models"
+)
+
+type SchemaRepository interface {
+	UpsertSchema(ctx context.Context, schema *models.EventSchema) error
+	GetSchema(ctx context.Context, projectID, eventName string) (*models.EventSchema, error)
+	ListSchemas(ctx context.Context, projectID string) ([]*models.EventSchema, error)
+	DeleteSchema(ctx context.Context, projectID, eventName string) error
+}
+
+type schemaRepository struct {
+	db *sql.DB
+}
+
+func NewSchemaRepository(db *sql.DB) SchemaRepository {
+	return &schemaRepository{
+		db: db,
+	}
+}
+
+func (r *schemaRepository) UpsertSchema(ctx context.Context, schema *models.EventSchema) error {
+	query := `
+        INSERT INTO event_schemas (id, project_id, event_name, mode, fields, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+        ON CONFLICT (project_id, event_name)
+        DO UPDATE SET mode = EXCLUDED.mode, fields = EXCLUDED.fields, updated_at = EXCLUDED.updated_at
+        RETURNING id, created_at
+    `
+
+	if schema.ID == "" {
+		schema.ID = uuid.New().String()
+	}
+	if schema.Fields == nil {
+		schema.Fields = map[string]models.SchemaField{}
+	}
+	now := time.Now()
+	schema.CreatedAt = now
+	schema.UpdatedAt = now
+
+	fields, err := json.Marshal(schema.Fields)
+	if err != nil {
+		return err
+	}
+
+	return r.db.QueryRowContext(ctx, query,
+		schema.ID,
+		schema.ProjectID,
+		schema.EventName,
+		schema.Mode,
+		fields,
+		schema.CreatedAt,
+		schema.UpdatedAt,
+	).Scan(&schema.ID, &schema.CreatedAt)
+}
+
+func (r *schemaRepository) GetSchema(ctx context.Context, projectID, eventName string) (*models.EventSchema, error) {
+	query := `
+        SELECT id, project_id, event_name, mode, fields, created_at, updated_at
+        FROM event_schemas
+        WHERE project_id = $1 AND event_name = $2
+    `
+
+	schema, err := scanSchema(r.db.QueryRowContext(ctx, query, projectID, eventName))
+	if err == sql.ErrNoRows {
+		return nil, models.ErrSchemaNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return schema, nil
+}
+
+func (r *schemaRepository) ListSchemas(ctx context.Context, projectID string) ([]*models.EventSchema, error) {
+	query := `
+        SELECT id, project_id, event_name, mode, fields, created_at, updated_at
+        FROM event_schemas
+        WHERE project_id = $1
+        ORDER BY event_name
+    `
+
+	rows, err := r.db.QueryContext(ctx, query, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schemas []*models.EventSchema
+	for rows.Next() {
+		schema, err := scanSchema(rows)
+		if err != nil {
+			return nil, err
+		}
+		schemas = append(schemas, schema)
+	}
+
+	return schemas, rows.Err()
+}
+
+func (r *schemaRepository) DeleteSchema(ctx context.Context, projectID, eventName string) error {
+	query := `DELETE FROM event_schemas WHERE project_id = $1 AND event_name = $2`
+
+	result, err := r.db.ExecContext(ctx, query, projectID, eventName)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return models.ErrSchemaNotFound
+	}
+
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSchema(row rowScanner) (*models.EventSchema, error) {
+	var schema models.EventSchema
+	var fields []byte
+
+	err := row.Scan(
+		&schema.ID,
+		&schema.ProjectID,
+		&schema.EventName,
+		&schema.Mode,
+		&fields,
+		&schema.CreatedAt,
+		&schema.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(fields) > 0 {
+		if err := json.Unmarshal(fields, &schema.Fields); err != nil {
+			return nil, err
+		}
+	}
+
+	return &schema, nil
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/yourusername/event-analytics-service/internal/models"
+)
+
+// TransformRepository хранит правила трансформации событий проектов
+type TransformRepository interface {
+	GetTransforms(ctx context.Context, projectID string) (*models.ProjectTransforms, error)
+	SaveTransforms(ctx context.Context, transforms *models.ProjectTransforms) error
+}
+
+type transformRepository struct {
+	db *sql.DB
+}
+
+func NewTransformRepository(db *sql.DB) TransformRepository {
+	return &transformRepository{
+		db: db,
+	}
+}
+
+// GetTransforms возвращает правила проекта; если они не заданы, список правил пуст
+func (r *transformRepository) GetTransforms(ctx context.Context, projectID string) (*models.ProjectTransforms, error) {
+	query := `
+        SELECT project_id, rules, updated_at
+        FROM event_transforms
+        WHERE project_id = $1
+    `
+
+	transforms := &models.ProjectTransforms{ProjectID: projectID}
+	var rules []byte
+
+	err := r.db.QueryRowContext(ctx, query, projectID).Scan(
+		&transforms.ProjectID,
+		&rules,
+		&transforms.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		transforms.Rules = []models.TransformRule{}
+		return transforms, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(rules, &transforms.Rules); err != nil {
+		return nil, err
+	}
+
+	return transforms, nil
+}
+
+func (r *transformRepository) SaveTransforms(ctx context.Context, transforms *models.ProjectTransforms) error {
+	query := `
+        INSERT INTO event_transforms (project_id, rules, updated_at)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (project_id)
+        DO UPDATE SET rules = EXCLUDED.rules, updated_at = EXCLUDED.updated_at
+    `
+
+	if transforms.Rules == nil {
+		transforms.Rules = []models.TransformRule{}
+	}
+	transforms.UpdatedAt = time.Now()
+
+	rules, err := json.Marshal(transforms.Rules)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, query, transforms.ProjectID, rules, transforms.UpdatedAt)
+	return err
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/yourusername/event-analytics-service/internal/models"
+)
+
+// ExtractAttribution заполняет UTM метки и идентификаторы кликов из
+// параметров page_url. Значения, переданные клиентом явно, не перезаписываются.
+func ExtractAttribution(event *models.Event) {
+	query := pageQuery(event.PageURL)
+	if len(query) == 0 {
+		return
+	}
+
+	fields := []struct {
+		param string
+		value *string
+	}{
+		{"utm_source", &event.UTMSource},
+		{"utm_medium", &event.UTMMedium},
+		{"utm_campaign", &event.UTMCampaign},
+		{"utm_term", &event.UTMTerm},
+		{"utm_content", &event.UTMContent},
+		{"gclid", &event.GCLID},
+		{"fbclid", &event.FBCLID},
+		{"msclkid", &event.MSCLKID},
+	}
+
+	for _, field := range fields {
+		if *field.value != "" {
+			continue
+		}
+		*field.value = strings.TrimSpace(query.Get(field.param))
+	}
+
+	// Источник и канал сравниваются без учета регистра
+	event.UTMSource = strings.ToLower(event.UTMSource)
+	event.UTMMedium = strings.ToLower(event.UTMMedium)
+}
+
+// pageQuery разбирает параметры запроса из абсолютного или относительного URL
+func pageQuery(pageURL string) url.Values {
+	if !strings.Contains(pageURL, "?") {
+		return nil
+	}
+
+	parsed, err := url.Parse(pageURL)
+	if err != nil {
+		// Битый URL: пробуем разобрать хотя бы часть после "?"
+		raw := pageURL[strings.Index(pageURL, "?")+1:]
+		if i := strings.Index(raw, "#"); i >= 0 {
+			raw = raw[:i]
+		}
+		query, _ := url.ParseQuery(raw)
+		return query
+	}
+
+	query, _ := url.ParseQuery(parsed.RawQuery)
+	return query
+}
+
+// IsExternalReferrer сообщает, что referrer ведет с другого сайта. Заголовок
+// Referer у запросов из браузера обычно указывает на саму страницу, поэтому
+// без абсолютного page_url сравнить их нельзя и referrer не считается внешним.
+func IsExternalReferrer(referrer, pageURL string) bool {
+	ref, err := url.Parse(referrer)
+	if err != nil || ref.Host == "" {
+		return false
+	}
+	page, err := url.Parse(pageURL)
+	if err != nil || page.Host == "" {
+		return false
+	}
+	return !strings.EqualFold(ref.Hostname(), page.Hostname())
+}
@@ -2,51 +2,564 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
+	"github.com/yourusername/event-analytics-service/internal/botdetect"
+	"github.com/yourusername/event-analytics-service/internal/geoip"
 	"github.com/yourusername/event-analytics-service/internal/metrics"
 	"github.com/yourusername/event-analytics-service/internal/models"
 	"github.com/yourusername/event-analytics-service/internal/producer"
 	"github.com/yourusername/event-analytics-service/internal/repository"
+	"github.com/yourusername/event-analytics-service/internal/useragent"
 )
 
+const (
+	// Максимальный размер metadata одного события в сериализованном виде
+	maxMetadataSize = 32 * 1024
+	// Максимальная длина названия события
+	maxEventNameLength = 128
+)
+
+type deliveryModeKey struct{}
+
+// WithDeliveryMode задает режим доставки для событий запроса; он имеет
+// приоритет над настройкой проекта
+func WithDeliveryMode(ctx context.Context, mode models.DeliveryMode) context.Context {
+	return context.WithValue(ctx, deliveryModeKey{}, mode)
+}
+
 type EventService struct {
-	repo     repository.EventRepository
-	producer *producer.EventProducer
-	metrics  *metrics.Metrics
+	repo        repository.EventRepository
+	producer    *producer.EventProducer
+	schemas     *SchemaService
+	projects    *ProjectService
+	quota       *QuotaService
+	cache       *repository.RedisRepository
+	geo         *geoip.Resolver
+	bots        *botdetect.Detector
+	metrics     *metrics.Metrics
+	dedupWindow time.Duration
+	hashSalt    string
+	window      models.TimestampWindow
 }
 
-func NewEventService(repo repository.EventRepository, producer *producer.EventProducer, metrics *metrics.Metrics) *EventService {
+func NewEventService(
+	repo repository.EventRepository,
+	producer *producer.EventProducer,
+	schemas *SchemaService,
+	projects *ProjectService,
+	quota *QuotaService,
+	cache *repository.RedisRepository,
+	geo *geoip.Resolver,
+	bots *botdetect.Detector,
+	metrics *metrics.Metrics,
+	dedupWindow time.Duration,
+	hashSalt string,
+	window models.TimestampWindow,
+) *EventService {
 	return &EventService{
-		repo:     repo,
-		producer: producer,
-		metrics:  metrics,
+		repo:        repo,
+		producer:    producer,
+		schemas:     schemas,
+		projects:    projects,
+		quota:       quota,
+		cache:       cache,
+		geo:         geo,
+		bots:        bots,
+		metrics:     metrics,
+		dedupWindow: dedupWindow,
+		hashSalt:    hashSalt,
+		window:      window,
 	}
 }
 
+// ProcessEvent валидирует событие, применяет правила приватности проекта и
+// отправляет его в Kafka. Если событие с таким ID уже принималось в окне
+// дедупликации, возвращается ErrDuplicateEvent, если исчерпана месячная
+// квота - ErrRateLimitExceeded. В синхронном режиме доставки ошибка брокера
+// возвращается как ErrEventNotDelivered.
 func (s *EventService) ProcessEvent(ctx context.Context, event *models.Event) error {
+	clientID := event.ID != ""
+
+	if err := s.prepareEvent(ctx, event); err != nil {
+		s.metrics.IncrementEventsFailed(string(event.EventType), "validation")
+		return err
+	}
+
+	project, err := s.project(ctx, event.ProjectID, make(map[string]*models.Project))
+	if err != nil {
+		return err
+	}
+
+	// Событие вне выборки считается принятым, но не сохраняется
+	if !s.sample(event, project) {
+		s.metrics.IncrementEventsSampledOut(string(event.EventType), event.ProjectID)
+		return nil
+	}
+
+	if err := s.applyPrivacy(event, project); err != nil {
+		return err
+	}
+
+	// Ключ дедупликации есть только у событий с ID клиента, которые удалось отметить
+	marked := false
+	if clientID {
+		duplicate, ok := s.isDuplicate(ctx, event)
+		if duplicate {
+			return models.ErrDuplicateEvent
+		}
+		marked = ok
+	}
+
+	if s.reserveQuota(ctx, project, 1) == 0 {
+		if marked {
+			s.cache.ForgetEvent(ctx, event.ProjectID, event.ID)
+		}
+		return models.ErrRateLimitExceeded
+	}
+
+	s.checkBotRate(ctx, event)
+
+	if s.deliveryMode(ctx, project) == models.DeliveryModeSync {
+		err = s.producer.SendEventSync(ctx, event)
+		if err != nil {
+			err = fmt.Errorf("%w: %v", models.ErrEventNotDelivered, err)
+		}
+	} else {
+		err = s.producer.SendEvent(ctx, event)
+	}
+	if err != nil {
+		s.metrics.IncrementEventsFailed(string(event.EventType), "producer")
+		if marked {
+			s.cache.ForgetEvent(ctx, event.ProjectID, event.ID)
+		}
+		s.releaseQuota(ctx, map[string]int64{event.ProjectID: 1})
+		return err
+	}
+
+	s.metrics.IncrementEventsReceived(string(event.EventType), event.ProjectID)
+
+	return nil
+}
+
+// ProcessBatch валидирует каждое событие отдельно и отправляет прошедшие
+// проверку одним вызовом в Kafka. Результат содержит статус для каждого
+// события в порядке следования во входном массиве; ошибка возвращается,
+// только если не удалось опубликовать батч.
+func (s *EventService) ProcessBatch(ctx context.Context, events []*models.Event) ([]models.BatchEventResult, error) {
+	results := make([]models.BatchEventResult, len(events))
+	valid := make([]*models.Event, 0, len(events))
+	validIdx := make([]int, 0, len(events))
+	// marked[i] - для события отмечен ключ дедупликации, который нужно
+	// снять, если событие не будет принято
+	marked := make([]bool, len(events))
+	projects := make(map[string]*models.Project)
+	sync := false
+
+	for i, event := range events {
+		results[i].Index = i
+		clientID := event.ID != ""
+
+		if err := s.prepareEvent(ctx, event); err != nil {
+			s.metrics.IncrementEventsFailed(string(event.EventType), "validation")
+			results[i].Status = models.BatchStatusRejected
+			results[i].Error = err.Error()
+
+			var schemaErr *models.SchemaValidationError
+			if errors.As(err, &schemaErr) {
+				results[i].Error = "event does not match schema"
+				results[i].Errors = schemaErr.Errors
+			}
+			continue
+		}
+
+		project, err := s.project(ctx, event.ProjectID, projects)
+		if err != nil {
+			results[i].Status = models.BatchStatusRejected
+			results[i].Error = "failed to process event"
+			continue
+		}
+
+		if !s.sample(event, project) {
+			s.metrics.IncrementEventsSampledOut(string(event.EventType), event.ProjectID)
+			results[i].Status = models.BatchStatusAccepted
+			results[i].EventID = event.ID
+			continue
+		}
+
+		if err := s.applyPrivacy(event, project); err != nil {
+			results[i].Status = models.BatchStatusRejected
+			results[i].Error = "failed to process event"
+			continue
+		}
+
+		results[i].EventID = event.ID
+		if clientID {
+			duplicate, ok := s.isDuplicate(ctx, event)
+			if duplicate {
+				results[i].Status = models.BatchStatusDuplicate
+				continue
+			}
+			marked[i] = ok
+		}
+
+		results[i].Warnings = event.Warnings
+		valid = append(valid, event)
+		validIdx = append(validIdx, i)
+
+		// Батч уходит в Kafka одним вызовом, поэтому синхронный режим
+		// хотя бы одного события распространяется на весь батч
+		if s.deliveryMode(ctx, project) == models.DeliveryModeSync {
+			sync = true
+		}
+	}
+
+	valid, validIdx = s.reserveBatchQuota(ctx, valid, validIdx, marked, projects, results)
+	if len(valid) == 0 {
+		return results, nil
+	}
+
+	usage := make(map[string]int64)
+	for _, event := range valid {
+		usage[event.ProjectID]++
+		s.checkBotRate(ctx, event)
+	}
+
+	var err error
+	if sync {
+		err = s.producer.SendBatchSync(ctx, valid)
+		if err != nil {
+			err = fmt.Errorf("%w: %v", models.ErrEventNotDelivered, err)
+		}
+	} else {
+		err = s.producer.SendBatch(ctx, valid)
+	}
+	if err != nil {
+		s.metrics.IncrementEventsFailed("batch", "producer")
+		for _, i := range validIdx {
+			results[i].Status = models.BatchStatusRejected
+			results[i].Error = "failed to publish event"
+			if marked[i] {
+				s.cache.ForgetEvent(ctx, events[i].ProjectID, events[i].ID)
+			}
+		}
+		s.releaseQuota(ctx, usage)
+		return results, err
+	}
+
+	for j, i := range validIdx {
+		results[i].Status = models.BatchStatusAccepted
+		s.metrics.IncrementEventsReceived(string(valid[j].EventType), valid[j].ProjectID)
+	}
+
+	return results, nil
+}
+
+// reserveBatchQuota резервирует квоту на прошедшие проверку события батча
+// одним вызовом на проект. События, не уместившиеся в квоту, отклоняются с
+// конца батча.
+func (s *EventService) reserveBatchQuota(ctx context.Context, valid []*models.Event, validIdx []int, marked []bool, projects map[string]*models.Project, results []models.BatchEventResult) ([]*models.Event, []int) {
+	counts := make(map[string]int64)
+	for _, event := range valid {
+		counts[event.ProjectID]++
+	}
+
+	allowed := make(map[string]int64, len(counts))
+	for projectID, count := range counts {
+		allowed[projectID] = s.reserveQuota(ctx, projects[projectID], count)
+	}
+
+	keptEvents := valid[:0]
+	keptIdx := validIdx[:0]
+	for j, event := range valid {
+		if allowed[event.ProjectID] == 0 {
+			i := validIdx[j]
+			results[i].Status = models.BatchStatusRejected
+			results[i].Error = models.ErrRateLimitExceeded.Error()
+			results[i].Warnings = nil
+			if marked[i] {
+				s.cache.ForgetEvent(ctx, event.ProjectID, event.ID)
+			}
+			continue
+		}
+		allowed[event.ProjectID]--
+		keptEvents = append(keptEvents, event)
+		keptIdx = append(keptIdx, validIdx[j])
+	}
+
+	return keptEvents, keptIdx
+}
+
+// reserveQuota резервирует count событий в месячной квоте проекта и
+// возвращает, сколько из них можно принять
+func (s *EventService) reserveQuota(ctx context.Context, project *models.Project, count int64) int64 {
+	if s.quota == nil {
+		return count
+	}
+
+	accepted := s.quota.ReserveEvents(ctx, project, count)
+	if accepted < count {
+		s.metrics.IncrementRateLimited(project.ID, "monthly_quota")
+	}
+	return accepted
+}
+
+// checkBotRate отмечает ботом пользователя, который шлет слишком много
+// событий. Вызывается после дедупликации и резервирования квоты: повторы и
+// отклоненные события не учитываются в частоте.
+func (s *EventService) checkBotRate(ctx context.Context, event *models.Event) {
+	if s.bots == nil || event.IsBot {
+		return
+	}
+	if s.bots.ExceedsRate(ctx, event) {
+		event.IsBot = true
+		s.metrics.IncrementBotEvents(botdetect.ReasonRate)
+	}
+}
+
+// releaseQuota возвращает в квоту события, которые не удалось отправить
+func (s *EventService) releaseQuota(ctx context.Context, usage map[string]int64) {
+	if s.quota == nil {
+		return
+	}
+	for projectID, count := range usage {
+		s.quota.ReleaseEvents(ctx, projectID, count)
+	}
+}
+
+// project возвращает настройки проекта события. Если они недоступны, событие
+// не принимается: без правил приватности сырые персональные данные не должны
+// попасть в хранилище. Проекты кэшируются в cache на время обработки запроса.
+func (s *EventService) project(ctx context.Context, projectID string, cache map[string]*models.Project) (*models.Project, error) {
+	if project, ok := cache[projectID]; ok {
+		return project, nil
+	}
+
+	project, err := s.projects.GetCachedProject(ctx, projectID)
+	if err != nil {
+		s.metrics.IncrementDBError("project_lookup", "projects")
+		return nil, err
+	}
+
+	cache[projectID] = project
+	return project, nil
+}
+
+// deliveryMode выбирает режим доставки: заголовок запроса, затем настройка
+// проекта, по умолчанию - асинхронный
+func (s *EventService) deliveryMode(ctx context.Context, project *models.Project) models.DeliveryMode {
+	if mode, ok := ctx.Value(deliveryModeKey{}).(models.DeliveryMode); ok && mode != "" {
+		return mode
+	}
+
+	mode, err := project.DeliveryMode()
+	if err != nil || mode == "" {
+		return models.DeliveryModeAsync
+	}
+	return mode
+}
+
+// applyPrivacy применяет правила приватности проекта до отправки события в Kafka
+func (s *EventService) applyPrivacy(event *models.Event, project *models.Project) error {
+	privacy, err := project.Privacy()
+	if err != nil {
+		return err
+	}
+
+	if !privacy.Enabled() {
+		return nil
+	}
+
+	// Страну определяем до того, как IP будет обрезан или захэширован
+	if privacy.IPMode != models.IPModeKeep && s.geo != nil && event.CountryCode == "" {
+		if loc, ok := s.geo.Lookup(event.IPAddress); ok {
+			event.CountryCode = loc.CountryCode
+			event.Region = loc.Region
+		}
+	}
+
+	ApplyPrivacy(event, privacy, s.hashSalt)
+	return nil
+}
+
+// isDuplicate отмечает ID события как увиденный и сообщает, встречался ли он
+// раньше и была ли поставлена отметка. При недоступности Redis событие
+// пропускается: повторы все равно схлопнутся в ClickHouse.
+func (s *EventService) isDuplicate(ctx context.Context, event *models.Event) (duplicate, marked bool) {
+	seen, err := s.cache.MarkEventSeen(ctx, event.ProjectID, event.ID, s.dedupWindow)
+	if err != nil {
+		return false, false
+	}
+	if seen {
+		s.metrics.IncrementEventsFailed(string(event.EventType), "duplicate")
+		return true, false
+	}
+	return false, true
+}
+
+// prepareEvent обогащает событие значениями по умолчанию и валидирует его
+func (s *EventService) prepareEvent(ctx context.Context, event *models.Event) error {
 	// Обогащаем событие
 	if event.ID == "" {
 		event.ID = uuid.New().String()
 	}
+	if event.UserID == "" {
+		event.UserID = event.AnonymousID
+	}
 
-	if event.Timestamp.IsZero() {
-		event.Timestamp = time.Now()
+	// Время приема всегда берется с часов сервера: по нему корректируется
+	// timestamp и считается задержка приема
+	event.ReceivedAt = time.Now()
+	if err := CorrectTimestamp(event, s.window); err != nil {
+		return err
 	}
 
-	// Базовая валидация
+	// Базовая валидация: событие только с названием считается custom,
+	// а без названия получает название по типу
 	if event.EventType == "" {
-		event.EventType = models.PageView
+		if event.EventName != "" {
+			event.EventType = models.Custom
+		} else {
+			event.EventType = models.PageView
+		}
+	}
+	if event.EventName == "" {
+		event.EventName = string(event.EventType)
 	}
 
-	// Отправляем в Kafka асинхронно
-	if err := s.producer.SendEvent(ctx, event); err != nil {
-		s.metrics.Increment("event.producer.errors")
+	// Устройство, браузер и ОС из User-Agent, если клиент не передал их сам
+	if event.DeviceType == "" && event.Browser == "" && event.OS == "" {
+		ua := useragent.Parse(event.UserAgent)
+		event.DeviceType = ua.DeviceType
+		event.Browser = ua.Browser
+		event.OS = ua.OS
+	}
+
+	ExtractAttribution(event)
+
+	if err := validateEvent(event); err != nil {
 		return err
 	}
 
-	s.metrics.IncrementEventsReceived(string(event.EventType), event.ProjectID)
+	// IP проверяется до анонимизации, поэтому классификация идет здесь, а не
+	// в консьюмере; частота событий проверяется после приема (checkBotRate)
+	if s.bots != nil {
+		if bot, reason := s.bots.Classify(event); bot {
+			event.IsBot = true
+			s.metrics.IncrementBotEvents(reason)
+		}
+	}
+
+	return s.validateSchema(ctx, event)
+}
+
+// CorrectTimestamp переводит время события на часы сервера: если клиент
+// передал sent_at, timestamp сдвигается на разницу между временем приема и
+// sent_at. Время за пределами окна приема сдвигается на границу окна или
+// событие отклоняется, в зависимости от политики. ReceivedAt выставляет
+// сервер; если он не задан, используется текущее время.
+func CorrectTimestamp(event *models.Event, window models.TimestampWindow) error {
+	if event.ReceivedAt.IsZero() {
+		event.ReceivedAt = time.Now()
+	}
+
+	if event.Timestamp.IsZero() {
+		event.Timestamp = event.ReceivedAt
+	} else if !event.SentAt.IsZero() {
+		event.Timestamp = event.Timestamp.Add(event.ReceivedAt.Sub(event.SentAt))
+	}
+
+	var bound time.Time
+	switch {
+	case window.MaxPast > 0 && event.Timestamp.Before(event.ReceivedAt.Add(-window.MaxPast)):
+		bound = event.ReceivedAt.Add(-window.MaxPast)
+	case window.MaxFuture > 0 && event.Timestamp.After(event.ReceivedAt.Add(window.MaxFuture)):
+		bound = event.ReceivedAt.Add(window.MaxFuture)
+	default:
+		return nil
+	}
+
+	if window.Policy == models.TimestampPolicyReject {
+		return models.ErrInvalidTimestamp
+	}
 
+	event.Timestamp = bound
+	event.Warnings = append(event.Warnings, models.ValidationError{
+		Field:   "timestamp",
+		Message: "outside acceptance window, clamped to " + bound.UTC().Format(time.RFC3339),
+	})
 	return nil
 }
+
+// validateSchema проверяет Metadata по схеме проекта: в strict режиме
+// событие отклоняется, в warn - принимается с предупреждениями.
+// Если реестр схем недоступен, событие принимается без проверки.
+func (s *EventService) validateSchema(ctx context.Context, event *models.Event) error {
+	mode, fieldErrors, err := s.schemas.Validate(ctx, event)
+	if err != nil {
+		s.metrics.IncrementDBError("schema_lookup", "event_schemas")
+		return nil
+	}
+	if len(fieldErrors) == 0 {
+		return nil
+	}
+
+	switch mode {
+	case models.SchemaModeStrict:
+		return &models.SchemaValidationError{Errors: fieldErrors}
+	case models.SchemaModeWarn:
+		s.metrics.IncrementEventsFailed(string(event.EventType), "schema_warning")
+		event.Warnings = append(event.Warnings, fieldErrors...)
+	}
+
+	return nil
+}
+
+func validateEvent(event *models.Event) error {
+	if event.ProjectID == "" {
+		return models.ErrInvalidAPIKey
+	}
+
+	switch event.EventType {
+	case models.PageView, models.ButtonClick, models.FormSubmit, models.Purchase, models.Custom:
+	default:
+		return models.ErrInvalidEventType
+	}
+
+	if !validEventName(event.EventName) {
+		return models.ErrInvalidEventName
+	}
+
+	if len(event.Metadata) > 0 {
+		data, err := json.Marshal(event.Metadata)
+		if err != nil {
+			return models.ErrInvalidEventData
+		}
+		if len(data) > maxMetadataSize {
+			return models.ErrEventTooLarge
+		}
+	}
+
+	return nil
+}
+
+// validEventName допускает любые печатные символы, включая пробелы
+// ("Order Completed"), длиной до maxEventNameLength
+func validEventName(name string) bool {
+	if name == "" || len(name) > maxEventNameLength || !utf8.ValidString(name) {
+		return false
+	}
+	for _, r := range name {
+		if unicode.IsControl(r) {
+			return false
+		}
+	}
+	return strings.TrimSpace(name) == name
+}
@@ -24,8 +24,7 @@ func NewExportService(eventRepo repository.EventRepository) *ExportService {
 
 func (s *ExportService) ExportToCSV(ctx context.Context, filter models.StatsRequest) ([]byte, error) {
 	// Получаем события
-	events, err := s.eventRepo.GetEventsByType(ctx, filter.EventType,
-		parseDate(filter.StartDate), parseDate(filter.EndDate))
+	events, err := s.eventRepo.GetEvents(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -35,7 +34,7 @@ func (s *ExportService) ExportToCSV(ctx context.Context, filter models.StatsRequ
 	writer := csv.NewWriter(buf)
 
 	// Записываем заголовки
-	headers := []string{"ID", "UserID", "EventType", "PageURL", "Timestamp", "UserAgent", "IPAddress"}
+	headers := []string{"ID", "UserID", "EventType", "EventName", "PageURL", "Timestamp", "UserAgent", "IPAddress"}
 	if err := writer.Write(headers); err != nil {
 		return nil, err
 	}
@@ -46,6 +45,7 @@ func (s *ExportService) ExportToCSV(ctx context.Context, filter models.StatsRequ
 			event.ID,
 			event.UserID,
 			string(event.EventType),
+			event.EventName,
 			event.PageURL,
 			event.Timestamp.Format(time.RFC3339),
 			event.UserAgent,
@@ -61,8 +61,7 @@ func (s *ExportService) ExportToCSV(ctx context.Context, filter models.StatsRequ
 }
 
 func (s *ExportService) ExportToJSON(ctx context.Context, filter models.StatsRequest) ([]byte, error) {
-	events, err := s.eventRepo.GetEventsByType(ctx, filter.EventType,
-		parseDate(filter.StartDate), parseDate(filter.EndDate))
+	events, err := s.eventRepo.GetEvents(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -80,13 +79,14 @@ func (s *ExportService) ExportAggregatedCSV(ctx context.Context, filter models.S
 	writer := csv.NewWriter(buf)
 
 	// Заголовки для агрегированных данных
-	headers := []string{"TimeBucket", "EventType", "Count"}
+	headers := []string{"TimeBucket", "EventType", "EventName", "Count"}
 	writer.Write(headers)
 
 	for _, stat := range stats {
 		record := []string{
 			stat.TimeBucket,
 			stat.EventType,
+			stat.EventName,
 			fmt.Sprintf("%d", stat.Count),
 		}
 		writer.Write(record)
@@ -95,8 +95,3 @@ func (s *ExportService) ExportAggregatedCSV(ctx context.Context, filter models.S
 	writer.Flush()
 	return buf.Bytes(), nil
 }
-
-func parseDate(dateStr string) time.Time {
-	t, _ := time.Parse("2006-01-02", dateStr)
-	return t
-}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/yourusername/event-analytics-service/internal/models"
+	"github.com/yourusername/event-analytics-service/internal/repository"
+)
+
+type IdentityService struct {
+	identityRepo repository.IdentityRepository
+	projects     *ProjectService
+	hashSalt     string
+}
+
+func NewIdentityService(identityRepo repository.IdentityRepository, projects *ProjectService, hashSalt string) *IdentityService {
+	return &IdentityService{
+		identityRepo: identityRepo,
+		projects:     projects,
+		hashSalt:     hashSalt,
+	}
+}
+
+// Identify сохраняет атрибуты пользователя. К атрибутам применяются те же
+// правила приватности проекта, что и к metadata событий. Если переданы и
+// user_id, и anonymous_id, сохраняется связь anonymous_id -> user_id, чтобы
+// анонимные события до входа относились к тому же пользователю.
+func (s *IdentityService) Identify(ctx context.Context, traits *models.UserTraits) error {
+	if traits.ProjectID == "" {
+		return models.ErrInvalidAPIKey
+	}
+	if traits.UserID == "" && traits.AnonymousID == "" {
+		return models.ErrInvalidEventData
+	}
+	if traits.Timestamp.IsZero() {
+		traits.Timestamp = time.Now()
+	}
+
+	project, err := s.projects.GetCachedProject(ctx, traits.ProjectID)
+	if err != nil {
+		return err
+	}
+	privacy, err := project.Privacy()
+	if err != nil {
+		return err
+	}
+	if privacy.Enabled() {
+		// IP не относится к атрибутам, обрабатываются только ключи и значения
+		privacy.IPMode = models.IPModeKeep
+		holder := &models.Event{ProjectID: traits.ProjectID, Metadata: traits.Traits}
+		ApplyPrivacy(holder, privacy, s.hashSalt)
+	}
+
+	if err := s.identityRepo.InsertTraits(ctx, traits); err != nil {
+		return err
+	}
+
+	if traits.UserID == "" || traits.AnonymousID == "" || traits.UserID == traits.AnonymousID {
+		return nil
+	}
+
+	return s.identityRepo.InsertAlias(ctx, &models.UserAlias{
+		ProjectID:  traits.ProjectID,
+		PreviousID: traits.AnonymousID,
+		UserID:     traits.UserID,
+		Timestamp:  traits.Timestamp,
+	})
+}
+
+// Alias связывает предыдущий идентификатор пользователя (обычно анонимный)
+// с постоянным user_id
+func (s *IdentityService) Alias(ctx context.Context, alias *models.UserAlias) error {
+	if alias.ProjectID == "" {
+		return models.ErrInvalidAPIKey
+	}
+	if alias.PreviousID == "" || alias.UserID == "" || alias.PreviousID == alias.UserID {
+		return models.ErrInvalidEventData
+	}
+	if alias.Timestamp.IsZero() {
+		alias.Timestamp = time.Now()
+	}
+
+	return s.identityRepo.InsertAlias(ctx, alias)
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/yourusername/event-analytics-service/internal/models"
+)
+
+const (
+	redactedEmail = "[email]"
+	redactedPhone = "[phone]"
+)
+
+var (
+	// Учитываем URL-кодированный @ (%40) в адресах страниц
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+(?:@|%40)[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	// Номер телефона - только в явном формате: международный с "+" или %2B
+	// (от 7 до 15 цифр), с кодом в скобках ("(999) 123-45-67") или
+	// 555-123-4567. Сплошные цифры (ID, timestamp), даты и суммы не
+	// считаются телефонами.
+	phonePattern = regexp.MustCompile(`(?:\+|%2[Bb])\d(?:[\s().-]{0,3}\d){6,14}` +
+		`|(?:\d{1,3}[\s.-]?)?\(\d{2,5}\)[\s.-]?\d{2,4}(?:[\s.-]?\d{2,4}){1,2}` +
+		`|\d{3}[.-]\d{3}[.-]\d{4}`)
+)
+
+// ApplyPrivacy обрабатывает IP адрес, metadata и URL события по правилам
+// проекта. salt используется для хэширования, чтобы хэши нельзя было
+// сопоставить между инсталляциями.
+func ApplyPrivacy(event *models.Event, settings models.PrivacySettings, salt string) {
+	switch settings.IPMode {
+	case models.IPModeTruncate:
+		event.IPAddress = truncateIP(event.IPAddress)
+	case models.IPModeHash:
+		if event.IPAddress != "" {
+			event.IPAddress = hashValue(salt, event.ProjectID, event.IPAddress)
+		}
+	}
+
+	hashed := make(map[string]bool, len(settings.HashKeys))
+	if event.Metadata != nil {
+		for _, key := range settings.DropKeys {
+			delete(event.Metadata, key)
+		}
+
+		for _, key := range settings.HashKeys {
+			value, ok := event.Metadata[key]
+			if !ok || value == nil {
+				continue
+			}
+			event.Metadata[key] = hashValue(salt, event.ProjectID, stringify(value))
+			hashed[key] = true
+		}
+	}
+
+	if !settings.RedactEmails && !settings.RedactPhones {
+		return
+	}
+
+	// Метки кампании и referrer тоже бывают с email (например, utm_content из рассылки)
+	for _, field := range []*string{
+		&event.PageURL, &event.Referrer, &event.UTMSource, &event.UTMMedium,
+		&event.UTMCampaign, &event.UTMTerm, &event.UTMContent,
+	} {
+		*field = redactString(*field, settings)
+	}
+	for key, value := range event.Metadata {
+		if hashed[key] {
+			continue
+		}
+		event.Metadata[key] = redactValue(value, settings)
+	}
+}
+
+// truncateIP оставляет сеть /24 для IPv4 и /48 для IPv6; некорректный адрес удаляется
+func truncateIP(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+
+	if v4 := parsed.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String()
+	}
+	return parsed.Mask(net.CIDRMask(48, 128)).String()
+}
+
+func hashValue(salt, projectID, value string) string {
+	mac := hmac.New(sha256.New, []byte(salt))
+	mac.Write([]byte(projectID))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+func stringify(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+func redactValue(value interface{}, settings models.PrivacySettings) interface{} {
+	switch v := value.(type) {
+	case string:
+		return redactString(v, settings)
+	case map[string]interface{}:
+		for key, nested := range v {
+			v[key] = redactValue(nested, settings)
+		}
+		return v
+	case []interface{}:
+		for i, nested := range v {
+			v[i] = redactValue(nested, settings)
+		}
+		return v
+	}
+	return value
+}
+
+func redactString(s string, settings models.PrivacySettings) string {
+	if settings.RedactEmails {
+		s = emailPattern.ReplaceAllString(s, redactedEmail)
+	}
+	if settings.RedactPhones {
+		s = redactPhones(s)
+	}
+	return s
+}
+
+// redactPhones заменяет номера телефонов, которые стоят отдельно: совпадение
+// внутри слова, идентификатора или сегмента пути URL ("/orders/555-123-4567")
+// не заменяется
+func redactPhones(s string) string {
+	matches := phonePattern.FindAllStringIndex(s, -1)
+	if len(matches) == 0 {
+		return s
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		if (m[0] > 0 && (isIDChar(s[m[0]-1]) || s[m[0]-1] == '.')) || continuesID(s[m[1]:]) {
+			continue
+		}
+		b.WriteString(s[last:m[0]])
+		b.WriteString(redactedPhone)
+		last = m[1]
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// continuesID сообщает, продолжает ли rest идентификатор или путь; точка в
+// конце предложения его не продолжает
+func continuesID(rest string) bool {
+	if rest == "" {
+		return false
+	}
+	if rest[0] == '.' {
+		return len(rest) > 1 && isIDChar(rest[1])
+	}
+	return isIDChar(rest[0])
+}
+
+// isIDChar сообщает, может ли символ быть частью идентификатора или пути
+func isIDChar(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' ||
+		c == '_' || c == '-' || c == '/'
+}
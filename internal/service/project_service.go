@@ -45,6 +45,16 @@ func (s *ProjectService) CreateProject(ctx context.Context, userID string, name
 		Active:   true,
 	}
 
+	if _, err := project.Privacy(); err != nil {
+		return nil, err
+	}
+	if _, err := project.DeliveryMode(); err != nil {
+		return nil, err
+	}
+	if _, err := project.Sampling(); err != nil {
+		return nil, err
+	}
+
 	if err := s.projectRepo.CreateProject(ctx, project); err != nil {
 		return nil, err
 	}
@@ -66,6 +76,31 @@ func (s *ProjectService) GetProject(ctx context.Context, projectID, userID strin
 	return project, nil
 }
 
+// GetCachedProject возвращает проект без проверки владельца, используя кэш
+// Redis; применяется на пути приема событий
+func (s *ProjectService) GetCachedProject(ctx context.Context, projectID string) (*models.Project, error) {
+	cacheKey := "project:" + projectID
+	cached, err := s.cacheRepo.Client.Get(ctx, cacheKey).Result()
+	if err == nil {
+		var project models.Project
+		if json.Unmarshal([]byte(cached), &project) == nil {
+			return &project, nil
+		}
+	}
+
+	project, err := s.projectRepo.GetProjectByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Cache for 5 minutes
+	if data, err := json.Marshal(project); err == nil {
+		s.cacheRepo.Client.Set(ctx, cacheKey, data, 5*time.Minute)
+	}
+
+	return project, nil
+}
+
 func (s *ProjectService) GetUserProjects(ctx context.Context, userID string, limit, offset int) ([]*models.Project, error) {
 	return s.projectRepo.GetProjectsByUserID(ctx, userID, limit, offset)
 }
@@ -90,13 +125,23 @@ func (s *ProjectService) UpdateProject(ctx context.Context, projectID, userID st
 		project.Settings = settings
 	}
 
+	if _, err := project.Privacy(); err != nil {
+		return nil, err
+	}
+	if _, err := project.DeliveryMode(); err != nil {
+		return nil, err
+	}
+	if _, err := project.Sampling(); err != nil {
+		return nil, err
+	}
+
 	// Save
 	if err := s.projectRepo.UpdateProject(ctx, project); err != nil {
 		return nil, err
 	}
 
 	// Invalidate cache
-	s.cacheRepo.Client.Del(ctx, "project:"+projectID)
+	s.cacheRepo.Client.Del(ctx, "project:"+projectID, "apikey:"+project.APIKey)
 
 	return project, nil
 }
@@ -118,7 +163,7 @@ func (s *ProjectService) DeleteProject(ctx context.Context, projectID, userID st
 	}
 
 	// Invalidate cache
-	s.cacheRepo.Client.Del(ctx, "project:"+projectID)
+	s.cacheRepo.Client.Del(ctx, "project:"+projectID, "apikey:"+project.APIKey)
 
 	return nil
 }
@@ -140,6 +185,9 @@ func (s *ProjectService) RegenerateAPIKey(ctx context.Context, projectID, userID
 		return "", err
 	}
 
+	// Old key must stop working immediately
+	s.cacheRepo.Client.Del(ctx, "project:"+projectID, "apikey:"+project.APIKey)
+
 	return newKey, nil
 }
 
@@ -202,3 +250,65 @@ func (s *ProjectService) GetProjectStats(ctx context.Context, projectID, userID
 
 	return stats, nil
 }
+
+// GetFunnel считает воронку по названиям событий для проекта пользователя
+func (s *ProjectService) GetFunnel(ctx context.Context, projectID, userID string, steps []string, start, end time.Time) ([]models.FunnelStep, error) {
+	project, err := s.projectRepo.GetProjectByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	if project.UserID != userID {
+		return nil, models.ErrProjectAccessDenied
+	}
+
+	return s.eventRepo.GetFunnelAnalysis(ctx, projectID, steps, start, end)
+}
+
+// Перерыв в активности пользователя, после которого начинается новая сессия
+const trafficSessionTimeout = 30 * time.Minute
+
+// GetUserSessions возвращает сессии посетителя проекта по его user_id или
+// anonymous_id; события под связанными идентификаторами объединяются
+func (s *ProjectService) GetUserSessions(ctx context.Context, projectID, userID, visitorID string) ([]models.UserSession, error) {
+	project, err := s.projectRepo.GetProjectByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	if project.UserID != userID {
+		return nil, models.ErrProjectAccessDenied
+	}
+
+	return s.eventRepo.GetUserSessions(ctx, projectID, visitorID, trafficSessionTimeout)
+}
+
+// GetIngestionLatency возвращает задержку приема событий проекта пользователя
+// за период с разбивкой по интервалу (hour или day)
+func (s *ProjectService) GetIngestionLatency(ctx context.Context, projectID, userID, interval string, start, end time.Time) (*models.IngestionLatencyReport, error) {
+	project, err := s.projectRepo.GetProjectByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	if project.UserID != userID {
+		return nil, models.ErrProjectAccessDenied
+	}
+
+	return s.eventRepo.GetIngestionLatency(ctx, projectID, interval, start, end)
+}
+
+// GetTrafficSources возвращает сессии и конверсии по источникам трафика
+// проекта пользователя; конверсией считается событие conversionEvent
+func (s *ProjectService) GetTrafficSources(ctx context.Context, projectID, userID, conversionEvent string, start, end time.Time) ([]models.TrafficSource, error) {
+	project, err := s.projectRepo.GetProjectByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	if project.UserID != userID {
+		return nil, models.ErrProjectAccessDenied
+	}
+
+	return s.eventRepo.GetTrafficSources(ctx, projectID, conversionEvent, trafficSessionTimeout, start, end)
+}
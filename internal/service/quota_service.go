@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/yourusername/event-analytics-service/internal/models"
+	"github.com/yourusername/event-analytics-service/internal/repository"
+)
+
+type QuotaService struct {
+	cacheRepo        *repository.RedisRepository
+	projectRepo      repository.ProjectRepository
+	defaultQuotaMode string
+
+	// Проекты, чей счетчик в Redis изменился с последней синхронизации
+	// projects.events_count
+	mu    sync.Mutex
+	dirty map[string]struct{}
+}
+
+func NewQuotaService(cacheRepo *repository.RedisRepository, projectRepo repository.ProjectRepository, defaultQuotaMode string) *QuotaService {
+	if defaultQuotaMode != models.QuotaModeSoft {
+		defaultQuotaMode = models.QuotaModeHard
+	}
+
+	return &QuotaService{
+		cacheRepo:        cacheRepo,
+		projectRepo:      projectRepo,
+		defaultQuotaMode: defaultQuotaMode,
+		dirty:            make(map[string]struct{}),
+	}
+}
+
+// CheckRateLimit учитывает запрос в лимите запросов в секунду по плану проекта
+func (s *QuotaService) CheckRateLimit(ctx context.Context, project *models.Project) (*models.RateLimitStatus, error) {
+	plan := models.GetPlan(project.PlanType)
+	return s.cacheRepo.CheckRateLimit(ctx, project.ID, plan.RequestsPerSecond, time.Second)
+}
+
+// CheckQuota возвращает состояние месячной квоты событий. Сами события
+// резервируются в квоте перед отправкой в Kafka (см. ReserveEvents).
+func (s *QuotaService) CheckQuota(ctx context.Context, project *models.Project) (*models.RateLimitStatus, error) {
+	now := time.Now().UTC()
+	used, err := s.cacheRepo.GetMonthlyUsage(ctx, project.ID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := project.MonthlyQuota()
+	remaining := limit - used
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return &models.RateLimitStatus{
+		Limit:     limit,
+		Remaining: remaining,
+		Reset:     time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC),
+		Exceeded:  used >= limit,
+	}, nil
+}
+
+// QuotaMode возвращает режим квоты проекта с учетом значения по умолчанию
+func (s *QuotaService) QuotaMode(project *models.Project) string {
+	return project.QuotaMode(s.defaultQuotaMode)
+}
+
+// ReserveEvents учитывает count событий проекта в месячной квоте и
+// возвращает, сколько из них в нее уложилось. Счетчик увеличивается сразу на
+// весь батч, поэтому параллельные запросы не могут вместе превысить квоту.
+// В мягком режиме и при недоступности Redis принимаются все события.
+func (s *QuotaService) ReserveEvents(ctx context.Context, project *models.Project, count int64) int64 {
+	used, err := s.cacheRepo.IncrementMonthlyUsage(ctx, project.ID, count)
+	if err != nil {
+		return count
+	}
+	s.markDirty(project.ID)
+
+	over := used - project.MonthlyQuota()
+	if over <= 0 || s.QuotaMode(project) == models.QuotaModeSoft {
+		return count
+	}
+	if over > count {
+		over = count
+	}
+
+	// Отклоненные события не должны занимать квоту
+	s.cacheRepo.DecrementMonthlyUsage(ctx, project.ID, over)
+	return count - over
+}
+
+// ReleaseEvents возвращает в квоту зарезервированные события, которые не
+// удалось отправить в Kafka
+func (s *QuotaService) ReleaseEvents(ctx context.Context, projectID string, count int64) {
+	if err := s.cacheRepo.DecrementMonthlyUsage(ctx, projectID, count); err == nil {
+		s.markDirty(projectID)
+	}
+}
+
+// SyncUsage периодически записывает использование квоты текущего месяца из
+// Redis в projects.events_count. Блокируется до отмены ctx; последнюю
+// синхронизацию при остановке выполняет FlushUsage.
+func (s *QuotaService) SyncUsage(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.FlushUsage(ctx)
+		}
+	}
+}
+
+// FlushUsage записывает в projects.events_count счетчики проектов, которые
+// изменились с прошлой синхронизации. Не записанные из-за ошибки проекты
+// синхронизируются в следующий раз.
+func (s *QuotaService) FlushUsage(ctx context.Context) {
+	s.mu.Lock()
+	dirty := s.dirty
+	s.dirty = make(map[string]struct{})
+	s.mu.Unlock()
+
+	now := time.Now()
+	for projectID := range dirty {
+		used, err := s.cacheRepo.GetMonthlyUsage(ctx, projectID, now)
+		if err == nil {
+			err = s.projectRepo.UpdateEventsCount(ctx, projectID, used)
+		}
+		if err != nil {
+			log.Printf("Failed to sync events count of project %s: %v", projectID, err)
+			s.markDirty(projectID)
+		}
+	}
+}
+
+func (s *QuotaService) markDirty(projectID string) {
+	s.mu.Lock()
+	s.dirty[projectID] = struct{}{}
+	s.mu.Unlock()
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"hash/fnv"
+	"math"
+
+	"github.com/yourusername/event-analytics-service/internal/models"
+)
+
+// SampleKey решает, попадает ли ключ в выборку с долей rate. Решение
+// детерминировано: один и тот же ключ при одной доле всегда сохраняется или
+// всегда отбрасывается, а ключи, попавшие в выборку с меньшей долей, попадают
+// и в выборку с большей.
+func SampleKey(key string, rate float64) bool {
+	if rate >= 1 {
+		return true
+	}
+	if rate <= 0 {
+		return false
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return float64(h.Sum64()) < rate*math.MaxUint64
+}
+
+// sample применяет выборку проекта к событию и записывает в него долю.
+// Ключ выборки - пользователь, поэтому его события сохраняются или
+// отбрасываются целиком; события без user_id выбираются по одному.
+func (s *EventService) sample(event *models.Event, project *models.Project) bool {
+	event.SampleRate = 1
+
+	settings, err := project.Sampling()
+	if err != nil || len(settings) == 0 {
+		return true
+	}
+
+	rate := settings.Rate(event.EventType)
+	event.SampleRate = rate
+
+	key := event.UserID
+	if key == "" {
+		key = event.ID
+	}
+	return SampleKey(key, rate)
+}
@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/yourusername/event-analytics-service/internal/models"
+	"github.com/yourusername/event-analytics-service/internal/repository"
+)
+
+const schemaCacheTTL = 5 * time.Minute
+
+type SchemaService struct {
+	schemaRepo repository.SchemaRepository
+	cacheRepo  *repository.RedisRepository
+}
+
+func NewSchemaService(schemaRepo repository.SchemaRepository, cacheRepo *repository.RedisRepository) *SchemaService {
+	return &SchemaService{
+		schemaRepo: schemaRepo,
+		cacheRepo:  cacheRepo,
+	}
+}
+
+func (s *SchemaService) SaveSchema(ctx context.Context, schema *models.EventSchema) error {
+	if err := checkSchema(schema); err != nil {
+		return err
+	}
+
+	if err := s.schemaRepo.UpsertSchema(ctx, schema); err != nil {
+		return err
+	}
+
+	s.cacheRepo.Client.Del(ctx, schemaCacheKey(schema.ProjectID, schema.EventName))
+	return nil
+}
+
+func (s *SchemaService) ListSchemas(ctx context.Context, projectID string) ([]*models.EventSchema, error) {
+	return s.schemaRepo.ListSchemas(ctx, projectID)
+}
+
+func (s *SchemaService) GetSchema(ctx context.Context, projectID, eventName string) (*models.EventSchema, error) {
+	return s.schemaRepo.GetSchema(ctx, projectID, eventName)
+}
+
+func (s *SchemaService) DeleteSchema(ctx context.Context, projectID, eventName string) error {
+	if err := s.schemaRepo.DeleteSchema(ctx, projectID, eventName); err != nil {
+		return err
+	}
+
+	s.cacheRepo.Client.Del(ctx, schemaCacheKey(projectID, eventName))
+	return nil
+}
+
+// Validate проверяет Metadata события по схеме проекта для его event_name. Возвращает режим схемы
+// и список ошибок; если схема не задана, режим равен SchemaModeOff.
+func (s *SchemaService) Validate(ctx context.Context, event *models.Event) (models.SchemaMode, []models.ValidationError, error) {
+	schema, err := s.cachedSchema(ctx, event.ProjectID, event.EventName)
+	if err != nil {
+		return models.SchemaModeOff, nil, err
+	}
+	if schema == nil || schema.Mode == models.SchemaModeOff {
+		return models.SchemaModeOff, nil, nil
+	}
+
+	return schema.Mode, ValidateMetadata(schema, event.Metadata), nil
+}
+
+// cachedSchema возвращает схему из Redis или из БД. Отсутствие схемы тоже
+// кэшируется, чтобы не ходить в Postgres на каждое событие.
+func (s *SchemaService) cachedSchema(ctx context.Context, projectID, eventName string) (*models.EventSchema, error) {
+	cacheKey := schemaCacheKey(projectID, eventName)
+	cached, err := s.cacheRepo.Client.Get(ctx, cacheKey).Bytes()
+	if err == nil {
+		var schema *models.EventSchema
+		if json.Unmarshal(cached, &schema) == nil {
+			return schema, nil
+		}
+	}
+
+	schema, err := s.schemaRepo.GetSchema(ctx, projectID, eventName)
+	if err != nil && !errors.Is(err, models.ErrSchemaNotFound) {
+		return nil, err
+	}
+
+	if data, err := json.Marshal(schema); err == nil {
+		s.cacheRepo.Client.Set(ctx, cacheKey, data, schemaCacheTTL)
+	}
+
+	return schema, nil
+}
+
+// ValidateMetadata возвращает ошибки по каждому ключу Metadata, не соответствующему схеме
+func ValidateMetadata(schema *models.EventSchema, metadata map[string]interface{}) []models.ValidationError {
+	var errs []models.ValidationError
+
+	keys := make([]string, 0, len(schema.Fields))
+	for key := range schema.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		field := schema.Fields[key]
+		value, ok := metadata[key]
+		if !ok || value == nil {
+			if field.Required {
+				errs = append(errs, models.ValidationError{
+					Field:   "metadata." + key,
+					Message: "required field missing",
+				})
+			}
+			continue
+		}
+
+		if !matchesType(value, field.Type) {
+			errs = append(errs, models.ValidationError{
+				Field:   "metadata." + key,
+				Message: fmt.Sprintf("expected %s, got %s", field.Type, typeName(value)),
+			})
+		}
+	}
+
+	return errs
+}
+
+func checkSchema(schema *models.EventSchema) error {
+	if schema.ProjectID == "" || !validEventName(schema.EventName) {
+		return models.ErrInvalidSchema
+	}
+
+	switch schema.Mode {
+	case "":
+		schema.Mode = models.SchemaModeStrict
+	case models.SchemaModeStrict, models.SchemaModeWarn, models.SchemaModeOff:
+	default:
+		return fmt.Errorf("%w: unknown mode %q", models.ErrInvalidSchema, schema.Mode)
+	}
+
+	for key, field := range schema.Fields {
+		switch field.Type {
+		case models.FieldTypeString, models.FieldTypeNumber, models.FieldTypeInteger,
+			models.FieldTypeBoolean, models.FieldTypeObject, models.FieldTypeArray:
+		default:
+			return fmt.Errorf("%w: unknown type %q for field %q", models.ErrInvalidSchema, field.Type, key)
+		}
+	}
+
+	return nil
+}
+
+func matchesType(value interface{}, fieldType models.FieldType) bool {
+	switch fieldType {
+	case models.FieldTypeString:
+		_, ok := value.(string)
+		return ok
+	case models.FieldTypeBoolean:
+		_, ok := value.(bool)
+		return ok
+	case models.FieldTypeNumber:
+		_, ok := toFloat(value)
+		return ok
+	case models.FieldTypeInteger:
+		f, ok := toFloat(value)
+		return ok && f == math.Trunc(f)
+	case models.FieldTypeObject:
+		_, ok := value.(map[string]interface{})
+		return ok
+	case models.FieldTypeArray:
+		_, ok := value.([]interface{})
+		return ok
+	}
+	return false
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case string:
+		return string(models.FieldTypeString)
+	case bool:
+		return string(models.FieldTypeBoolean)
+	case map[string]interface{}:
+		return string(models.FieldTypeObject)
+	case []interface{}:
+		return string(models.FieldTypeArray)
+	}
+	if _, ok := toFloat(value); ok {
+		return string(models.FieldTypeNumber)
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func schemaCacheKey(projectID, eventName string) string {
+	return "schema:" + projectID + ":" + eventName
+}
@@ -25,8 +25,21 @@ func NewStatsService(repo repository.EventRepository, cache *repository.RedisRep
 }
 
 func (s *StatsService) GetEventStatistics(ctx context.Context, req models.StatsRequest) ([]models.EventStats, error) {
+    return s.eventStatistics(ctx, "", req)
+}
+
+// GetProjectEventStatistics - статистика событий одного проекта (для доступа по API ключу)
+func (s *StatsService) GetProjectEventStatistics(ctx context.Context, projectID string, req models.StatsRequest) ([]models.EventStats, error) {
+    return s.eventStatistics(ctx, projectID, req)
+}
+
+// eventStatistics возвращает статистику из кэша или ClickHouse; пустой projectID - все проекты
+func (s *StatsService) eventStatistics(ctx context.Context, projectID string, req models.StatsRequest) ([]models.EventStats, error) {
     // Пробуем получить из кэша
-    cacheKey := fmt.Sprintf("stats:%s:%s:%s", req.EventType, req.StartDate, req.EndDate)
+    cacheKey := fmt.Sprintf("stats:%s:%s:%s:%s:%s:%s:%s:%t", req.EventType, req.EventName, req.StartDate, req.EndDate, req.GroupBy, req.Breakdown, req.Country, req.IncludeBots)
+    if projectID != "" {
+        cacheKey = "project:" + projectID + ":" + cacheKey
+    }
     cached, err := s.cache.GetCachedStats(ctx, cacheKey)
     if err == nil && cached != nil {
         s.metrics.IncrementCacheHit("stats")
@@ -35,7 +48,7 @@ func (s *StatsService) GetEventStatistics(ctx context.Context, req models.StatsR
     s.metrics.IncrementCacheMiss("stats")
 
     // Получаем из репозитория
-    stats, err := s.repo.GetStats(ctx, req)
+    stats, err := s.repo.GetStatsByProject(ctx, projectID, req)
     if err != nil {
         return nil, err
     }
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/yourusername/event-analytics-service/internal/metrics"
+	"github.com/yourusername/event-analytics-service/internal/models"
+	"github.com/yourusername/event-analytics-service/internal/repository"
+	"github.com/yourusername/event-analytics-service/internal/transform"
+)
+
+// Сколько консьюмер использует скомпилированные правила проекта, прежде чем
+// перечитать их из Postgres. Изменения правил вступают в силу в пределах этого времени.
+const transformCacheTTL = 30 * time.Second
+
+type cachedPipeline struct {
+	pipeline *transform.Pipeline
+	loadedAt time.Time
+}
+
+type TransformService struct {
+	transformRepo repository.TransformRepository
+	metrics       *metrics.Metrics
+
+	mu        sync.Mutex
+	pipelines map[string]cachedPipeline
+}
+
+func NewTransformService(transformRepo repository.TransformRepository, metrics *metrics.Metrics) *TransformService {
+	return &TransformService{
+		transformRepo: transformRepo,
+		metrics:       metrics,
+		pipelines:     make(map[string]cachedPipeline),
+	}
+}
+
+func (s *TransformService) GetTransforms(ctx context.Context, projectID string) (*models.ProjectTransforms, error) {
+	return s.transformRepo.GetTransforms(ctx, projectID)
+}
+
+// SaveTransforms проверяет правила и заменяет ими текущий набор правил проекта
+func (s *TransformService) SaveTransforms(ctx context.Context, transforms *models.ProjectTransforms) error {
+	if _, err := transform.Compile(transforms.Rules); err != nil {
+		return err
+	}
+
+	if err := s.transformRepo.SaveTransforms(ctx, transforms); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	delete(s.pipelines, transforms.ProjectID)
+	s.mu.Unlock()
+	return nil
+}
+
+// DryRun применяет правила к копиям событий и возвращает результат по каждому
+// событию. Если rules == nil, используются сохраненные правила проекта.
+func (s *TransformService) DryRun(ctx context.Context, projectID string, rules []models.TransformRule, events []*models.Event) ([]models.TransformResult, error) {
+	if rules == nil {
+		transforms, err := s.transformRepo.GetTransforms(ctx, projectID)
+		if err != nil {
+			return nil, err
+		}
+		rules = transforms.Rules
+	}
+
+	pipeline, err := transform.Compile(rules)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]models.TransformResult, len(events))
+	for i, event := range events {
+		copied := copyEvent(event)
+		copied.ProjectID = projectID
+
+		results[i] = models.TransformResult{Index: i}
+		if pipeline.Apply(copied) {
+			results[i].Event = copied
+		} else {
+			results[i].Dropped = true
+		}
+	}
+
+	return results, nil
+}
+
+// Apply применяет правила проектов к батчу консьюмера и возвращает события,
+// которые нужно сохранить. Если правила проекта не удалось загрузить,
+// используются ранее загруженные, а при их отсутствии события сохраняются
+// без изменений: потеря событий хуже, чем нетрансформированные данные.
+func (s *TransformService) Apply(ctx context.Context, events []*models.Event) []*models.Event {
+	kept := events[:0]
+	dropped := make(map[string]int)
+
+	for _, event := range events {
+		pipeline := s.pipeline(ctx, event.ProjectID)
+		if pipeline.Apply(event) {
+			kept = append(kept, event)
+			continue
+		}
+		dropped[event.ProjectID]++
+	}
+
+	for projectID, count := range dropped {
+		s.metrics.AddEventsTransformDropped(projectID, count)
+	}
+
+	return kept
+}
+
+func (s *TransformService) pipeline(ctx context.Context, projectID string) *transform.Pipeline {
+	s.mu.Lock()
+	cached, ok := s.pipelines[projectID]
+	s.mu.Unlock()
+	if ok && time.Since(cached.loadedAt) < transformCacheTTL {
+		return cached.pipeline
+	}
+
+	transforms, err := s.transformRepo.GetTransforms(ctx, projectID)
+	if err != nil {
+		log.Printf("Failed to load transforms for project %s: %v", projectID, err)
+		s.metrics.IncrementDBError("transform_lookup", "event_transforms")
+		return cached.pipeline
+	}
+
+	pipeline, err := transform.Compile(transforms.Rules)
+	if err != nil {
+		// Правила проверяются при сохранении, сюда попадают только правила,
+		// ставшие невалидными после изменения кода
+		log.Printf("Invalid transforms for project %s: %v", projectID, err)
+		pipeline = nil
+	}
+
+	s.mu.Lock()
+	s.pipelines[projectID] = cachedPipeline{pipeline: pipeline, loadedAt: time.Now()}
+	s.mu.Unlock()
+
+	return pipeline
+}
+
+// copyEvent копирует событие вместе с Metadata, чтобы правила не меняли исходник
+func copyEvent(event *models.Event) *models.Event {
+	copied := *event
+	if event.Metadata != nil {
+		copied.Metadata = make(map[string]interface{}, len(event.Metadata))
+		for key, value := range event.Metadata {
+			copied.Metadata[key] = value
+		}
+	}
+	return &copied
+}
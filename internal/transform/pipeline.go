@@ -0,0 +1,253 @@
+// Package transform применяет к событиям правила проекта: переименование
+// типов и названий событий, ключей metadata, вычисление новых ключей,
+// перезапись URL и удаление событий по фильтру.
+package transform
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/yourusername/event-analytics-service/internal/models"
+)
+
+// Максимальное количество правил проекта
+const MaxRules = 100
+
+type filter struct {
+	eventType models.EventType
+	eventName string
+	url       *regexp.Regexp
+	metadata  map[string]string
+}
+
+type rule struct {
+	models.TransformRule
+	filter  *filter
+	pattern *regexp.Regexp
+}
+
+// Pipeline - скомпилированный набор правил; безопасен для одновременного использования
+type Pipeline struct {
+	rules []rule
+}
+
+// Compile проверяет правила и компилирует регулярные выражения
+func Compile(rules []models.TransformRule) (*Pipeline, error) {
+	if len(rules) > MaxRules {
+		return nil, fmt.Errorf("%w: more than %d rules", models.ErrInvalidTransform, MaxRules)
+	}
+
+	p := &Pipeline{rules: make([]rule, 0, len(rules))}
+	for i, r := range rules {
+		compiled, err := compileRule(r)
+		if err != nil {
+			return nil, fmt.Errorf("%w: rule %d: %v", models.ErrInvalidTransform, i, err)
+		}
+		p.rules = append(p.rules, compiled)
+	}
+
+	return p, nil
+}
+
+// Empty сообщает, что правил нет и события не меняются
+func (p *Pipeline) Empty() bool {
+	return p == nil || len(p.rules) == 0
+}
+
+// Apply изменяет событие на месте и возвращает false, если событие нужно отбросить
+func (p *Pipeline) Apply(event *models.Event) bool {
+	if p.Empty() {
+		return true
+	}
+
+	for _, r := range p.rules {
+		if !r.filter.matches(event) {
+			continue
+		}
+
+		switch r.Action {
+		case models.TransformDropEvent:
+			return false
+		case models.TransformRenameEventType:
+			if string(event.EventType) == r.From {
+				// Название по умолчанию совпадает с типом и переименовывается вместе с ним
+				if event.EventName == r.From {
+					event.EventName = r.To
+				}
+				event.EventType = models.EventType(r.To)
+			}
+		case models.TransformRenameEventName:
+			if event.EventName == r.From {
+				event.EventName = r.To
+			}
+		case models.TransformRenameKey:
+			if value, ok := event.Metadata[r.From]; ok {
+				delete(event.Metadata, r.From)
+				event.Metadata[r.To] = value
+			}
+		case models.TransformDropKey:
+			delete(event.Metadata, r.Key)
+		case models.TransformDeriveKey:
+			if value, ok := r.derive(event); ok {
+				if event.Metadata == nil {
+					event.Metadata = make(map[string]interface{})
+				}
+				event.Metadata[r.Key] = value
+			}
+		case models.TransformRewriteURL:
+			event.PageURL = r.pattern.ReplaceAllString(event.PageURL, r.Replacement)
+		}
+	}
+
+	return true
+}
+
+// derive вычисляет значение нового ключа: константа value, значение source
+// или результат замены pattern в source. Если pattern не совпал, ключ не
+// создается.
+func (r *rule) derive(event *models.Event) (interface{}, bool) {
+	if r.Source == "" {
+		return r.Value, true
+	}
+
+	source, ok := sourceValue(event, r.Source)
+	if !ok {
+		return nil, false
+	}
+	if r.pattern == nil {
+		return source, true
+	}
+
+	match := r.pattern.FindStringSubmatchIndex(source)
+	if match == nil {
+		return nil, false
+	}
+	return string(r.pattern.ExpandString(nil, r.Replacement, source, match)), true
+}
+
+func (f *filter) matches(event *models.Event) bool {
+	if f == nil {
+		return true
+	}
+	if f.eventType != "" && event.EventType != f.eventType {
+		return false
+	}
+	if f.eventName != "" && event.EventName != f.eventName {
+		return false
+	}
+	if f.url != nil && !f.url.MatchString(event.PageURL) {
+		return false
+	}
+	for key, expected := range f.metadata {
+		value, ok := event.Metadata[key]
+		if !ok || fmt.Sprint(value) != expected {
+			return false
+		}
+	}
+	return true
+}
+
+func compileRule(r models.TransformRule) (rule, error) {
+	compiled := rule{TransformRule: r}
+
+	switch r.Action {
+	case models.TransformRenameEventType:
+		if !validEventType(r.From) || !validEventType(r.To) {
+			return compiled, fmt.Errorf("from and to must be event types")
+		}
+	case models.TransformRenameEventName, models.TransformRenameKey:
+		if r.From == "" || r.To == "" {
+			return compiled, fmt.Errorf("from and to required")
+		}
+	case models.TransformDropKey:
+		if r.Key == "" {
+			return compiled, fmt.Errorf("key required")
+		}
+	case models.TransformDeriveKey:
+		if r.Key == "" {
+			return compiled, fmt.Errorf("key required")
+		}
+		if r.Source == "" && r.Value == nil {
+			return compiled, fmt.Errorf("source or value required")
+		}
+		if r.Source != "" && !validSource(r.Source) {
+			return compiled, fmt.Errorf("unknown source %q", r.Source)
+		}
+		if r.Pattern != "" && r.Source == "" {
+			return compiled, fmt.Errorf("pattern requires source")
+		}
+	case models.TransformRewriteURL:
+		if r.Pattern == "" {
+			return compiled, fmt.Errorf("pattern required")
+		}
+	case models.TransformDropEvent:
+		// Без фильтра правило удалило бы все события проекта
+		if r.Filter == nil {
+			return compiled, fmt.Errorf("filter required")
+		}
+	default:
+		return compiled, fmt.Errorf("unknown action %q", r.Action)
+	}
+
+	if r.Pattern != "" {
+		pattern, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return compiled, err
+		}
+		compiled.pattern = pattern
+	}
+
+	if r.Filter != nil {
+		f := &filter{
+			eventType: r.Filter.EventType,
+			eventName: r.Filter.EventName,
+			metadata:  r.Filter.Metadata,
+		}
+		if r.Filter.URLPattern != "" {
+			url, err := regexp.Compile(r.Filter.URLPattern)
+			if err != nil {
+				return compiled, err
+			}
+			f.url = url
+		}
+		compiled.filter = f
+	}
+
+	return compiled, nil
+}
+
+func sourceValue(event *models.Event, source string) (string, bool) {
+	switch source {
+	case "page_url":
+		return event.PageURL, true
+	case "referrer":
+		return event.Referrer, true
+	case "event_name":
+		return event.EventName, true
+	case "user_id":
+		return event.UserID, true
+	}
+
+	value, ok := event.Metadata[strings.TrimPrefix(source, "metadata.")]
+	if !ok || value == nil {
+		return "", false
+	}
+	return fmt.Sprint(value), true
+}
+
+func validSource(source string) bool {
+	switch source {
+	case "page_url", "referrer", "event_name", "user_id":
+		return true
+	}
+	return strings.HasPrefix(source, "metadata.") && len(source) > len("metadata.")
+}
+
+func validEventType(value string) bool {
+	switch models.EventType(value) {
+	case models.PageView, models.ButtonClick, models.FormSubmit, models.Purchase, models.Custom:
+		return true
+	}
+	return false
+}
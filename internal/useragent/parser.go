@@ -0,0 +1,131 @@
+// Package useragent извлекает тип устройства, браузер и ОС из строки User-Agent.
+package useragent
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Типы устройств
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceOther   = "other"
+)
+
+// Info - результат разбора User-Agent
+type Info struct {
+	DeviceType string
+	Browser    string
+	OS         string
+}
+
+type rule struct {
+	name    string
+	tokens  []string // достаточно любого из токенов
+	words   []string // то же, но с учетом регистра и только целым словом
+	exclude []string // ни один из токенов не должен встречаться
+}
+
+// Порядок важен: Edge, Opera и другие браузеры на Chromium содержат "Chrome/",
+// а Chrome содержит "Safari/"
+var browserRules = []rule{
+	{name: "Edge", tokens: []string{"edg/", "edge/", "edga/", "edgios/"}},
+	{name: "Opera", tokens: []string{"opr/", "opera"}},
+	{name: "Samsung Internet", tokens: []string{"samsungbrowser/"}},
+	{name: "Yandex", tokens: []string{"yabrowser/"}},
+	{name: "Firefox", tokens: []string{"firefox/", "fxios/"}},
+	{name: "Android WebView", tokens: []string{"; wv)"}},
+	{name: "Chrome", tokens: []string{"chrome/", "crios/"}},
+	{name: "Safari", tokens: []string{"safari/"}, exclude: []string{"android"}},
+	{name: "Internet Explorer", tokens: []string{"msie ", "trident/"}},
+}
+
+var osRules = []rule{
+	{name: "Windows Phone", tokens: []string{"windows phone"}},
+	{name: "Windows", tokens: []string{"windows nt", "windows"}},
+	{name: "iOS", tokens: []string{"iphone", "ipad", "ipod"}},
+	{name: "macOS", tokens: []string{"mac os x", "macintosh"}},
+	{name: "Android", tokens: []string{"android"}},
+	// "cros" без учета регистра встречается внутри "Microsoft"
+	{name: "Chrome OS", words: []string{"CrOS"}},
+	{name: "Linux", tokens: []string{"linux", "x11"}},
+}
+
+// Parse разбирает строку User-Agent. Для пустой или нераспознанной строки
+// возвращаются пустые Browser/OS и тип устройства DeviceOther.
+func Parse(ua string) Info {
+	s := strings.ToLower(ua)
+	if strings.TrimSpace(s) == "" {
+		return Info{DeviceType: DeviceOther}
+	}
+
+	info := Info{
+		Browser: match(ua, s, browserRules),
+		OS:      match(ua, s, osRules),
+	}
+	info.DeviceType = deviceType(s, info.OS)
+
+	return info
+}
+
+func deviceType(s, os string) string {
+	switch {
+	case containsAny(s, "ipad", "tablet", "kindle", "silk/", "playbook"),
+		os == "Android" && !strings.Contains(s, "mobile"):
+		return DeviceTablet
+	case containsAny(s, "mobi", "iphone", "ipod", "windows phone", "opera mini"):
+		return DeviceMobile
+	case os == "Windows" || os == "macOS" || os == "Linux" || os == "Chrome OS":
+		return DeviceDesktop
+	}
+	return DeviceOther
+}
+
+// match ищет токены правил в s (User-Agent в нижнем регистре), а слова - в
+// исходной строке ua
+func match(ua, s string, rules []rule) string {
+	for _, r := range rules {
+		if (containsAny(s, r.tokens...) || containsWord(ua, r.words...)) && !containsAny(s, r.exclude...) {
+			return r.name
+		}
+	}
+	return ""
+}
+
+func containsAny(s string, tokens ...string) bool {
+	for _, token := range tokens {
+		if strings.Contains(s, token) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsWord проверяет, встречается ли одно из слов в s, не будучи частью
+// более длинного слова
+func containsWord(s string, words ...string) bool {
+	for _, word := range words {
+		for offset := 0; ; {
+			i := strings.Index(s[offset:], word)
+			if i < 0 {
+				break
+			}
+			start, end := offset+i, offset+i+len(word)
+			if !isWordChar(s, start-1) && !isWordChar(s, end) {
+				return true
+			}
+			offset = start + 1
+		}
+	}
+	return false
+}
+
+func isWordChar(s string, i int) bool {
+	if i < 0 || i >= len(s) {
+		return false
+	}
+	r := rune(s[i])
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
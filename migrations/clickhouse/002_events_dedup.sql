@@ -0,0 +1,55 @@
+-- Перевод events на ReplacingMergeTree для дедупликации по ID события.
+-- Ключ - (project_id, id) без timestamp: повтор того же события (ретрай
+-- клиента без timestamp, скорректированное время, повторное чтение из Kafka
+-- консьюмером) схлопывается при слияниях, а запросы с FINAL не видят дублей.
+-- Партиции по месяцу timestamp нужны для TTL и отсечения по времени; повторы,
+-- попавшие в разные месяцы, не схлопываются.
+--
+-- Перед применением остановите консьюмер: таблица копируется целиком, и
+-- события, записанные во время копирования, были бы потеряны при обмене.
+-- Пока консьюмер стоит, события ждут в Kafka. Миграция проверяет, что записи
+-- не идут, и прерывается до обмена таблиц, если это не так.
+USE analytics;
+
+-- Повторный запуск на уже переведенной таблице прерывается здесь
+SELECT throwIf(engine = 'ReplacingMergeTree', 'events is already a ReplacingMergeTree, migration 002 was applied')
+FROM system.tables
+WHERE database = 'analytics' AND name = 'events';
+
+SELECT throwIf(count() > 0, 'events were inserted during the last minute, stop the consumer before migration 002')
+FROM events
+WHERE processed_at > now() - INTERVAL 1 MINUTE;
+
+-- Остаток прерванного запуска
+DROP TABLE IF EXISTS events_dedup;
+
+CREATE TABLE events_dedup AS events
+ENGINE = ReplacingMergeTree(processed_at)
+PARTITION BY toYYYYMM(timestamp)
+ORDER BY (project_id, id)
+TTL timestamp + INTERVAL 90 DAY DELETE
+SETTINGS
+    index_granularity = 8192,
+    -- идентичный повторно вставленный блок отбрасывается целиком
+    non_replicated_deduplication_window = 1000;
+
+INSERT INTO events_dedup SELECT * FROM events;
+
+SELECT throwIf(
+    (SELECT count() FROM events) != (SELECT count() FROM events_dedup),
+    'events were inserted during the copy, stop the consumer and rerun migration 002'
+);
+
+-- Материализованные представления ссылаются на таблицу по имени и после
+-- обмена продолжают читать вставки в analytics.events
+EXCHANGE TABLES events AND events_dedup;
+
+DROP TABLE events_dedup;
+
+-- Представления со счетчиками учитывают каждую вставку, в том числе повторы,
+-- которые events схлопывает; сервис их не читает, статистика считается по
+-- events FINAL. daily_unique_users (uniqState) к повторам нечувствительна.
+DROP TABLE IF EXISTS daily_project_stats;
+DROP TABLE IF EXISTS hourly_project_stats;
+DROP TABLE IF EXISTS top_pages_mv;
+DROP TABLE IF EXISTS top_pages;
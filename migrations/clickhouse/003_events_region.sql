@@ -0,0 +1,4 @@
+-- Регион (ISO 3166-2) рядом со страной; оба поля заполняет консьюмер по базе GeoIP
+USE analytics;
+
+ALTER TABLE events ADD COLUMN IF NOT EXISTS region LowCardinality(String) AFTER country_code;
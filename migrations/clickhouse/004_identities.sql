@@ -0,0 +1,24 @@
+-- Атрибуты пользователей (identify) и связи идентификаторов (alias)
+USE analytics;
+
+-- Каждый вызов identify сохраняется отдельной строкой, актуальное значение
+-- атрибута - последнее по timestamp
+CREATE TABLE IF NOT EXISTS user_traits (
+    project_id String,
+    user_id String,
+    anonymous_id String,
+    traits String,
+    timestamp DateTime64(3),
+    received_at DateTime64(3) DEFAULT now64()
+) ENGINE = MergeTree()
+PARTITION BY toYYYYMM(timestamp)
+ORDER BY (project_id, user_id, timestamp);
+
+-- Для каждого previous_id хранится последний user_id
+CREATE TABLE IF NOT EXISTS user_aliases (
+    project_id String,
+    previous_id String,
+    user_id String,
+    timestamp DateTime64(3)
+) ENGINE = ReplacingMergeTree(timestamp)
+ORDER BY (project_id, previous_id);
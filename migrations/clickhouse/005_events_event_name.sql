@@ -0,0 +1,15 @@
+-- Произвольные названия событий проекта. Для уже записанных строк название
+-- вычисляется из event_type, поэтому старые данные доступны по event_name
+-- без переписывания.
+USE analytics;
+
+ALTER TABLE events
+    ADD COLUMN IF NOT EXISTS event_name LowCardinality(String) DEFAULT toString(event_type) AFTER event_type;
+
+ALTER TABLE events
+    ADD INDEX IF NOT EXISTS idx_event_name event_name TYPE set(1000) GRANULARITY 4;
+
+-- Сохраняем вычисленные значения в старых партах, чтобы фильтр по
+-- event_name использовал индекс
+ALTER TABLE events MATERIALIZE COLUMN event_name;
+ALTER TABLE events MATERIALIZE INDEX idx_event_name;
@@ -0,0 +1,13 @@
+-- Атрибуция трафика: UTM метки и идентификаторы кликов из page_url.
+-- Колонка referrer уже есть в events и теперь заполняется при приеме события.
+USE analytics;
+
+ALTER TABLE events
+    ADD COLUMN IF NOT EXISTS utm_source LowCardinality(String) AFTER referrer,
+    ADD COLUMN IF NOT EXISTS utm_medium LowCardinality(String) AFTER utm_source,
+    ADD COLUMN IF NOT EXISTS utm_campaign LowCardinality(String) AFTER utm_medium,
+    ADD COLUMN IF NOT EXISTS utm_term String AFTER utm_campaign,
+    ADD COLUMN IF NOT EXISTS utm_content String AFTER utm_term,
+    ADD COLUMN IF NOT EXISTS gclid String AFTER utm_content,
+    ADD COLUMN IF NOT EXISTS fbclid String AFTER gclid,
+    ADD COLUMN IF NOT EXISTS msclkid String AFTER fbclid;
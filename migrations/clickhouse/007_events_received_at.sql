@@ -0,0 +1,6 @@
+-- Время приема события сервером. timestamp хранит время события,
+-- скорректированное на расхождение часов клиента (sent_at).
+USE analytics;
+
+ALTER TABLE events
+    ADD COLUMN IF NOT EXISTS received_at DateTime64(3) DEFAULT timestamp AFTER timestamp;
@@ -0,0 +1,6 @@
+-- Признак события бота, краулера или сервиса мониторинга. Статистика
+-- по умолчанию исключает такие события.
+USE analytics;
+
+ALTER TABLE events
+    ADD COLUMN IF NOT EXISTS is_bot Bool DEFAULT false AFTER region;
@@ -0,0 +1,6 @@
+-- Анонимный идентификатор устройства. Запросы по пользователям объединяют
+-- анонимные и постоянные идентификаторы через user_aliases.
+USE analytics;
+
+ALTER TABLE events
+    ADD COLUMN IF NOT EXISTS anonymous_id String DEFAULT '' AFTER user_id;
@@ -0,0 +1,6 @@
+-- Доля выборки, с которой сохранено событие. Агрегаты учитывают каждое
+-- событие с весом 1 / sample_rate.
+USE analytics;
+
+ALTER TABLE events
+    ADD COLUMN IF NOT EXISTS sample_rate Float64 DEFAULT 1 AFTER is_bot;
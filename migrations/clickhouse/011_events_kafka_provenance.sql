@@ -0,0 +1,10 @@
+-- Происхождение события в Kafka и время прохождения этапов приема:
+-- received_at (API) -> produced_at (запись в Kafka) -> consumed_at (чтение
+-- консьюмером) -> processed_at (запись в ClickHouse). У событий, сохраненных
+-- до миграции, kafka_topic пуст, а produced_at и consumed_at равны received_at.
+USE analytics;
+
+ALTER TABLE events
+    ADD COLUMN IF NOT EXISTS produced_at DateTime64(3) DEFAULT received_at AFTER received_at,
+    ADD COLUMN IF NOT EXISTS consumed_at DateTime64(3) DEFAULT received_at AFTER produced_at,
+    ADD COLUMN IF NOT EXISTS kafka_topic LowCardinality(String) DEFAULT '' AFTER processed_at;
@@ -0,0 +1,54 @@
+#!/bin/sh
+# Применяет миграции, которые еще не записаны в таблицу schema_migrations
+# базы. Каждая миграция выполняется один раз, в порядке имен файлов.
+#
+#   migrations/migrate.sh clickhouse|postgres [--baseline]
+#
+# --baseline только отмечает все миграции примененными, не выполняя их: для
+# баз, развернутых до появления schema_migrations.
+set -eu
+
+target=${1:-}
+mode=${2:-}
+dir=$(dirname "$0")/$target
+
+case $target in
+clickhouse)
+	query() { docker exec -i analytics-clickhouse clickhouse-client --query "$1"; }
+	apply() { docker exec -i analytics-clickhouse clickhouse-client --multiquery < "$1"; }
+
+	query "CREATE DATABASE IF NOT EXISTS analytics"
+	query "CREATE TABLE IF NOT EXISTS analytics.schema_migrations (
+		version String,
+		applied_at DateTime DEFAULT now()
+	) ENGINE = MergeTree ORDER BY version"
+	table=analytics.schema_migrations
+	;;
+postgres)
+	query() { docker exec -i analytics-postgres psql -U admin -d analytics -qtA -v ON_ERROR_STOP=1 -c "$1"; }
+	apply() { docker exec -i analytics-postgres psql -U admin -d analytics -q -v ON_ERROR_STOP=1 < "$1"; }
+
+	query "CREATE TABLE IF NOT EXISTS schema_migrations (
+		version VARCHAR(255) PRIMARY KEY,
+		applied_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+	)"
+	table=schema_migrations
+	;;
+*)
+	echo "usage: $0 clickhouse|postgres [--baseline]" >&2
+	exit 2
+	;;
+esac
+
+for file in "$dir"/*.sql; do
+	version=$(basename "$file" .sql)
+	if [ "$(query "SELECT count(*) FROM $table WHERE version = '$version'")" != "0" ]; then
+		continue
+	fi
+
+	if [ "$mode" != "--baseline" ]; then
+		echo "Applying $target migration $version"
+		apply "$file"
+	fi
+	query "INSERT INTO $table (version) VALUES ('$version')"
+done
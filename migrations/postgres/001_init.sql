@@ -1,8 +1,5 @@
--- Создаем базу данных
-CREATE DATABASE analytics;
-
--- Подключаемся к базе
-\c analytics;
+-- База analytics создается контейнером (POSTGRES_DB), миграции применяются
+-- к ней через make migrate-postgres
 
 -- Расширение для UUID
 CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
@@ -0,0 +1,18 @@
+-- Реестр схем событий: описание ключей Metadata для каждого типа события проекта
+CREATE TABLE IF NOT EXISTS event_schemas (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    event_type VARCHAR(100) NOT NULL,
+    mode VARCHAR(10) NOT NULL DEFAULT 'strict' CHECK (mode IN ('strict', 'warn', 'off')),
+    fields JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE(project_id, event_type)
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_schemas_project_id ON event_schemas(project_id);
+
+CREATE TRIGGER update_event_schemas_updated_at BEFORE UPDATE ON event_schemas
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+GRANT SELECT, INSERT, UPDATE, DELETE ON event_schemas TO analytics_app;
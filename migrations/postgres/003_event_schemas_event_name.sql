@@ -0,0 +1,14 @@
+-- Схемы привязываются к названию события (event_name), а не к фиксированному типу.
+-- Существующие схемы продолжают работать: для событий без event_name
+-- название совпадает с типом.
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_name = 'event_schemas' AND column_name = 'event_type'
+    ) THEN
+        ALTER TABLE event_schemas RENAME COLUMN event_type TO event_name;
+    END IF;
+END $$;
+
+ALTER TABLE event_schemas ALTER COLUMN event_name TYPE VARCHAR(128);
@@ -0,0 +1,8 @@
+-- Правила трансформации событий проекта: применяются консьюмером перед записью в ClickHouse
+CREATE TABLE IF NOT EXISTS event_transforms (
+    project_id UUID PRIMARY KEY REFERENCES projects(id) ON DELETE CASCADE,
+    rules JSONB NOT NULL DEFAULT '[]',
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+GRANT SELECT, INSERT, UPDATE, DELETE ON event_transforms TO analytics_app;
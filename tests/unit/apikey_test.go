@@ -0,0 +1,58 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yourusername/event-analytics-service/internal/handler"
+	"github.com/yourusername/event-analytics-service/internal/middleware"
+	"github.com/yourusername/event-analytics-service/internal/models"
+	"github.com/yourusername/event-analytics-service/internal/service"
+)
+
+func TestValidateAPIKeyStampsProject(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	project := &models.Project{ID: "project-1", Active: true}
+	h := newIngestHarness(t, project, models.TimestampWindow{})
+	h.projects.On("ValidateAPIKey", mock.Anything, "key-1").Return(project, nil)
+	h.projects.On("ValidateAPIKey", mock.Anything, "revoked").Return(nil, models.ErrInvalidAPIKey)
+	h.projects.On("ValidateAPIKey", mock.Anything, "disabled").Return(&models.Project{ID: "project-2"}, nil)
+
+	auth := middleware.NewAuthMiddleware("secret", time.Hour, service.NewProjectService(h.projects, nil, h.cache))
+	router := gin.New()
+	router.POST("/events/track", auth.ValidateAPIKey(), handler.NewEventHandler(h.service).TrackEvent)
+
+	track := func(setKey func(*http.Request)) int {
+		req := httptest.NewRequest(http.MethodPost, "/events/track",
+			strings.NewReader(`{"project_id": "someone-else", "user_id": "u1", "event_type": "page_view"}`))
+		req.Header.Set("Content-Type", "application/json")
+		setKey(req)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Ключ передается заголовком, параметром или в имени пользователя Basic
+	assert.Equal(t, http.StatusAccepted, track(func(r *http.Request) { r.Header.Set("X-API-Key", "key-1") }))
+	assert.Equal(t, http.StatusAccepted, track(func(r *http.Request) { r.URL.RawQuery = "api_key=key-1" }))
+	assert.Equal(t, http.StatusAccepted, track(func(r *http.Request) { r.SetBasicAuth("key-1", "") }))
+
+	assert.Equal(t, http.StatusUnauthorized, track(func(r *http.Request) {}))
+	assert.Equal(t, http.StatusUnauthorized, track(func(r *http.Request) { r.Header.Set("X-API-Key", "revoked") }))
+	assert.Equal(t, http.StatusUnauthorized, track(func(r *http.Request) { r.Header.Set("X-API-Key", "disabled") }))
+
+	// project_id из тела запроса заменяется проектом ключа
+	published := h.published(t)
+	if assert.Len(t, published, 3) {
+		for _, event := range published {
+			assert.Equal(t, project.ID, event.ProjectID)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/event-analytics-service/internal/models"
+	"github.com/yourusername/event-analytics-service/internal/service"
+)
+
+func TestExtractAttribution(t *testing.T) {
+	event := &models.Event{
+		PageURL: "https://shop.example.com/sale?utm_source=Newsletter&utm_medium=Email&utm_campaign=spring&gclid=abc123#top",
+	}
+	service.ExtractAttribution(event)
+
+	assert.Equal(t, "newsletter", event.UTMSource)
+	assert.Equal(t, "email", event.UTMMedium)
+	assert.Equal(t, "spring", event.UTMCampaign)
+	assert.Equal(t, "abc123", event.GCLID)
+	assert.Empty(t, event.FBCLID)
+
+	// Явно переданные значения не перезаписываются, относительный URL тоже разбирается
+	event = &models.Event{PageURL: "/landing?utm_source=google&fbclid=xyz", UTMSource: "partner"}
+	service.ExtractAttribution(event)
+	assert.Equal(t, "partner", event.UTMSource)
+	assert.Equal(t, "xyz", event.FBCLID)
+}
+
+func TestIsExternalReferrer(t *testing.T) {
+	assert.True(t, service.IsExternalReferrer("https://www.google.com/", "https://shop.example.com/"))
+	assert.False(t, service.IsExternalReferrer("https://shop.example.com/cart", "https://shop.example.com/checkout"))
+	// Без абсолютного page_url сравнить нельзя
+	assert.False(t, service.IsExternalReferrer("https://www.google.com/", "/checkout"))
+	assert.False(t, service.IsExternalReferrer("", "https://shop.example.com/"))
+}
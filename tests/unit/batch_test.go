@@ -0,0 +1,119 @@
+package unit
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/event-analytics-service/internal/handler"
+	"github.com/yourusername/event-analytics-service/internal/models"
+)
+
+type batchResponse struct {
+	Accepted   int                       `json:"accepted"`
+	Duplicates int                       `json:"duplicates"`
+	Rejected   int                       `json:"rejected"`
+	Results    []models.BatchEventResult `json:"results"`
+}
+
+func newBatchRouter(h *ingestHarness, projectID string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("project_id", projectID)
+	})
+	router.POST("/events/batch", handler.NewEventHandler(h.service).TrackBatch)
+	return router
+}
+
+func postBatch(router *gin.Engine, contentType, body string) (*httptest.ResponseRecorder, batchResponse) {
+	req := httptest.NewRequest(http.MethodPost, "/events/batch", strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var response batchResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return w, response
+}
+
+func TestTrackBatch(t *testing.T) {
+	project := &models.Project{ID: "project-1", Active: true}
+	h := newIngestHarness(t, project, models.TimestampWindow{})
+	router := newBatchRouter(h, project.ID)
+
+	// Статус возвращается по каждому событию в порядке запроса
+	w, response := postBatch(router, "application/json", `[
+		{"user_id": "u1", "event_type": "page_view", "page_url": "/a"},
+		"not an event",
+		{"user_id": "u2", "event_type": "unknown"},
+		{"user_id": "u3", "event_name": "Signed Up"}
+	]`)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, 2, response.Accepted)
+	assert.Equal(t, 2, response.Rejected)
+	if assert.Len(t, response.Results, 4) {
+		for i, result := range response.Results {
+			assert.Equal(t, i, result.Index)
+		}
+		assert.Equal(t, models.BatchStatusAccepted, response.Results[0].Status)
+		assert.NotEmpty(t, response.Results[0].EventID)
+		assert.Equal(t, models.BatchStatusRejected, response.Results[1].Status)
+		assert.Equal(t, models.ErrInvalidEventData.Error(), response.Results[1].Error)
+		assert.Equal(t, models.BatchStatusRejected, response.Results[2].Status)
+		assert.Equal(t, models.ErrInvalidEventType.Error(), response.Results[2].Error)
+		assert.Equal(t, models.BatchStatusAccepted, response.Results[3].Status)
+	}
+
+	// NDJSON: пустые строки пропускаются
+	w, response = postBatch(router, "application/x-ndjson",
+		"{\"user_id\": \"u4\", \"event_type\": \"button_click\"}\n\n{\"user_id\": \"u5\", \"event_type\": \"purchase\"}\n")
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, 2, response.Accepted)
+
+	published := h.published(t)
+	if assert.Len(t, published, 4) {
+		assert.Equal(t, "u1", published[0].UserID)
+		assert.Equal(t, project.ID, published[0].ProjectID)
+		assert.Equal(t, "Signed Up", published[1].EventName)
+		assert.Equal(t, models.Custom, published[1].EventType)
+	}
+}
+
+func TestTrackBatchLimits(t *testing.T) {
+	project := &models.Project{ID: "project-1", Active: true}
+	h := newIngestHarness(t, project, models.TimestampWindow{})
+	router := newBatchRouter(h, project.ID)
+
+	w, _ := postBatch(router, "application/json", `[]`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w, _ = postBatch(router, "application/json", `{"user_id": "u1"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	events := make([]map[string]string, 1001)
+	for i := range events {
+		events[i] = map[string]string{"user_id": "u", "event_type": "page_view"}
+	}
+	body, _ := json.Marshal(events)
+	w, _ = postBatch(router, "application/json", string(body))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Ни одно событие не принято - 400
+	w, response := postBatch(router, "application/json", `[{"event_type": "unknown"}]`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, 1, response.Rejected)
+
+	big := bytes.Repeat([]byte(" "), 10<<20+1)
+	w, _ = postBatch(router, "application/json", string(big))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	assert.Empty(t, h.published(t))
+}
@@ -0,0 +1,120 @@
+package unit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/event-analytics-service/internal/botdetect"
+	"github.com/yourusername/event-analytics-service/internal/models"
+	"github.com/yourusername/event-analytics-service/internal/service"
+)
+
+type fakeRateCounter struct {
+	counts map[string]int64
+}
+
+func (f *fakeRateCounter) IncrementUserRate(ctx context.Context, projectID, userID string, window time.Duration) (int64, error) {
+	f.counts[projectID+":"+userID]++
+	return f.counts[projectID+":"+userID], nil
+}
+
+func TestMatchUserAgent(t *testing.T) {
+	bots := []string{
+		"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+		"Mozilla/5.0 (compatible; bingbot/2.0; +http://www.bing.com/bingbot.htm)",
+		"facebookexternalhit/1.1 (+http://www.facebook.com/externalhit_uatext.php)",
+		"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) HeadlessChrome/120.0.0.0 Safari/537.36",
+		"Mozilla/5.0 (compatible; UptimeRobot/2.0; http://www.uptimerobot.com/)",
+	}
+	for _, ua := range bots {
+		assert.True(t, botdetect.MatchUserAgent(ua), ua)
+	}
+
+	humans := []string{
+		"",
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+		"Mozilla/5.0 (Linux; Android 10; CUBOT NOTE 7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/96.0 Mobile Safari/537.36",
+	}
+	for _, ua := range humans {
+		assert.False(t, botdetect.MatchUserAgent(ua), ua)
+	}
+}
+
+func TestDetectorClassify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "datacenters.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("# AWS\n3.0.0.0/9\n2600:1f00::/24\n192.0.2.10 # single host\n"), 0o644))
+
+	rate := &fakeRateCounter{counts: make(map[string]int64)}
+	detector, err := botdetect.NewDetector(path, rate, 2)
+	assert.NoError(t, err)
+
+	assert.True(t, detector.IsDatacenterIP("3.15.1.1"))
+	assert.True(t, detector.IsDatacenterIP("2600:1f00::1"))
+	assert.True(t, detector.IsDatacenterIP("192.0.2.10"))
+	assert.False(t, detector.IsDatacenterIP("192.0.2.11"))
+
+	bot, reason := detector.Classify(&models.Event{IPAddress: "3.15.1.1"})
+	assert.True(t, bot)
+	assert.Equal(t, botdetect.ReasonDatacenter, reason)
+
+	// Classify не трогает счетчик частоты
+	event := &models.Event{ProjectID: "p1", UserID: "u1", IPAddress: "203.0.113.5"}
+	bot, _ = detector.Classify(event)
+	assert.False(t, bot)
+	assert.Empty(t, rate.counts)
+
+	assert.False(t, detector.ExceedsRate(context.Background(), event))
+	assert.False(t, detector.ExceedsRate(context.Background(), event))
+	assert.True(t, detector.ExceedsRate(context.Background(), event))
+
+	_, err = botdetect.NewDetector(filepath.Join(t.TempDir(), "missing.txt"), nil, 0)
+	assert.Error(t, err)
+}
+
+func TestBotRateCountsAcceptedEventsOnly(t *testing.T) {
+	ctx := context.Background()
+	project := &models.Project{ID: "project-1", Active: true}
+	h := newIngestHarness(t, project, models.TimestampWindow{})
+
+	detector, err := botdetect.NewDetector("", h.cache, 2)
+	assert.NoError(t, err)
+	h.service = service.NewEventService(
+		new(MockEventRepository),
+		h.producer,
+		service.NewSchemaService(noSchemas{}, h.cache),
+		service.NewProjectService(h.projects, nil, h.cache),
+		h.quota,
+		h.cache,
+		nil,
+		detector,
+		testMetrics,
+		time.Hour,
+		"salt",
+		models.TimestampWindow{},
+	)
+
+	event := func(id string) *models.Event {
+		return &models.Event{ID: id, ProjectID: project.ID, UserID: "u1", EventType: models.PageView}
+	}
+
+	// Ретраи одного события не увеличивают частоту пользователя
+	assert.NoError(t, h.service.ProcessEvent(ctx, event("evt-1")))
+	assert.ErrorIs(t, h.service.ProcessEvent(ctx, event("evt-1")), models.ErrDuplicateEvent)
+	results, err := h.service.ProcessBatch(ctx, []*models.Event{event("evt-1"), event("evt-2")})
+	assert.NoError(t, err)
+	assert.Equal(t, models.BatchStatusDuplicate, results[0].Status)
+	assert.Equal(t, models.BatchStatusAccepted, results[1].Status)
+
+	// Третье принятое событие за минуту превышает порог
+	assert.NoError(t, h.service.ProcessEvent(ctx, event("evt-3")))
+
+	bots := make(map[string]bool)
+	for _, published := range h.published(t) {
+		bots[published.ID] = published.IsBot
+	}
+	assert.Equal(t, map[string]bool{"evt-1": false, "evt-2": false, "evt-3": true}, bots)
+}
@@ -0,0 +1,101 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/event-analytics-service/internal/handler"
+	"github.com/yourusername/event-analytics-service/internal/models"
+	"github.com/yourusername/event-analytics-service/internal/service"
+)
+
+func TestEventDeduplication(t *testing.T) {
+	project := &models.Project{ID: "project-1", Active: true}
+	h := newIngestHarness(t, project, models.TimestampWindow{})
+	ctx := context.Background()
+
+	newEvent := func(id string) *models.Event {
+		return &models.Event{ID: id, ProjectID: project.ID, UserID: "u1", EventType: models.PageView}
+	}
+
+	assert.NoError(t, h.service.ProcessEvent(ctx, newEvent("evt-1")))
+	assert.ErrorIs(t, h.service.ProcessEvent(ctx, newEvent("evt-1")), models.ErrDuplicateEvent)
+
+	// Повтор внутри батча и повтор уже принятого события
+	results, err := h.service.ProcessBatch(ctx, []*models.Event{newEvent("evt-2"), newEvent("evt-2"), newEvent("evt-1")})
+	assert.NoError(t, err)
+	assert.Equal(t, models.BatchStatusAccepted, results[0].Status)
+	assert.Equal(t, models.BatchStatusDuplicate, results[1].Status)
+	assert.Equal(t, models.BatchStatusDuplicate, results[2].Status)
+	assert.Equal(t, "evt-1", results[2].EventID)
+
+	// События без ID не дедуплицируются: сервер выдает каждому новый ID
+	assert.NoError(t, h.service.ProcessEvent(ctx, newEvent("")))
+	assert.NoError(t, h.service.ProcessEvent(ctx, newEvent("")))
+
+	published := h.published(t)
+	assert.Len(t, published, 4)
+}
+
+func TestFailedBatchForgetsOnlyClientIDs(t *testing.T) {
+	project := &models.Project{ID: "project-1", Active: true}
+	h := newIngestHarness(t, project, models.TimestampWindow{})
+
+	// Синхронная доставка в недоступную Kafka завершается ошибкой
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	ctx = service.WithDeliveryMode(ctx, models.DeliveryModeSync)
+
+	events := []*models.Event{
+		{ID: "evt-1", ProjectID: project.ID, UserID: "u1", EventType: models.PageView},
+		{ProjectID: project.ID, UserID: "u2", EventType: models.PageView},
+		{ProjectID: project.ID, UserID: "u3", EventType: models.PageView},
+	}
+	_, err := h.service.ProcessBatch(ctx, events)
+	assert.ErrorIs(t, err, models.ErrEventNotDelivered)
+
+	// Снимается только ключ события с ID клиента: серверным ID ключ не ставился
+	assert.Equal(t, 1, h.redis.Calls("del"))
+	_, seen := h.redis.Get("dedup:" + project.ID + ":evt-1")
+	assert.False(t, seen)
+}
+
+func TestTrackEventDuplicateResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	project := &models.Project{ID: "project-1", Active: true}
+	h := newIngestHarness(t, project, models.TimestampWindow{})
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("project_id", project.ID) })
+	router.POST("/events/track", handler.NewEventHandler(h.service).TrackEvent)
+
+	track := func() (int, map[string]interface{}) {
+		req := httptest.NewRequest(http.MethodPost, "/events/track",
+			strings.NewReader(`{"id": "evt-1", "user_id": "u1", "event_type": "page_view"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return w.Code, response
+	}
+
+	code, response := track()
+	assert.Equal(t, http.StatusAccepted, code)
+	assert.Equal(t, "evt-1", response["event_id"])
+
+	// Повтор отвечает успехом, чтобы клиент прекратил ретраи
+	code, response = track()
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, true, response["duplicate"])
+	assert.Equal(t, "evt-1", response["event_id"])
+}
@@ -0,0 +1,29 @@
+package unit
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/event-analytics-service/internal/models"
+)
+
+func TestProjectDeliveryMode(t *testing.T) {
+	project := &models.Project{Settings: map[string]interface{}{}}
+	mode, err := project.DeliveryMode()
+	assert.NoError(t, err)
+	assert.Equal(t, models.DeliveryMode(""), mode)
+
+	project.Settings["delivery_mode"] = "sync"
+	mode, err = project.DeliveryMode()
+	assert.NoError(t, err)
+	assert.Equal(t, models.DeliveryModeSync, mode)
+
+	project.Settings["delivery_mode"] = "eventually"
+	_, err = project.DeliveryMode()
+	assert.True(t, errors.Is(err, models.ErrInvalidDeliveryMode))
+
+	project.Settings["delivery_mode"] = true
+	_, err = project.DeliveryMode()
+	assert.True(t, errors.Is(err, models.ErrInvalidDeliveryMode))
+}
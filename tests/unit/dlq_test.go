@@ -0,0 +1,72 @@
+package unit
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/event-analytics-service/internal/dlq"
+	"github.com/yourusername/event-analytics-service/internal/models"
+)
+
+func TestDeadLetterDecode(t *testing.T) {
+	failedAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	data, _ := json.Marshal(&models.Event{ID: "e1", ProjectID: "p1", EventType: models.Purchase})
+
+	letter := dlq.Decode(kafka.Message{
+		Partition: 2,
+		Offset:    42,
+		Value:     data,
+		Headers: []kafka.Header{
+			{Key: dlq.HeaderReason, Value: []byte(dlq.ReasonInsertFailed)},
+			{Key: dlq.HeaderError, Value: []byte("connection refused")},
+			{Key: dlq.HeaderFailedAt, Value: []byte(failedAt.Format(time.RFC3339Nano))},
+			{Key: dlq.HeaderSourceTopic, Value: []byte("events")},
+			{Key: dlq.HeaderSourcePartition, Value: []byte("1")},
+			{Key: dlq.HeaderSourceOffset, Value: []byte("1000")},
+		},
+	})
+
+	assert.Equal(t, 2, letter.Partition)
+	assert.Equal(t, int64(42), letter.Offset)
+	assert.Equal(t, dlq.ReasonInsertFailed, letter.Reason)
+	assert.Equal(t, "connection refused", letter.Error)
+	assert.True(t, failedAt.Equal(letter.FailedAt))
+	assert.Equal(t, "events", letter.SourceTopic)
+	assert.Equal(t, 1, letter.SourcePartition)
+	assert.Equal(t, int64(1000), letter.SourceOffset)
+	// Проект и тип берутся из события, если заголовков нет
+	assert.Equal(t, "p1", letter.ProjectID)
+	assert.Equal(t, string(models.Purchase), letter.EventType)
+	assert.NotNil(t, letter.Event)
+
+	raw := dlq.Decode(kafka.Message{
+		Value:   []byte("{not json"),
+		Headers: []kafka.Header{{Key: dlq.HeaderProjectID, Value: []byte("p2")}},
+	})
+	assert.Nil(t, raw.Event)
+	assert.Equal(t, "{not json", raw.Raw)
+	assert.Equal(t, "p2", raw.ProjectID)
+}
+
+func TestDeadLetterFilterMatch(t *testing.T) {
+	failedAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	letter := &models.DeadLetter{
+		ProjectID: "p1",
+		EventType: "purchase",
+		Reason:    dlq.ReasonInsertFailed,
+		FailedAt:  failedAt,
+	}
+
+	assert.True(t, models.DeadLetterFilter{}.Empty())
+	assert.True(t, models.DeadLetterFilter{}.Match(letter))
+	assert.True(t, models.DeadLetterFilter{ProjectID: "p1", Reason: dlq.ReasonInsertFailed}.Match(letter))
+	assert.False(t, models.DeadLetterFilter{ProjectID: "p2"}.Match(letter))
+	assert.False(t, models.DeadLetterFilter{EventType: "click"}.Match(letter))
+	assert.False(t, models.DeadLetterFilter{Reason: dlq.ReasonUnmarshalFailed}.Match(letter))
+	assert.True(t, models.DeadLetterFilter{Since: failedAt.Add(-time.Hour), Until: failedAt.Add(time.Hour)}.Match(letter))
+	assert.False(t, models.DeadLetterFilter{Since: failedAt.Add(time.Minute)}.Match(letter))
+	assert.False(t, models.DeadLetterFilter{Until: failedAt.Add(-time.Minute)}.Match(letter))
+}
@@ -0,0 +1,158 @@
+package unit
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/event-analytics-service/internal/geoip"
+)
+
+// writeCountryDB записывает базу MaxMind DB (IPv4, 24-битные записи), в
+// которой каждой IPv4 сети соответствует код страны
+func writeCountryDB(t *testing.T, path string, networks map[string]string) {
+	t.Helper()
+
+	type node struct{ records [2]int } // индекс узла, -1 - пусто, <= -2 - данные
+	nodes := []node{{records: [2]int{-1, -1}}}
+
+	var data bytes.Buffer
+	cidrs := make([]string, 0, len(networks))
+	for cidr := range networks {
+		cidrs = append(cidrs, cidr)
+	}
+	sort.Strings(cidrs)
+
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		require.NoError(t, err)
+		ones, _ := network.Mask.Size()
+		ip := network.IP.To4()
+
+		offset := data.Len()
+		writeMap(&data, map[string]func(*bytes.Buffer){
+			"country": func(b *bytes.Buffer) {
+				writeMap(b, map[string]func(*bytes.Buffer){
+					"iso_code": func(b *bytes.Buffer) { writeString(b, networks[cidr]) },
+				})
+			},
+		})
+
+		current := 0
+		for i := 0; i < ones; i++ {
+			bit := int(ip[i/8]>>(7-uint(i%8))) & 1
+			if i == ones-1 {
+				nodes[current].records[bit] = -2 - offset
+				break
+			}
+			if nodes[current].records[bit] < 0 {
+				nodes = append(nodes, node{records: [2]int{-1, -1}})
+				nodes[current].records[bit] = len(nodes) - 1
+			}
+			current = nodes[current].records[bit]
+		}
+	}
+
+	var db bytes.Buffer
+	nodeCount := len(nodes)
+	for _, n := range nodes {
+		for _, record := range n.records {
+			value := record
+			switch {
+			case record == -1:
+				value = nodeCount
+			case record <= -2:
+				value = nodeCount + 16 + (-2 - record)
+			}
+			db.Write([]byte{byte(value >> 16), byte(value >> 8), byte(value)})
+		}
+	}
+	db.Write(make([]byte, 16))
+	db.Write(data.Bytes())
+
+	db.WriteString("\xAB\xCD\xEFMaxMind.com")
+	writeMap(&db, map[string]func(*bytes.Buffer){
+		"node_count":                  func(b *bytes.Buffer) { writeUint(b, 6, uint64(nodeCount)) },
+		"record_size":                 func(b *bytes.Buffer) { writeUint(b, 5, 24) },
+		"ip_version":                  func(b *bytes.Buffer) { writeUint(b, 5, 4) },
+		"database_type":               func(b *bytes.Buffer) { writeString(b, "GeoLite2-Country") },
+		"binary_format_major_version": func(b *bytes.Buffer) { writeUint(b, 5, 2) },
+	})
+
+	require.NoError(t, os.WriteFile(path, db.Bytes(), 0o644))
+}
+
+func writeMap(b *bytes.Buffer, fields map[string]func(*bytes.Buffer)) {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	b.WriteByte(7<<5 | byte(len(fields)))
+	for _, key := range keys {
+		writeString(b, key)
+		fields[key](b)
+	}
+}
+
+func writeString(b *bytes.Buffer, s string) {
+	b.WriteByte(2<<5 | byte(len(s)))
+	b.WriteString(s)
+}
+
+// writeUint записывает uint16 (тип 5) или uint32 (тип 6) минимальным числом байт
+func writeUint(b *bytes.Buffer, typ byte, value uint64) {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], value)
+	trimmed := bytes.TrimLeft(buf[:], "\x00")
+	b.WriteByte(typ<<5 | byte(len(trimmed)))
+	b.Write(trimmed)
+}
+
+func TestGeoIPResolverHotReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "GeoLite2-Country.mmdb")
+	writeCountryDB(t, path, map[string]string{"81.2.69.0/24": "GB"})
+
+	resolver, err := geoip.NewResolver(path)
+	require.NoError(t, err)
+	defer resolver.Close()
+
+	loc, ok := resolver.Lookup("81.2.69.142")
+	assert.True(t, ok)
+	assert.Equal(t, "GB", loc.CountryCode)
+
+	_, ok = resolver.Lookup("10.0.0.1")
+	assert.False(t, ok)
+
+	go resolver.Watch(10 * time.Millisecond)
+
+	// Новая база подхватывается без перезапуска
+	writeCountryDB(t, path, map[string]string{"81.2.69.0/24": "DE", "10.0.0.0/8": "FR"})
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(path, later, later))
+
+	assert.Eventually(t, func() bool {
+		loc, ok := resolver.Lookup("81.2.69.142")
+		return ok && loc.CountryCode == "DE"
+	}, time.Second, 10*time.Millisecond)
+
+	loc, ok = resolver.Lookup("10.0.0.1")
+	assert.True(t, ok)
+	assert.Equal(t, "FR", loc.CountryCode)
+
+	// Поврежденный файл не заменяет загруженную базу
+	require.NoError(t, os.WriteFile(path, []byte("not a database"), 0o644))
+	time.Sleep(50 * time.Millisecond)
+
+	loc, ok = resolver.Lookup("81.2.69.142")
+	assert.True(t, ok)
+	assert.Equal(t, "DE", loc.CountryCode)
+}
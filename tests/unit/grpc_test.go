@@ -0,0 +1,157 @@
+package unit
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/yourusername/event-analytics-service/internal/grpcserver"
+	"github.com/yourusername/event-analytics-service/internal/models"
+	"github.com/yourusername/event-analytics-service/internal/service"
+	"github.com/yourusername/event-analytics-service/proto/analyticspb"
+)
+
+func TestGRPCRequiresAPIKey(t *testing.T) {
+	server := grpcserver.NewServer(nil, nil, nil, nil, nil)
+
+	called := false
+	_, err := server.UnaryInterceptor(context.Background(), &analyticspb.TrackRequest{},
+		&grpc.UnaryServerInfo{FullMethod: analyticspb.AnalyticsService_Track_FullMethodName},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			called = true
+			return nil, nil
+		})
+
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	assert.False(t, called)
+}
+
+// startGRPC запускает gRPC сервер поверх ingestHarness и возвращает клиента
+// с API ключом проекта в metadata
+func startGRPC(t *testing.T, h *ingestHarness, project *models.Project) (analyticspb.AnalyticsServiceClient, context.Context) {
+	t.Helper()
+
+	h.projects.On("ValidateAPIKey", mock.Anything, "key-1").Return(project, nil)
+	server := grpcserver.NewServer(h.service, nil, service.NewProjectService(h.projects, nil, h.cache), h.quota, testMetrics).GRPCServer()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "key-1")
+	return analyticspb.NewAnalyticsServiceClient(conn), ctx
+}
+
+// trackStream отправляет count событий одним потоком
+func trackStream(t *testing.T, client analyticspb.AnalyticsServiceClient, ctx context.Context, count int) (*analyticspb.TrackStreamResponse, error) {
+	t.Helper()
+
+	stream, err := client.TrackStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < count; i++ {
+		event := &analyticspb.Event{UserId: fmt.Sprintf("u%d", i), EventType: "page_view"}
+		if err := stream.Send(&analyticspb.TrackRequest{Event: event}); err != nil {
+			break
+		}
+	}
+	return stream.CloseAndRecv()
+}
+
+// partialResults возвращает результаты, переданные в деталях статуса прерванного потока
+func partialResults(err error) *analyticspb.TrackStreamResponse {
+	for _, detail := range status.Convert(err).Details() {
+		if response, ok := detail.(*analyticspb.TrackStreamResponse); ok {
+			return response
+		}
+	}
+	return nil
+}
+
+func TestGRPCStreamRechecksQuota(t *testing.T) {
+	project := &models.Project{ID: "project-1", Active: true, EventsLimit: 500}
+	h := newIngestHarness(t, project, models.TimestampWindow{})
+	client, ctx := startGRPC(t, h, project)
+
+	// Квота заканчивается на первом батче: поток прерывается на втором, а не
+	// принимает события до конца
+	_, err := trackStream(t, client, ctx, 1200)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// Клиент знает, какие события уже приняты
+	partial := partialResults(err)
+	if assert.NotNil(t, partial) {
+		assert.Equal(t, int32(500), partial.Accepted)
+		assert.Len(t, partial.Results, 500)
+		assert.Equal(t, int32(499), partial.Results[499].Index)
+		assert.NotEmpty(t, partial.Results[0].EventId)
+	}
+
+	used, _ := h.redis.Get(monthlyUsageKey(project.ID))
+	assert.Equal(t, "500", used)
+	assert.Len(t, h.published(t), 500)
+}
+
+// fillRateLimit занимает все запросы, кроме left, в окнах ближайших секунд
+func fillRateLimit(t *testing.T, h *ingestHarness, project *models.Project, left int) {
+	t.Helper()
+
+	now := time.Now().Truncate(time.Second)
+	limit := models.GetPlan(project.PlanType).RequestsPerSecond
+	for i := 0; i < 3; i++ {
+		key := fmt.Sprintf("ratelimit:%s:%d", project.ID, now.Add(time.Duration(i)*time.Second).Unix())
+		h.cache.Client.Set(context.Background(), key, limit-left, time.Minute)
+	}
+}
+
+func TestGRPCStreamFirstBatchNotChargedTwice(t *testing.T) {
+	project := &models.Project{ID: "project-1", Active: true, PlanType: models.PlanFree}
+	h := newIngestHarness(t, project, models.TimestampWindow{})
+	client, ctx := startGRPC(t, h, project)
+
+	// Открытие потока занимает последний запрос секунды; первый батч уже
+	// учтен им и не проверяется повторно
+	fillRateLimit(t, h, project, 1)
+
+	response, err := trackStream(t, client, ctx, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(10), response.GetAccepted())
+	assert.Len(t, h.published(t), 10)
+}
+
+func TestGRPCStreamRechecksRateLimit(t *testing.T) {
+	project := &models.Project{ID: "project-1", Active: true, PlanType: models.PlanFree}
+	h := newIngestHarness(t, project, models.TimestampWindow{})
+	client, ctx := startGRPC(t, h, project)
+
+	// Открытие потока занимает последний запрос секунды, второй батч уже не проходит
+	fillRateLimit(t, h, project, 1)
+
+	_, err := trackStream(t, client, ctx, 600)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	if partial := partialResults(err); assert.NotNil(t, partial) {
+		assert.Equal(t, int32(500), partial.Accepted)
+		assert.Len(t, partial.Results, 500)
+	}
+	assert.Len(t, h.published(t), 500)
+}
@@ -7,41 +7,92 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+
 	"github.com/yourusername/event-analytics-service/internal/handler"
 	"github.com/yourusername/event-analytics-service/internal/models"
+	"github.com/yourusername/event-analytics-service/internal/repository"
 	"github.com/yourusername/event-analytics-service/internal/service"
 )
 
-type MockEventService struct {
+type MockUserRepository struct {
 	mock.Mock
 }
 
-func (m *MockEventService) ProcessEvent(ctx context.Context, event *models.Event) error {
-	args := m.Called(ctx, event)
-	return args.Error(0)
+func (m *MockUserRepository) Create(ctx context.Context, user *models.User) error {
+	return m.Called(ctx, user).Error(0)
+}
+
+func (m *MockUserRepository) GetByID(ctx context.Context, id string) (*models.User, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockUserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	args := m.Called(ctx, email)
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockUserRepository) Update(ctx context.Context, user *models.User) error {
+	return m.Called(ctx, user).Error(0)
+}
+
+func (m *MockUserRepository) Delete(ctx context.Context, id string) error {
+	return m.Called(ctx, id).Error(0)
 }
 
-type MockStatsService struct {
+type MockSessionRepository struct {
 	mock.Mock
 }
 
-func (m *MockStatsService) GetEventStatistics(ctx context.Context, req models.StatsRequest) ([]models.EventStats, error) {
-	args := m.Called(ctx, req)
-	return args.Get(0).([]models.EventStats), args.Error(1)
+func (m *MockSessionRepository) Create(ctx context.Context, session *models.Session) error {
+	return m.Called(ctx, session).Error(0)
+}
+
+func (m *MockSessionRepository) GetByRefreshToken(ctx context.Context, refreshToken string) (*models.Session, error) {
+	args := m.Called(ctx, refreshToken)
+	return args.Get(0).(*models.Session), args.Error(1)
+}
+
+func (m *MockSessionRepository) GetByUserID(ctx context.Context, userID string) ([]*models.Session, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]*models.Session), args.Error(1)
+}
+
+func (m *MockSessionRepository) Delete(ctx context.Context, id string) error {
+	return m.Called(ctx, id).Error(0)
+}
+
+func (m *MockSessionRepository) DeleteAllForUser(ctx context.Context, userID string) error {
+	return m.Called(ctx, userID).Error(0)
+}
+
+func (m *MockSessionRepository) CleanupExpired(ctx context.Context) error {
+	return m.Called(ctx).Error(0)
+}
+
+// unreachableRedis - Redis, к которому нельзя подключиться: кэш всегда промахивается
+func unreachableRedis() *repository.RedisRepository {
+	return repository.NewRedisRepository("127.0.0.1:1", "", 0)
 }
 
 func TestEventHandler_TrackEvent(t *testing.T) {
 	// Setup
 	gin.SetMode(gin.TestMode)
 
-	mockEventService := new(MockEventService)
-	eventHandler := handler.NewEventHandler(mockEventService)
+	project := &models.Project{ID: "project-1", Active: true}
+	h := newIngestHarness(t, project, models.TimestampWindow{})
+	eventHandler := handler.NewEventHandler(h.service)
 
 	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("project_id", project.ID)
+	})
 	router.POST("/events/track", eventHandler.TrackEvent)
 
 	// Test data
@@ -54,9 +105,6 @@ func TestEventHandler_TrackEvent(t *testing.T) {
 		},
 	}
 
-	// Mock expectations
-	mockEventService.On("ProcessEvent", mock.Anything, mock.AnythingOfType("*models.Event")).Return(nil)
-
 	// Execute request
 	jsonData, _ := json.Marshal(event)
 	req, _ := http.NewRequest("POST", "/events/track", bytes.NewBuffer(jsonData))
@@ -76,15 +124,52 @@ func TestEventHandler_TrackEvent(t *testing.T) {
 	assert.Contains(t, response, "event_id")
 	assert.Equal(t, "Event tracked successfully", response["message"])
 
-	mockEventService.AssertExpectations(t)
+	published := h.published(t)
+	if assert.Len(t, published, 1) {
+		assert.Equal(t, response["event_id"], published[0].ID)
+		assert.Equal(t, project.ID, published[0].ProjectID)
+		assert.Equal(t, "test-agent", published[0].UserAgent)
+	}
+
+	// Невалидный JSON отклоняется до обработки
+	req, _ = http.NewRequest("POST", "/events/track", bytes.NewBufferString("{"))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestEventHandler_TrackEventWithoutProject(t *testing.T) {
+	// Setup: запрос без проекта в контексте (middleware не отработал)
+	gin.SetMode(gin.TestMode)
+
+	eventService := service.NewEventService(new(MockEventRepository), nil, nil, nil, nil, nil, nil, nil, testMetrics, time.Hour, "", models.TimestampWindow{})
+	eventHandler := handler.NewEventHandler(eventService)
+
+	router := gin.New()
+	router.POST("/events/track", eventHandler.TrackEvent)
+
+	req, _ := http.NewRequest("POST", "/events/track", bytes.NewBufferString(`{"user_id": "user123", "event_type": "page_view"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, models.ErrInvalidAPIKey.Error(), response["error"])
 }
 
 func TestStatsHandler_GetStatistics(t *testing.T) {
 	// Setup
 	gin.SetMode(gin.TestMode)
 
-	mockStatsService := new(MockStatsService)
-	statsHandler := handler.NewStatsHandler(mockStatsService)
+	mockRepo := new(MockEventRepository)
+	statsService := service.NewStatsService(mockRepo, unreachableRedis(), testMetrics)
+	statsHandler := handler.NewStatsHandler(statsService)
 
 	router := gin.New()
 	router.GET("/stats/events", statsHandler.GetStatistics)
@@ -96,7 +181,7 @@ func TestStatsHandler_GetStatistics(t *testing.T) {
 	}
 
 	// Mock expectations
-	mockStatsService.On("GetEventStatistics", mock.Anything, mock.AnythingOfType("models.StatsRequest")).
+	mockRepo.On("GetStatsByProject", mock.Anything, "", mock.AnythingOfType("models.StatsRequest")).
 		Return(expectedStats, nil)
 
 	// Execute request
@@ -115,15 +200,14 @@ func TestStatsHandler_GetStatistics(t *testing.T) {
 	assert.True(t, exists)
 	assert.NotNil(t, stats)
 
-	mockStatsService.AssertExpectations(t)
+	mockRepo.AssertExpectations(t)
 }
 
 func TestStatsHandler_GetStatistics_ValidationError(t *testing.T) {
 	// Setup
 	gin.SetMode(gin.TestMode)
 
-	mockStatsService := new(MockStatsService)
-	statsHandler := handler.NewStatsHandler(mockStatsService)
+	statsHandler := handler.NewStatsHandler(nil)
 
 	router := gin.New()
 	router.GET("/stats/events", statsHandler.GetStatistics)
@@ -146,8 +230,10 @@ func TestAuthHandler_Login(t *testing.T) {
 	// Setup
 	gin.SetMode(gin.TestMode)
 
-	mockAuthService := new(MockAuthService)
-	authHandler := handler.NewAuthHandler(mockAuthService)
+	mockUsers := new(MockUserRepository)
+	mockSessions := new(MockSessionRepository)
+	authService := service.NewAuthService(mockUsers, mockSessions, "secret", time.Hour, 24*time.Hour)
+	authHandler := handler.NewAuthHandler(authService)
 
 	router := gin.New()
 	router.POST("/auth/login", authHandler.Login)
@@ -158,15 +244,14 @@ func TestAuthHandler_Login(t *testing.T) {
 		"password": "password123",
 	}
 
-	expectedTokens := &service.TokenPair{
-		AccessToken:  "access_token_123",
-		RefreshToken: "refresh_token_123",
-		ExpiresIn:    3600,
-	}
+	hash, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	user := &models.User{ID: "user-1", Email: "test@example.com", Role: "user", PasswordHash: string(hash)}
 
 	// Mock expectations
-	mockAuthService.On("Login", mock.Anything, "test@example.com", "password123").
-		Return(expectedTokens, nil)
+	mockUsers.On("GetByEmail", mock.Anything, "test@example.com").Return(user, nil)
+	mockUsers.On("GetByID", mock.Anything, "user-1").Return(user, nil)
+	mockUsers.On("Update", mock.Anything, user).Return(nil)
+	mockSessions.On("Create", mock.Anything, mock.AnythingOfType("*models.Session")).Return(nil)
 
 	// Execute request
 	jsonData, _ := json.Marshal(loginReq)
@@ -185,5 +270,6 @@ func TestAuthHandler_Login(t *testing.T) {
 	assert.Contains(t, response, "access_token")
 	assert.Contains(t, response, "refresh_token")
 
-	mockAuthService.AssertExpectations(t)
+	mockUsers.AssertExpectations(t)
+	mockSessions.AssertExpectations(t)
 }
@@ -0,0 +1,167 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/mock"
+	"github.com/yourusername/event-analytics-service/internal/models"
+	"github.com/yourusername/event-analytics-service/internal/producer"
+	"github.com/yourusername/event-analytics-service/internal/repository"
+	"github.com/yourusername/event-analytics-service/internal/service"
+)
+
+type MockProjectRepository struct {
+	mock.Mock
+}
+
+func (m *MockProjectRepository) CreateProject(ctx context.Context, project *models.Project) error {
+	return m.Called(ctx, project).Error(0)
+}
+
+func (m *MockProjectRepository) GetProjectByID(ctx context.Context, id string) (*models.Project, error) {
+	args := m.Called(ctx, id)
+	project, _ := args.Get(0).(*models.Project)
+	return project, args.Error(1)
+}
+
+func (m *MockProjectRepository) GetProjectByAPIKey(ctx context.Context, apiKey string) (*models.Project, error) {
+	args := m.Called(ctx, apiKey)
+	project, _ := args.Get(0).(*models.Project)
+	return project, args.Error(1)
+}
+
+func (m *MockProjectRepository) GetProjectsByUserID(ctx context.Context, userID string, limit, offset int) ([]*models.Project, error) {
+	args := m.Called(ctx, userID, limit, offset)
+	return args.Get(0).([]*models.Project), args.Error(1)
+}
+
+func (m *MockProjectRepository) UpdateProject(ctx context.Context, project *models.Project) error {
+	return m.Called(ctx, project).Error(0)
+}
+
+func (m *MockProjectRepository) DeleteProject(ctx context.Context, id string) error {
+	return m.Called(ctx, id).Error(0)
+}
+
+func (m *MockProjectRepository) GenerateAPIKey(ctx context.Context, projectID string) (string, error) {
+	args := m.Called(ctx, projectID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockProjectRepository) ValidateAPIKey(ctx context.Context, apiKey string) (*models.Project, error) {
+	args := m.Called(ctx, apiKey)
+	project, _ := args.Get(0).(*models.Project)
+	return project, args.Error(1)
+}
+
+func (m *MockProjectRepository) GetProjectStats(ctx context.Context, projectID string, start, end time.Time) (*models.ProjectStats, error) {
+	args := m.Called(ctx, projectID, start, end)
+	return args.Get(0).(*models.ProjectStats), args.Error(1)
+}
+
+func (m *MockProjectRepository) UpdateEventsCount(ctx context.Context, projectID string, count int64) error {
+	return m.Called(ctx, projectID, count).Error(0)
+}
+
+// noSchemas - реестр схем, в котором у проектов нет ни одной схемы
+type noSchemas struct{}
+
+func (noSchemas) UpsertSchema(ctx context.Context, schema *models.EventSchema) error { return nil }
+
+func (noSchemas) GetSchema(ctx context.Context, projectID, eventName string) (*models.EventSchema, error) {
+	return nil, models.ErrSchemaNotFound
+}
+
+func (noSchemas) ListSchemas(ctx context.Context, projectID string) ([]*models.EventSchema, error) {
+	return nil, nil
+}
+
+func (noSchemas) DeleteSchema(ctx context.Context, projectID, eventName string) error { return nil }
+
+// ingestHarness - EventService с Redis в памяти и недоступной Kafka: все
+// опубликованные события попадают в spool, откуда их читает published
+type ingestHarness struct {
+	service  *service.EventService
+	redis    *fakeRedis
+	cache    *repository.RedisRepository
+	projects *MockProjectRepository
+	quota    *service.QuotaService
+	producer *producer.EventProducer
+	spoolDir string
+}
+
+func newIngestHarness(t *testing.T, project *models.Project, window models.TimestampWindow) *ingestHarness {
+	t.Helper()
+
+	redis, cache := newFakeRedis(t)
+
+	projects := new(MockProjectRepository)
+	projects.On("GetProjectByID", mock.Anything, project.ID).Return(project, nil)
+
+	spoolDir := t.TempDir()
+	spool, err := producer.NewSpool(spoolDir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eventProducer := producer.NewEventProducer([]string{"127.0.0.1:1"}, "events", kafka.RequireOne, spool, time.Hour, testMetrics)
+	quota := service.NewQuotaService(cache, projects, models.QuotaModeHard)
+
+	eventService := service.NewEventService(
+		new(MockEventRepository),
+		eventProducer,
+		service.NewSchemaService(noSchemas{}, cache),
+		service.NewProjectService(projects, nil, cache),
+		quota,
+		cache,
+		nil,
+		nil,
+		testMetrics,
+		time.Hour,
+		"salt",
+		window,
+	)
+
+	return &ingestHarness{
+		service:  eventService,
+		redis:    redis,
+		cache:    cache,
+		projects: projects,
+		quota:    quota,
+		producer: eventProducer,
+		spoolDir: spoolDir,
+	}
+}
+
+// published останавливает продюсер и возвращает события, записанные в spool
+func (h *ingestHarness) published(t *testing.T) []*models.Event {
+	t.Helper()
+
+	h.producer.Close()
+
+	spool, err := producer.NewSpool(h.spoolDir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer spool.Close()
+
+	var events []*models.Event
+	_, err = spool.Replay(context.Background(), func(ctx context.Context, messages ...kafka.Message) error {
+		for _, msg := range messages {
+			var event models.Event
+			if err := json.Unmarshal(msg.Value, &event); err != nil {
+				return err
+			}
+			events = append(events, &event)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return events
+}
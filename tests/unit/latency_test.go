@@ -0,0 +1,80 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yourusername/event-analytics-service/internal/handler"
+	"github.com/yourusername/event-analytics-service/internal/models"
+	"github.com/yourusername/event-analytics-service/internal/service"
+)
+
+func newLatencyRouter(projects *MockProjectRepository, events *MockEventRepository) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	projectHandler := handler.NewProjectHandler(service.NewProjectService(projects, events, unreachableRedis()))
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("user_id", "user-1") })
+	router.GET("/projects/:id/ingestion-latency", projectHandler.GetIngestionLatency)
+	return router
+}
+
+func getLatency(router *gin.Engine, query string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodGet, "/projects/project-1/ingestion-latency?"+query, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestGetIngestionLatency(t *testing.T) {
+	projects := new(MockProjectRepository)
+	events := new(MockEventRepository)
+	router := newLatencyRouter(projects, events)
+
+	projects.On("GetProjectByID", mock.Anything, "project-1").Return(&models.Project{ID: "project-1", UserID: "user-1"}, nil)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	report := &models.IngestionLatencyReport{
+		Total:   models.IngestionLatency{Events: 10, P50: 0.2, P95: 1.5, P99: 2, Max: 3},
+		Buckets: []models.IngestionLatency{{TimeBucket: "2024-01-01 00:00:00", Events: 10, P95: 1.5}},
+	}
+	// end_date включается в период целиком
+	events.On("GetIngestionLatency", mock.Anything, "project-1", "day", start, start.AddDate(0, 0, 7)).Return(report, nil)
+
+	w := getLatency(router, "start_date=2024-01-01&end_date=2024-01-07&interval=day")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Latency  models.IngestionLatencyReport `json:"latency"`
+		Interval string                        `json:"interval"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "day", response.Interval)
+	assert.Equal(t, *report, response.Latency)
+	events.AssertExpectations(t)
+}
+
+func TestGetIngestionLatencyErrors(t *testing.T) {
+	projects := new(MockProjectRepository)
+	events := new(MockEventRepository)
+	router := newLatencyRouter(projects, events)
+
+	assert.Equal(t, http.StatusBadRequest, getLatency(router, "end_date=2024-01-07").Code)
+	assert.Equal(t, http.StatusBadRequest, getLatency(router, "start_date=2024-01-01&end_date=2024-01-07&interval=week").Code)
+
+	// Чужой проект
+	projects.On("GetProjectByID", mock.Anything, "project-1").Return(&models.Project{ID: "project-1", UserID: "user-2"}, nil).Once()
+	assert.Equal(t, http.StatusForbidden, getLatency(router, "start_date=2024-01-01&end_date=2024-01-07").Code)
+
+	projects.On("GetProjectByID", mock.Anything, "project-1").Return(nil, models.ErrProjectNotFound).Once()
+	assert.Equal(t, http.StatusNotFound, getLatency(router, "start_date=2024-01-01&end_date=2024-01-07").Code)
+
+	events.AssertNotCalled(t, "GetIngestionLatency")
+}
@@ -0,0 +1,72 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/event-analytics-service/internal/handler"
+	"github.com/yourusername/event-analytics-service/internal/models"
+)
+
+func TestTrackPixelWithoutProject(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// Без проекта в контексте событие не обрабатывается, но картинка отдается
+	router := gin.New()
+	router.GET("/pixel.gif", handler.NewEventHandler(nil).TrackPixel)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/pixel.gif?api_key=bad&event_type=unknown", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/gif", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Cache-Control"), "no-store")
+	assert.Equal(t, "GIF89a", w.Body.String()[:6])
+	assert.Equal(t, 43, w.Body.Len())
+}
+
+func TestRefererHeaderOnlyForPixel(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	project := &models.Project{ID: "project-1", Active: true}
+	h := newIngestHarness(t, project, models.TimestampWindow{})
+	eventHandler := handler.NewEventHandler(h.service)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("project_id", project.ID) })
+	router.POST("/events/track", eventHandler.TrackEvent)
+	router.POST("/events/batch", eventHandler.TrackBatch)
+	router.GET("/pixel.gif", eventHandler.TrackPixel)
+
+	send := func(req *http.Request) {
+		req.Header.Set("Referer", "https://app.example.com/dashboard")
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// У JavaScript-трекера Referer - страница с трекером, а не источник перехода
+	send(httptest.NewRequest(http.MethodPost, "/events/track",
+		strings.NewReader(`{"user_id": "u1", "page_url": "https://www.example.com/pricing"}`)))
+	send(httptest.NewRequest(http.MethodPost, "/events/batch",
+		strings.NewReader(`[{"user_id": "u2", "page_url": "https://www.example.com/pricing"}]`)))
+	send(httptest.NewRequest(http.MethodPost, "/events/track",
+		strings.NewReader(`{"user_id": "u3", "page_url": "https://www.example.com/", "referrer": "https://www.google.com/"}`)))
+	send(httptest.NewRequest(http.MethodGet, "/pixel.gif?user_id=u4&page_url="+url.QueryEscape("https://www.example.com/"), nil))
+
+	referrers := make(map[string]string)
+	for _, event := range h.published(t) {
+		referrers[event.UserID] = event.Referrer
+	}
+	assert.Equal(t, map[string]string{
+		"u1": "",
+		"u2": "",
+		"u3": "https://www.google.com/",
+		"u4": "https://app.example.com/dashboard",
+	}, referrers)
+}
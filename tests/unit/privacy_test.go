@@ -0,0 +1,120 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/event-analytics-service/internal/models"
+	"github.com/yourusername/event-analytics-service/internal/service"
+)
+
+func TestApplyPrivacy(t *testing.T) {
+	settings := models.PrivacySettings{
+		IPMode:       models.IPModeTruncate,
+		DropKeys:     []string{"password"},
+		HashKeys:     []string{"email"},
+		RedactEmails: true,
+		RedactPhones: true,
+	}
+
+	event := &models.Event{
+		ProjectID: "project-1",
+		IPAddress: "203.0.113.42",
+		PageURL:   "/signup?email=john%40example.com",
+		Metadata: map[string]interface{}{
+			"password": "secret",
+			"email":    "john@example.com",
+			"comment":  "call me at +7 (999) 123-45-67",
+			"nested":   map[string]interface{}{"contact": "jane@example.org"},
+			"price":    9.99,
+		},
+	}
+
+	service.ApplyPrivacy(event, settings, "salt")
+
+	assert.Equal(t, "203.0.113.0", event.IPAddress)
+	assert.Equal(t, "/signup?email=[email]", event.PageURL)
+	assert.NotContains(t, event.Metadata, "password")
+	assert.Len(t, event.Metadata["email"], 32)
+	assert.NotEqual(t, "john@example.com", event.Metadata["email"])
+	assert.Equal(t, "call me at [phone]", event.Metadata["comment"])
+	assert.Equal(t, map[string]interface{}{"contact": "[email]"}, event.Metadata["nested"])
+	assert.Equal(t, 9.99, event.Metadata["price"])
+
+	// IPv6 обрезается до /48
+	event = &models.Event{IPAddress: "2001:db8:85a3:8d3:1319:8a2e:370:7348"}
+	service.ApplyPrivacy(event, models.PrivacySettings{IPMode: models.IPModeTruncate}, "salt")
+	assert.Equal(t, "2001:db8:85a3::", event.IPAddress)
+
+	// Хэш стабилен внутри проекта и различается между проектами
+	a := &models.Event{ProjectID: "project-1", IPAddress: "203.0.113.42"}
+	b := &models.Event{ProjectID: "project-1", IPAddress: "203.0.113.42"}
+	c := &models.Event{ProjectID: "project-2", IPAddress: "203.0.113.42"}
+	hash := models.PrivacySettings{IPMode: models.IPModeHash}
+	service.ApplyPrivacy(a, hash, "salt")
+	service.ApplyPrivacy(b, hash, "salt")
+	service.ApplyPrivacy(c, hash, "salt")
+	assert.Equal(t, a.IPAddress, b.IPAddress)
+	assert.NotEqual(t, a.IPAddress, c.IPAddress)
+}
+
+func TestRedactPhones(t *testing.T) {
+	settings := models.PrivacySettings{RedactPhones: true}
+
+	redacted := map[string]string{
+		"call me at +7 (999) 123-45-67.":  "call me at [phone].",
+		"+79991234567":                    "[phone]",
+		"tel: +44 20 7946 0958":           "tel: [phone]",
+		"office (495) 123-45-67":          "office [phone]",
+		"8 (999) 123-45-67":               "[phone]",
+		"US 555-123-4567 or 555.123.4567": "US [phone] or [phone]",
+		"/contact?phone=%2B79991234567":   "/contact?phone=[phone]",
+		"/contact?phone=+79991234567&x=1": "/contact?phone=[phone]&x=1",
+	}
+	for input, want := range redacted {
+		event := &models.Event{Metadata: map[string]interface{}{"value": input}}
+		service.ApplyPrivacy(event, settings, "salt")
+		assert.Equal(t, want, event.Metadata["value"], input)
+	}
+
+	// Идентификаторы, даты, timestamp и суммы не считаются телефонами
+	for _, input := range []string{
+		"/orders/1234567890",
+		"/orders/555-123-4567/items",
+		"550e8400-e29b-41d4-a716-446655440000",
+		"12345678-1234-1234-1234-123456789012",
+		"2024-01-15 10:30:00",
+		"2024-01-15T10:30:00+03:00",
+		"ts=1697040000000",
+		"1 299 000.50",
+		"order 123456789 total 1299.00",
+	} {
+		event := &models.Event{PageURL: input, Metadata: map[string]interface{}{"value": input}}
+		service.ApplyPrivacy(event, settings, "salt")
+		assert.Equal(t, input, event.PageURL)
+		assert.Equal(t, input, event.Metadata["value"])
+	}
+}
+
+func TestProjectPrivacySettings(t *testing.T) {
+	project := &models.Project{Settings: models.JSON{
+		"privacy": map[string]interface{}{
+			"ip_mode":   "hash",
+			"drop_keys": []interface{}{"password"},
+		},
+	}}
+	settings, err := project.Privacy()
+	assert.NoError(t, err)
+	assert.Equal(t, models.IPModeHash, settings.IPMode)
+	assert.Equal(t, []string{"password"}, settings.DropKeys)
+	assert.True(t, settings.Enabled())
+
+	project.Settings = models.JSON{"privacy": map[string]interface{}{"ip_mode": "erase"}}
+	_, err = project.Privacy()
+	assert.ErrorIs(t, err, models.ErrInvalidPrivacySettings)
+
+	project.Settings = nil
+	settings, err = project.Privacy()
+	assert.NoError(t, err)
+	assert.False(t, settings.Enabled())
+}
@@ -0,0 +1,133 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yourusername/event-analytics-service/internal/models"
+	"github.com/yourusername/event-analytics-service/internal/service"
+)
+
+func monthlyUsageKey(projectID string) string {
+	return fmt.Sprintf("usage:%s:%s", projectID, time.Now().UTC().Format("200601"))
+}
+
+func batchOf(projectID string, n int) []*models.Event {
+	events := make([]*models.Event, n)
+	for i := range events {
+		events[i] = &models.Event{
+			ProjectID: projectID,
+			UserID:    fmt.Sprintf("u%d", i),
+			EventType: models.PageView,
+		}
+	}
+	return events
+}
+
+func TestQuotaReservesAcceptedEvents(t *testing.T) {
+	ctx := context.Background()
+	project := &models.Project{ID: "project-1", Active: true, EventsLimit: 5}
+	h := newIngestHarness(t, project, models.TimestampWindow{})
+	h.cache.Client.Set(ctx, monthlyUsageKey(project.ID), 3, 0)
+
+	// Из батча в квоту укладываются только первые два события
+	results, err := h.service.ProcessBatch(ctx, batchOf(project.ID, 4))
+	assert.NoError(t, err)
+	if assert.Len(t, results, 4) {
+		assert.Equal(t, models.BatchStatusAccepted, results[0].Status)
+		assert.Equal(t, models.BatchStatusAccepted, results[1].Status)
+		assert.Equal(t, models.BatchStatusRejected, results[2].Status)
+		assert.Equal(t, models.ErrRateLimitExceeded.Error(), results[2].Error)
+		assert.Equal(t, models.BatchStatusRejected, results[3].Status)
+	}
+
+	used, _ := h.redis.Get(monthlyUsageKey(project.ID))
+	assert.Equal(t, "5", used)
+
+	// Квота исчерпана: одиночное событие отклоняется и не занимает квоту
+	err = h.service.ProcessEvent(ctx, &models.Event{ID: "evt-1", ProjectID: project.ID, EventType: models.PageView})
+	assert.ErrorIs(t, err, models.ErrRateLimitExceeded)
+
+	used, _ = h.redis.Get(monthlyUsageKey(project.ID))
+	assert.Equal(t, "5", used)
+
+	// Отклоненное по квоте событие не считается дублем при повторной отправке
+	_, seen := h.redis.Get("dedup:" + project.ID + ":evt-1")
+	assert.False(t, seen)
+
+	assert.Len(t, h.published(t), 2)
+}
+
+func TestQuotaSoftModeAcceptsOverage(t *testing.T) {
+	ctx := context.Background()
+	project := &models.Project{
+		ID:          "project-1",
+		Active:      true,
+		EventsLimit: 2,
+		Settings:    models.JSON{"quota_mode": models.QuotaModeSoft},
+	}
+	h := newIngestHarness(t, project, models.TimestampWindow{})
+
+	results, err := h.service.ProcessBatch(ctx, batchOf(project.ID, 3))
+	assert.NoError(t, err)
+	for _, result := range results {
+		assert.Equal(t, models.BatchStatusAccepted, result.Status)
+	}
+
+	used, _ := h.redis.Get(monthlyUsageKey(project.ID))
+	assert.Equal(t, "3", used)
+}
+
+func TestQuotaReleasedOnPublishFailure(t *testing.T) {
+	project := &models.Project{ID: "project-1", Active: true}
+	h := newIngestHarness(t, project, models.TimestampWindow{})
+
+	// Синхронная доставка в недоступную Kafka завершается ошибкой
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	ctx = service.WithDeliveryMode(ctx, models.DeliveryModeSync)
+
+	_, err := h.service.ProcessBatch(ctx, batchOf(project.ID, 3))
+	assert.True(t, errors.Is(err, models.ErrEventNotDelivered))
+
+	used, _ := h.redis.Get(monthlyUsageKey(project.ID))
+	assert.Equal(t, "0", used)
+}
+
+func TestQuotaHandlerRejectsBatchOverQuota(t *testing.T) {
+	project := &models.Project{ID: "project-1", Active: true, EventsLimit: 1}
+	h := newIngestHarness(t, project, models.TimestampWindow{})
+	h.cache.Client.Set(context.Background(), monthlyUsageKey(project.ID), 1, 0)
+	router := newBatchRouter(h, project.ID)
+
+	w, response := postBatch(router, "application/json", `[{"user_id": "u1"}, {"user_id": "u2"}]`)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, 2, response.Rejected)
+}
+
+func TestQuotaFlushUsage(t *testing.T) {
+	ctx := context.Background()
+	project := &models.Project{ID: "project-1", Active: true}
+	h := newIngestHarness(t, project, models.TimestampWindow{})
+
+	_, err := h.service.ProcessBatch(ctx, batchOf(project.ID, 3))
+	assert.NoError(t, err)
+
+	// Использование из Redis записывается в projects.events_count
+	h.projects.On("UpdateEventsCount", mock.Anything, project.ID, int64(3)).Return(errors.New("db down")).Once()
+	h.quota.FlushUsage(ctx)
+
+	// Не записанный из-за ошибки проект синхронизируется в следующий раз
+	h.projects.On("UpdateEventsCount", mock.Anything, project.ID, int64(3)).Return(nil).Once()
+	h.quota.FlushUsage(ctx)
+
+	// Без новых событий синхронизировать нечего
+	h.quota.FlushUsage(ctx)
+	h.projects.AssertNumberOfCalls(t, "UpdateEventsCount", 2)
+}
@@ -0,0 +1,408 @@
+package unit
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yourusername/event-analytics-service/internal/repository"
+)
+
+// fakeRedis - Redis в памяти с командами, которыми пользуются сервисы на
+// пути приема событий: строки со сроком жизни, счетчики и транзакции
+// MULTI/EXEC, а также хеши, sorted set'ы и списки очереди повторов. Lua не
+// исполняется: EVALSHA всегда отвечает NOSCRIPT, а EVAL поддерживает только
+// скрипт выдачи повторов, который повторен на Go.
+type fakeRedis struct {
+	mu      sync.Mutex
+	values  map[string]string
+	expires map[string]time.Time
+	hashes  map[string]map[string]string
+	zsets   map[string]map[string]float64
+	lists   map[string][]string
+	calls   map[string]int
+}
+
+// newFakeRedis запускает сервер на случайном порту и возвращает репозиторий,
+// подключенный к нему
+func newFakeRedis(t *testing.T) (*fakeRedis, *repository.RedisRepository) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := &fakeRedis{
+		values:  make(map[string]string),
+		expires: make(map[string]time.Time),
+		hashes:  make(map[string]map[string]string),
+		zsets:   make(map[string]map[string]float64),
+		lists:   make(map[string][]string),
+		calls:   make(map[string]int),
+	}
+	go server.serve(listener)
+
+	repo := repository.NewRedisRepository(listener.Addr().String(), "", 0)
+	t.Cleanup(func() {
+		repo.Close()
+		listener.Close()
+	})
+	return server, repo
+}
+
+// Get возвращает значение ключа, как его видит клиент
+func (r *fakeRedis) Get(key string) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.get(key)
+}
+
+// Calls возвращает, сколько раз выполнялась команда (в нижнем регистре)
+func (r *fakeRedis) Calls(command string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.calls[command]
+}
+
+// HGet возвращает поле хеша
+func (r *fakeRedis) HGet(key, field string) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	value, ok := r.hashes[key][field]
+	return value, ok
+}
+
+// ZScore возвращает вес элемента sorted set'а
+func (r *fakeRedis) ZScore(key, member string) (float64, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	score, ok := r.zsets[key][member]
+	return score, ok
+}
+
+// List возвращает копию списка
+func (r *fakeRedis) List(key string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.lists[key]...)
+}
+
+func (r *fakeRedis) serve(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go r.handle(conn)
+	}
+}
+
+func (r *fakeRedis) handle(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	var queued [][]string
+	inMulti := false
+
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+
+		name := strings.ToLower(args[0])
+		switch {
+		case name == "multi":
+			inMulti, queued = true, nil
+			io.WriteString(conn, "+OK\r\n")
+		case name == "exec":
+			inMulti = false
+			reply := fmt.Sprintf("*%d\r\n", len(queued))
+			for _, cmd := range queued {
+				reply += r.exec(cmd)
+			}
+			io.WriteString(conn, reply)
+		case inMulti:
+			queued = append(queued, args)
+			io.WriteString(conn, "+QUEUED\r\n")
+		default:
+			io.WriteString(conn, r.exec(args))
+		}
+	}
+}
+
+func (r *fakeRedis) exec(args []string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls[strings.ToLower(args[0])]++
+	switch strings.ToLower(args[0]) {
+	case "ping":
+		return "+PONG\r\n"
+	case "get":
+		if value, ok := r.get(args[1]); ok {
+			return bulk(value)
+		}
+		return "$-1\r\n"
+	case "set":
+		return r.set(args[1], args[2], args[3:])
+	case "setnx":
+		if _, ok := r.get(args[1]); ok {
+			return ":0\r\n"
+		}
+		r.values[args[1]] = args[2]
+		return ":1\r\n"
+	case "del":
+		deleted := 0
+		for _, key := range args[1:] {
+			if _, ok := r.get(key); ok {
+				deleted++
+			}
+			delete(r.values, key)
+			delete(r.expires, key)
+			delete(r.hashes, key)
+			delete(r.zsets, key)
+			delete(r.lists, key)
+		}
+		return fmt.Sprintf(":%d\r\n", deleted)
+	case "incr":
+		return r.incrBy(args[1], "1")
+	case "incrby":
+		return r.incrBy(args[1], args[2])
+	case "decrby":
+		return r.incrBy(args[1], "-"+args[2])
+	case "expire", "pexpire":
+		if _, ok := r.get(args[1]); !ok {
+			return ":0\r\n"
+		}
+		n, _ := strconv.ParseInt(args[2], 10, 64)
+		unit := time.Second
+		if strings.ToLower(args[0]) == "pexpire" {
+			unit = time.Millisecond
+		}
+		r.expires[args[1]] = time.Now().Add(time.Duration(n) * unit)
+		return ":1\r\n"
+	case "hset":
+		hash := r.hashes[args[1]]
+		if hash == nil {
+			hash = make(map[string]string)
+			r.hashes[args[1]] = hash
+		}
+		added := 0
+		for i := 2; i+1 < len(args); i += 2 {
+			if _, ok := hash[args[i]]; !ok {
+				added++
+			}
+			hash[args[i]] = args[i+1]
+		}
+		return fmt.Sprintf(":%d\r\n", added)
+	case "hget":
+		if value, ok := r.hashes[args[1]][args[2]]; ok {
+			return bulk(value)
+		}
+		return "$-1\r\n"
+	case "hdel":
+		deleted := 0
+		for _, field := range args[2:] {
+			if _, ok := r.hashes[args[1]][field]; ok {
+				deleted++
+				delete(r.hashes[args[1]], field)
+			}
+		}
+		return fmt.Sprintf(":%d\r\n", deleted)
+	case "zadd":
+		return r.zadd(args[1], args[2:])
+	case "zrem":
+		removed := 0
+		for _, member := range args[2:] {
+			if _, ok := r.zsets[args[1]][member]; ok {
+				removed++
+				delete(r.zsets[args[1]], member)
+			}
+		}
+		return fmt.Sprintf(":%d\r\n", removed)
+	case "zcard":
+		return fmt.Sprintf(":%d\r\n", len(r.zsets[args[1]]))
+	case "rpush":
+		r.lists[args[1]] = append(r.lists[args[1]], args[2:]...)
+		return fmt.Sprintf(":%d\r\n", len(r.lists[args[1]]))
+	case "evalsha":
+		return "-NOSCRIPT No matching script. Please use EVAL.\r\n"
+	case "eval":
+		if strings.Contains(args[1], "ZRANGEBYSCORE") && strings.Contains(args[1], "HGET") {
+			return r.claimRetries(args[3:6], args[6:])
+		}
+		return "-ERR unsupported script\r\n"
+	}
+
+	return "-ERR unknown command '" + args[0] + "'\r\n"
+}
+
+func (r *fakeRedis) get(key string) (string, bool) {
+	if expiry, ok := r.expires[key]; ok && time.Now().After(expiry) {
+		delete(r.values, key)
+		delete(r.expires, key)
+	}
+	value, ok := r.values[key]
+	return value, ok
+}
+
+func (r *fakeRedis) set(key, value string, options []string) string {
+	var ttl time.Duration
+	for i := 0; i < len(options); i++ {
+		switch strings.ToLower(options[i]) {
+		case "nx":
+			if _, ok := r.get(key); ok {
+				return "$-1\r\n"
+			}
+		case "ex", "px":
+			i++
+			n, _ := strconv.ParseInt(options[i], 10, 64)
+			ttl = time.Duration(n) * time.Second
+			if strings.ToLower(options[i-1]) == "px" {
+				ttl = time.Duration(n) * time.Millisecond
+			}
+		}
+	}
+
+	r.values[key] = value
+	delete(r.expires, key)
+	if ttl > 0 {
+		r.expires[key] = time.Now().Add(ttl)
+	}
+	return "+OK\r\n"
+}
+
+func (r *fakeRedis) incrBy(key, by string) string {
+	delta, err := strconv.ParseInt(by, 10, 64)
+	if err != nil {
+		return "-ERR value is not an integer or out of range\r\n"
+	}
+
+	current, _ := r.get(key)
+	n, _ := strconv.ParseInt(current, 10, 64)
+	n += delta
+	r.values[key] = strconv.FormatInt(n, 10)
+	return fmt.Sprintf(":%d\r\n", n)
+}
+
+func (r *fakeRedis) zadd(key string, args []string) string {
+	nx := false
+	if strings.ToLower(args[0]) == "nx" {
+		nx, args = true, args[1:]
+	}
+
+	set := r.zsets[key]
+	if set == nil {
+		set = make(map[string]float64)
+		r.zsets[key] = set
+	}
+
+	added := 0
+	for i := 0; i+1 < len(args); i += 2 {
+		score, err := strconv.ParseFloat(args[i], 64)
+		if err != nil {
+			return "-ERR value is not a valid float\r\n"
+		}
+		if _, ok := set[args[i+1]]; ok {
+			if !nx {
+				set[args[i+1]] = score
+			}
+			continue
+		}
+		set[args[i+1]] = score
+		added++
+	}
+	return fmt.Sprintf(":%d\r\n", added)
+}
+
+// claimRetries повторяет claimRetriesScript из репозитория: выдает пары
+// ID - запись с наступившим временем попытки и продлевает их аренду
+func (r *fakeRedis) claimRetries(keys, args []string) string {
+	now, _ := strconv.ParseFloat(args[0], 64)
+	leaseUntil, _ := strconv.ParseFloat(args[1], 64)
+	limit, _ := strconv.Atoi(args[2])
+
+	schedule := r.zsets[keys[0]]
+	var due []string
+	for id, score := range schedule {
+		if score <= now {
+			due = append(due, id)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if schedule[due[i]] != schedule[due[j]] {
+			return schedule[due[i]] < schedule[due[j]]
+		}
+		return due[i] < due[j]
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	var reply []string
+	for _, id := range due {
+		data, ok := r.hashes[keys[1]][id]
+		if !ok {
+			delete(schedule, id)
+			delete(r.zsets[keys[2]], id)
+			continue
+		}
+		schedule[id] = leaseUntil
+		reply = append(reply, id, data)
+	}
+
+	out := fmt.Sprintf("*%d\r\n", len(reply))
+	for _, value := range reply {
+		out += bulk(value)
+	}
+	return out
+}
+
+func bulk(value string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+}
+
+// readCommand читает команду клиента в формате RESP (массив bulk-строк)
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		return nil, fmt.Errorf("unexpected command line %q", line)
+	}
+
+	count, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, count)
+	for i := range args {
+		header, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(header[1:]))
+		if err != nil {
+			return nil, err
+		}
+
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		args[i] = string(data[:size])
+	}
+
+	return args, nil
+}
@@ -0,0 +1,96 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/event-analytics-service/internal/consumer"
+	"github.com/yourusername/event-analytics-service/internal/models"
+)
+
+func TestRetryBackoff(t *testing.T) {
+	policy := consumer.RetryPolicy{MaxAttempts: 5, BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+
+	for attempt, max := range map[int]time.Duration{
+		1: time.Second,
+		2: 2 * time.Second,
+		3: 4 * time.Second,
+		4: 8 * time.Second,
+		5: 10 * time.Second,
+		9: 10 * time.Second,
+	} {
+		for i := 0; i < 20; i++ {
+			delay := policy.Backoff(attempt)
+			assert.GreaterOrEqual(t, delay, max/2, "attempt %d", attempt)
+			assert.Less(t, delay, max, "attempt %d", attempt)
+		}
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	assert.True(t, consumer.IsTransient(context.DeadlineExceeded))
+	assert.True(t, consumer.IsTransient(fmt.Errorf("dial: %w", syscall.ECONNREFUSED)))
+	assert.True(t, consumer.IsTransient(clickhouse.ErrAcquireConnTimeout))
+	assert.True(t, consumer.IsTransient(&clickhouse.Exception{Code: 252, Name: "TOO_MANY_PARTS"}))
+
+	assert.False(t, consumer.IsTransient(&clickhouse.Exception{Code: 53, Name: "TYPE_MISMATCH"}))
+	assert.False(t, consumer.IsTransient(errors.New("clickhouse [AppendRow]: converting string to Int64 is unsupported")))
+}
+
+func TestClaimRetriesMovesCorruptEntries(t *testing.T) {
+	ctx := context.Background()
+	fake, repo := newFakeRedis(t)
+	now := time.Now()
+
+	require.NoError(t, repo.PushToRetryQueue(ctx, &models.RetryEntry{
+		ID:            "evt-1",
+		Event:         &models.Event{ID: "evt-1", ProjectID: "project-1"},
+		Attempts:      1,
+		FirstFailedAt: now.Add(-time.Minute),
+		NextAttemptAt: now.Add(-time.Second),
+	}))
+
+	// Запись, которую нельзя разобрать, и запись без события
+	score := float64(now.Add(-time.Second).UnixMilli())
+	for id, data := range map[string]string{"broken": "{not json", "empty": `{"id":"empty"}`} {
+		repo.Client.HSet(ctx, "retry:entries", id, data)
+		repo.Client.ZAdd(ctx, "retry:schedule", &redis.Z{Score: score, Member: id})
+		repo.Client.ZAdd(ctx, "retry:first", &redis.Z{Score: score, Member: id})
+	}
+
+	entries, corrupt, err := repo.ClaimRetries(ctx, now, time.Minute, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 2, corrupt)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "evt-1", entries[0].ID)
+	}
+
+	// Поврежденные записи убраны из очереди и сохранены для разбора
+	for _, id := range []string{"broken", "empty"} {
+		_, ok := fake.HGet("retry:entries", id)
+		assert.False(t, ok)
+		_, ok = fake.ZScore("retry:schedule", id)
+		assert.False(t, ok)
+		_, ok = fake.ZScore("retry:first", id)
+		assert.False(t, ok)
+	}
+	assert.ElementsMatch(t, []string{"{not json", `{"id":"empty"}`}, fake.List("retry:corrupt"))
+
+	// Корректная запись осталась в очереди до завершения
+	score, ok := fake.ZScore("retry:schedule", "evt-1")
+	assert.True(t, ok)
+	assert.Equal(t, float64(now.Add(time.Minute).UnixMilli()), score)
+
+	entries, corrupt, err = repo.ClaimRetries(ctx, now.Add(2*time.Minute), time.Minute, 10)
+	require.NoError(t, err)
+	assert.Zero(t, corrupt)
+	assert.Len(t, entries, 1)
+}
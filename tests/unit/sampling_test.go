@@ -0,0 +1,49 @@
+package unit
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/event-analytics-service/internal/models"
+	"github.com/yourusername/event-analytics-service/internal/service"
+)
+
+func TestSampleKey(t *testing.T) {
+	kept := 0
+	for i := 0; i < 10000; i++ {
+		key := fmt.Sprintf("user-%d", i)
+		keep := service.SampleKey(key, 0.1)
+		// Решение для пользователя не меняется между событиями
+		assert.Equal(t, keep, service.SampleKey(key, 0.1))
+		// Выборка с меньшей долей вложена в выборку с большей
+		if keep {
+			assert.True(t, service.SampleKey(key, 0.5))
+			kept++
+		}
+	}
+	assert.InDelta(t, 1000, kept, 150)
+
+	assert.True(t, service.SampleKey("user-1", 1))
+	assert.False(t, service.SampleKey("user-1", 0))
+}
+
+func TestProjectSampling(t *testing.T) {
+	project := &models.Project{Settings: map[string]interface{}{
+		"sampling": map[string]interface{}{"button_click": 0.1, "*": 0.5},
+	}}
+	settings, err := project.Sampling()
+	assert.NoError(t, err)
+	assert.Equal(t, 0.1, settings.Rate(models.ButtonClick))
+	assert.Equal(t, 0.5, settings.Rate(models.PageView))
+
+	project.Settings["sampling"] = map[string]interface{}{"button_click": 1.5}
+	_, err = project.Sampling()
+	assert.True(t, errors.Is(err, models.ErrInvalidSamplingSettings))
+
+	delete(project.Settings, "sampling")
+	settings, err = project.Sampling()
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, settings.Rate(models.ButtonClick))
+}
@@ -0,0 +1,79 @@
+package unit
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yourusername/event-analytics-service/internal/handler"
+	"github.com/yourusername/event-analytics-service/internal/models"
+	"github.com/yourusername/event-analytics-service/internal/service"
+)
+
+func TestValidateMetadata(t *testing.T) {
+	schema := &models.EventSchema{
+		EventName: "purchase",
+		Mode:      models.SchemaModeStrict,
+		Fields: map[string]models.SchemaField{
+			"price":    {Type: models.FieldTypeNumber, Required: true},
+			"quantity": {Type: models.FieldTypeInteger},
+			"currency": {Type: models.FieldTypeString},
+		},
+	}
+
+	// Корректные данные
+	errs := service.ValidateMetadata(schema, map[string]interface{}{
+		"price":    9.99,
+		"quantity": float64(2),
+		"extra":    "ignored",
+	})
+	assert.Empty(t, errs)
+
+	// Цена строкой, дробное количество, нет обязательного поля
+	errs = service.ValidateMetadata(schema, map[string]interface{}{
+		"price":    "9.99",
+		"quantity": 1.5,
+	})
+	assert.Equal(t, []models.ValidationError{
+		{Field: "metadata.price", Message: "expected number, got string"},
+		{Field: "metadata.quantity", Message: "expected integer, got number"},
+	}, errs)
+
+	errs = service.ValidateMetadata(schema, nil)
+	assert.Equal(t, []models.ValidationError{
+		{Field: "metadata.price", Message: "required field missing"},
+	}, errs)
+}
+
+func TestSchemaHandlerProjectLookupErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	projects := new(MockProjectRepository)
+	schemaHandler := handler.NewSchemaHandler(nil, service.NewProjectService(projects, nil, unreachableRedis()))
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("user_id", "user-1") })
+	router.GET("/projects/:id/schemas", schemaHandler.ListSchemas)
+
+	listSchemas := func() int {
+		req, _ := http.NewRequest(http.MethodGet, "/projects/project-1/schemas", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	projects.On("GetProjectByID", mock.Anything, "project-1").Return(nil, models.ErrProjectNotFound).Once()
+	assert.Equal(t, http.StatusNotFound, listSchemas())
+
+	// Чужой проект выглядит как несуществующий
+	projects.On("GetProjectByID", mock.Anything, "project-1").Return(&models.Project{ID: "project-1", UserID: "user-2"}, nil).Once()
+	assert.Equal(t, http.StatusNotFound, listSchemas())
+
+	// Ошибка базы данных не выдается за отсутствие проекта
+	projects.On("GetProjectByID", mock.Anything, "project-1").Return(nil, errors.New("connection refused")).Once()
+	assert.Equal(t, http.StatusInternalServerError, listSchemas())
+}
@@ -0,0 +1,104 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/event-analytics-service/internal/handler"
+	"github.com/yourusername/event-analytics-service/internal/models"
+)
+
+func TestSegmentMessageToEvent(t *testing.T) {
+	var msg models.SegmentMessage
+	err := json.Unmarshal([]byte(`{
+		"type": "track",
+		"messageId": "msg-1",
+		"anonymousId": "anon-1",
+		"event": "Order Completed",
+		"properties": {"revenue": 42.5},
+		"context": {"ip": "203.0.113.42", "userAgent": "curl/8.0"},
+		"timestamp": "2024-05-01T10:00:00Z"
+	}`), &msg)
+	assert.NoError(t, err)
+
+	event, err := msg.ToEvent()
+	assert.NoError(t, err)
+	assert.Equal(t, "msg-1", event.ID)
+	assert.Equal(t, "anon-1", event.UserID)
+	assert.Equal(t, "anon-1", event.AnonymousID)
+	assert.Equal(t, models.Custom, event.EventType)
+	assert.Equal(t, "Order Completed", event.EventName)
+	assert.Equal(t, 42.5, event.Metadata["revenue"])
+	assert.Equal(t, "203.0.113.42", event.IPAddress)
+	assert.Equal(t, 2024, event.Timestamp.Year())
+
+	// page берет URL из properties, а при его отсутствии - из context.page
+	page := models.SegmentMessage{Type: models.SegmentPage, UserID: "user-1"}
+	page.Context.Page.URL = "https://example.com/pricing"
+	event, err = page.ToEvent()
+	assert.NoError(t, err)
+	assert.Equal(t, models.PageView, event.EventType)
+	assert.Equal(t, "https://example.com/pricing", event.PageURL)
+	assert.Nil(t, event.Metadata)
+
+	screen := models.SegmentMessage{Type: models.SegmentScreen, UserID: "user-1", Name: "Checkout"}
+	event, err = screen.ToEvent()
+	assert.NoError(t, err)
+	assert.Equal(t, models.SegmentScreenEventName, event.EventName)
+	assert.Equal(t, "Checkout", event.Metadata["name"])
+
+	// track без названия и сообщения без пользователя отклоняются
+	_, err = (&models.SegmentMessage{Type: models.SegmentTrack, UserID: "user-1"}).ToEvent()
+	assert.ErrorIs(t, err, models.ErrInvalidEventData)
+	_, err = (&models.SegmentMessage{Type: models.SegmentTrack, Event: "Signed Up"}).ToEvent()
+	assert.ErrorIs(t, err, models.ErrInvalidEventData)
+	_, err = (&models.SegmentMessage{Type: "group", UserID: "user-1"}).ToEvent()
+	assert.ErrorIs(t, err, models.ErrInvalidEventType)
+}
+
+func TestSegmentDeliveryModeHeader(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	project := &models.Project{ID: "project-1", Active: true}
+	h := newIngestHarness(t, project, models.TimestampWindow{})
+	segmentHandler := handler.NewSegmentHandler(h.service, nil)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("project_id", project.ID) })
+	router.POST("/segment/track", segmentHandler.Track)
+	router.POST("/segment/batch", segmentHandler.Batch)
+
+	send := func(path, mode, body string) int {
+		// Синхронная доставка в недоступную Kafka ждет брокера до конца запроса
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)).WithContext(ctx)
+		req.Header.Set("Content-Type", "application/json")
+		if mode != "" {
+			req.Header.Set("X-Delivery-Mode", mode)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	track := `{"userId": "u1", "event": "Signed Up"}`
+	batch := `{"batch": [{"type": "track", "userId": "u1", "event": "Signed Up"}]}`
+
+	// Асинхронно событие уходит в spool, синхронно - ждет подтверждения брокера
+	assert.Equal(t, http.StatusOK, send("/segment/track", "", track))
+	assert.Equal(t, http.StatusServiceUnavailable, send("/segment/track", "sync", track))
+	assert.Equal(t, http.StatusOK, send("/segment/batch", "async", batch))
+	assert.Equal(t, http.StatusServiceUnavailable, send("/segment/batch", "sync", batch))
+
+	assert.Equal(t, http.StatusBadRequest, send("/segment/track", "eventually", track))
+	assert.Equal(t, http.StatusBadRequest, send("/segment/batch", "eventually", batch))
+}
@@ -7,10 +7,15 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/yourusername/event-analytics-service/internal/metrics"
 	"github.com/yourusername/event-analytics-service/internal/models"
 	"github.com/yourusername/event-analytics-service/internal/service"
 )
 
+// Метрики регистрируются в глобальном реестре Prometheus, поэтому создаются
+// один раз на все тесты пакета
+var testMetrics = metrics.NewMetrics("unit-test")
+
 // Mock репозитория
 type MockEventRepository struct {
 	mock.Mock
@@ -31,12 +36,63 @@ func (m *MockEventRepository) GetStats(ctx context.Context, filter models.StatsR
 	return args.Get(0).([]models.EventStats), args.Error(1)
 }
 
-// Остальные методы интерфейса...
+func (m *MockEventRepository) GetStatsByProject(ctx context.Context, projectID string, filter models.StatsRequest) ([]models.EventStats, error) {
+	args := m.Called(ctx, projectID, filter)
+	return args.Get(0).([]models.EventStats), args.Error(1)
+}
+
+func (m *MockEventRepository) GetEventsByUser(ctx context.Context, userID string, limit, offset int) ([]models.Event, error) {
+	args := m.Called(ctx, userID, limit, offset)
+	return args.Get(0).([]models.Event), args.Error(1)
+}
+
+func (m *MockEventRepository) GetEventsByType(ctx context.Context, eventType models.EventType, start, end time.Time) ([]models.Event, error) {
+	args := m.Called(ctx, eventType, start, end)
+	return args.Get(0).([]models.Event), args.Error(1)
+}
+
+func (m *MockEventRepository) GetEvents(ctx context.Context, filter models.StatsRequest) ([]models.Event, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]models.Event), args.Error(1)
+}
+
+func (m *MockEventRepository) GetTopPages(ctx context.Context, projectID string, limit int) ([]models.PageStat, error) {
+	args := m.Called(ctx, projectID, limit)
+	return args.Get(0).([]models.PageStat), args.Error(1)
+}
+
+func (m *MockEventRepository) GetUserSessions(ctx context.Context, projectID, userID string, sessionTimeout time.Duration) ([]models.UserSession, error) {
+	args := m.Called(ctx, projectID, userID, sessionTimeout)
+	return args.Get(0).([]models.UserSession), args.Error(1)
+}
+
+func (m *MockEventRepository) GetTrafficSources(ctx context.Context, projectID, conversionEvent string, sessionTimeout time.Duration, start, end time.Time) ([]models.TrafficSource, error) {
+	args := m.Called(ctx, projectID, conversionEvent, sessionTimeout, start, end)
+	return args.Get(0).([]models.TrafficSource), args.Error(1)
+}
+
+func (m *MockEventRepository) GetFunnelAnalysis(ctx context.Context, projectID string, steps []string, start, end time.Time) ([]models.FunnelStep, error) {
+	args := m.Called(ctx, projectID, steps, start, end)
+	return args.Get(0).([]models.FunnelStep), args.Error(1)
+}
+
+func (m *MockEventRepository) GetIngestionLatency(ctx context.Context, projectID, interval string, start, end time.Time) (*models.IngestionLatencyReport, error) {
+	args := m.Called(ctx, projectID, interval, start, end)
+	return args.Get(0).(*models.IngestionLatencyReport), args.Error(1)
+}
+
+func (m *MockEventRepository) Ping(ctx context.Context) error {
+	return m.Called(ctx).Error(0)
+}
+
+func (m *MockEventRepository) Close() error {
+	return m.Called().Error(0)
+}
 
 func TestEventService_ProcessEvent(t *testing.T) {
-	// Arrange
+	// Arrange: событие без проекта отклоняется до обращения к Kafka и Redis
 	mockRepo := new(MockEventRepository)
-	service := service.NewEventService(mockRepo)
+	service := service.NewEventService(mockRepo, nil, nil, nil, nil, nil, nil, nil, testMetrics, time.Hour, "", models.TimestampWindow{})
 
 	event := &models.Event{
 		UserID:    "user123",
@@ -45,13 +101,11 @@ func TestEventService_ProcessEvent(t *testing.T) {
 		Timestamp: time.Now(),
 	}
 
-	mockRepo.On("InsertEvent", mock.Anything, mock.AnythingOfType("*models.Event")).Return(nil)
-
 	// Act
 	err := service.ProcessEvent(context.Background(), event)
 
 	// Assert
-	assert.NoError(t, err)
+	assert.ErrorIs(t, err, models.ErrInvalidAPIKey)
 	assert.NotEmpty(t, event.ID) // Проверяем, что ID сгенерировался
 	mockRepo.AssertExpectations(t)
 }
@@ -59,7 +113,7 @@ func TestEventService_ProcessEvent(t *testing.T) {
 func TestStatsService_CalculateConversionRate(t *testing.T) {
 	// Arrange
 	mockRepo := new(MockEventRepository)
-	service := service.NewStatsService(mockRepo)
+	service := service.NewStatsService(mockRepo, nil, testMetrics)
 
 	viewStats := []models.EventStats{
 		{TimeBucket: "2024-01-01", EventType: "page_view", Count: 100},
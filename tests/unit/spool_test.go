@@ -0,0 +1,137 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/event-analytics-service/internal/producer"
+)
+
+func TestSpoolReplay(t *testing.T) {
+	dir := t.TempDir()
+
+	spool, err := producer.NewSpool(dir, 0)
+	assert.NoError(t, err)
+
+	accepted := time.Now().Add(-time.Hour)
+	assert.NoError(t, spool.Append([]kafka.Message{
+		{Key: []byte("user-1"), Value: []byte(`{"id":"1"}`), Time: accepted},
+		{Key: []byte("user-2"), Value: []byte(`{"id":"2"}`), Time: accepted},
+	}))
+	assert.NoError(t, spool.Close())
+
+	// После перезапуска неотправленные сообщения подхватываются с диска
+	spool, err = producer.NewSpool(dir, 0)
+	assert.NoError(t, err)
+	records, _, oldest := spool.Stats()
+	assert.Equal(t, int64(2), records)
+	assert.False(t, oldest.IsZero())
+
+	// Kafka недоступна - сообщения остаются в spool
+	failing := func(ctx context.Context, msgs ...kafka.Message) error {
+		return errors.New("broker unavailable")
+	}
+	sent, err := spool.Replay(context.Background(), failing)
+	assert.Error(t, err)
+	assert.Equal(t, 0, sent)
+
+	var delivered []kafka.Message
+	writer := func(ctx context.Context, msgs ...kafka.Message) error {
+		delivered = append(delivered, msgs...)
+		return nil
+	}
+	sent, err = spool.Replay(context.Background(), writer)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, sent)
+	assert.Equal(t, "user-1", string(delivered[0].Key))
+	assert.Equal(t, `{"id":"2"}`, string(delivered[1].Value))
+	// Время сообщения - момент записи в Kafka, а не приема API
+	for _, msg := range delivered {
+		assert.WithinDuration(t, time.Now(), msg.Time, time.Minute)
+	}
+
+	records, bytes, _ := spool.Stats()
+	assert.Equal(t, int64(0), records)
+	assert.Equal(t, int64(0), bytes)
+
+	segments, _ := filepath.Glob(filepath.Join(dir, "*.log"))
+	assert.Empty(t, segments)
+}
+
+func TestSpoolTruncatesTornRecord(t *testing.T) {
+	dir := t.TempDir()
+
+	spool, err := producer.NewSpool(dir, 0)
+	assert.NoError(t, err)
+	assert.NoError(t, spool.Append([]kafka.Message{{Value: []byte("first")}}))
+	assert.NoError(t, spool.Close())
+
+	// Запись, оборванная при падении процесса
+	segments, _ := filepath.Glob(filepath.Join(dir, "*.log"))
+	assert.Len(t, segments, 1)
+	file, err := os.OpenFile(segments[0], os.O_APPEND|os.O_WRONLY, 0o644)
+	assert.NoError(t, err)
+	file.Write([]byte{0, 0, 0, 42, 1, 2})
+	file.Close()
+
+	spool, err = producer.NewSpool(dir, 0)
+	assert.NoError(t, err)
+	records, _, _ := spool.Stats()
+	assert.Equal(t, int64(1), records)
+}
+
+func TestSpoolKeepsSegmentWithCorruptedRecord(t *testing.T) {
+	dir := t.TempDir()
+
+	spool, err := producer.NewSpool(dir, 0)
+	assert.NoError(t, err)
+	for _, value := range []string{"first", "second", "third"} {
+		assert.NoError(t, spool.Append([]kafka.Message{{Value: []byte(value)}}))
+	}
+	assert.NoError(t, spool.Close())
+
+	// Поврежденная запись в середине сегмента: за ней есть корректные
+	segments, _ := filepath.Glob(filepath.Join(dir, "*.log"))
+	assert.Len(t, segments, 1)
+	data, err := os.ReadFile(segments[0])
+	assert.NoError(t, err)
+	damaged := append([]byte(nil), data...)
+	damaged[bytes.Index(damaged, []byte("c2Vjb25k"))] ^= 0xff // "second" в base64
+	assert.NoError(t, os.WriteFile(segments[0], damaged, 0o644))
+
+	spool, err = producer.NewSpool(dir, 0)
+	assert.NoError(t, err)
+	records, _, _ := spool.Stats()
+	assert.Equal(t, int64(1), records)
+
+	var delivered []string
+	sent, err := spool.Replay(context.Background(), func(ctx context.Context, msgs ...kafka.Message) error {
+		for _, msg := range msgs {
+			delivered = append(delivered, string(msg.Value))
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	assert.Equal(t, []string{"first"}, delivered)
+
+	// Файл не обрезан и не удален, а отложен целиком
+	kept, err := os.ReadFile(segments[0] + ".corrupt")
+	assert.NoError(t, err)
+	assert.Equal(t, damaged, kept)
+}
+
+func TestSpoolFull(t *testing.T) {
+	spool, err := producer.NewSpool(t.TempDir(), 64)
+	assert.NoError(t, err)
+
+	err = spool.Append([]kafka.Message{{Value: make([]byte, 128)}})
+	assert.True(t, errors.Is(err, producer.ErrSpoolFull))
+}
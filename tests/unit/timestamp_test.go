@@ -0,0 +1,98 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/event-analytics-service/internal/handler"
+	"github.com/yourusername/event-analytics-service/internal/models"
+	"github.com/yourusername/event-analytics-service/internal/service"
+)
+
+func TestCorrectTimestamp(t *testing.T) {
+	received := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	window := models.TimestampWindow{
+		MaxPast:   24 * time.Hour,
+		MaxFuture: 10 * time.Minute,
+		Policy:    models.TimestampPolicyClamp,
+	}
+
+	// Часы клиента отстают на час: событие за 5 секунд до отправки
+	event := &models.Event{
+		Timestamp:  received.Add(-time.Hour - 5*time.Second),
+		SentAt:     received.Add(-time.Hour),
+		ReceivedAt: received,
+	}
+	assert.NoError(t, service.CorrectTimestamp(event, window))
+	assert.Equal(t, received.Add(-5*time.Second), event.Timestamp)
+	assert.Empty(t, event.Warnings)
+
+	// Без timestamp используется время приема
+	event = &models.Event{ReceivedAt: received}
+	assert.NoError(t, service.CorrectTimestamp(event, window))
+	assert.Equal(t, received, event.Timestamp)
+
+	// Событие из будущего сдвигается на границу окна с предупреждением
+	event = &models.Event{Timestamp: received.Add(time.Hour), ReceivedAt: received}
+	assert.NoError(t, service.CorrectTimestamp(event, window))
+	assert.Equal(t, received.Add(10*time.Minute), event.Timestamp)
+	assert.Len(t, event.Warnings, 1)
+	assert.Equal(t, "timestamp", event.Warnings[0].Field)
+
+	// В режиме reject старое событие отклоняется
+	window.Policy = models.TimestampPolicyReject
+	event = &models.Event{Timestamp: received.AddDate(-1, 0, 0), ReceivedAt: received}
+	assert.ErrorIs(t, service.CorrectTimestamp(event, window), models.ErrInvalidTimestamp)
+}
+
+func TestReceivedAtSetByServer(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	project := &models.Project{ID: "project-1", Active: true}
+	h := newIngestHarness(t, project, models.TimestampWindow{MaxPast: 24 * time.Hour, Policy: models.TimestampPolicyClamp})
+	eventHandler := handler.NewEventHandler(h.service)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("project_id", project.ID) })
+	router.POST("/events/track", eventHandler.TrackEvent)
+	router.POST("/events/batch", eventHandler.TrackBatch)
+
+	// Клиент присылает received_at в прошлом, чтобы сдвинуть timestamp и
+	// исказить задержку приема
+	forged := `"received_at": "2020-01-01T00:00:00Z", "sent_at": "2024-05-01T12:00:00Z", "timestamp": "2024-05-01T11:59:55Z"`
+	for path, body := range map[string]string{
+		"/events/track": `{"user_id": "u1", ` + forged + `}`,
+		"/events/batch": `[{"user_id": "u2", ` + forged + `}]`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusAccepted, w.Code, path)
+	}
+
+	// Событие сервиса с заполненным ReceivedAt (gRPC, Segment) тоже получает
+	// время сервера
+	event := &models.Event{
+		ProjectID:  project.ID,
+		UserID:     "u3",
+		EventType:  models.PageView,
+		ReceivedAt: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	assert.NoError(t, h.service.ProcessEvent(context.Background(), event))
+
+	published := h.published(t)
+	if assert.Len(t, published, 3) {
+		for _, event := range published {
+			assert.WithinDuration(t, time.Now(), event.ReceivedAt, time.Minute, event.UserID)
+			// timestamp скорректирован относительно часов сервера
+			assert.WithinDuration(t, time.Now().Add(-5*time.Second), event.Timestamp, time.Minute, event.UserID)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package unit
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/event-analytics-service/internal/models"
+	"github.com/yourusername/event-analytics-service/internal/transform"
+)
+
+func TestTransformPipeline(t *testing.T) {
+	pipeline, err := transform.Compile([]models.TransformRule{
+		{Action: models.TransformRenameEventType, From: "custom", To: "purchase"},
+		{Action: models.TransformRenameKey, From: "cost", To: "price"},
+		{Action: models.TransformDropKey, Key: "debug"},
+		{Action: models.TransformRewriteURL, Pattern: `/users/[0-9]+`, Replacement: "/users/:id"},
+		{Action: models.TransformDeriveKey, Key: "section", Source: "page_url", Pattern: `^https?://[^/]+/([^/?]+)`, Replacement: "$1"},
+		{Action: models.TransformDeriveKey, Key: "source", Value: "web", Filter: &models.TransformFilter{EventType: models.Purchase}},
+		{Action: models.TransformDropEvent, Filter: &models.TransformFilter{Metadata: map[string]string{"env": "staging"}}},
+	})
+	assert.NoError(t, err)
+
+	event := &models.Event{
+		EventType: models.Custom,
+		EventName: "custom",
+		PageURL:   "https://example.com/users/42/orders",
+		Metadata:  map[string]interface{}{"cost": 10.5, "debug": true},
+	}
+	assert.True(t, pipeline.Apply(event))
+	assert.Equal(t, models.Purchase, event.EventType)
+	assert.Equal(t, "purchase", event.EventName)
+	assert.Equal(t, "https://example.com/users/:id/orders", event.PageURL)
+	assert.Equal(t, map[string]interface{}{
+		"price":   10.5,
+		"section": "users",
+		"source":  "web",
+	}, event.Metadata)
+
+	staging := &models.Event{EventType: models.PageView, Metadata: map[string]interface{}{"env": "staging"}}
+	assert.False(t, pipeline.Apply(staging))
+}
+
+func TestTransformCompileErrors(t *testing.T) {
+	invalid := [][]models.TransformRule{
+		{{Action: "unknown"}},
+		{{Action: models.TransformRenameEventType, From: "custom", To: "refund"}},
+		{{Action: models.TransformRewriteURL, Pattern: "("}},
+		{{Action: models.TransformDropEvent}},
+		{{Action: models.TransformDeriveKey, Key: "x", Source: "ip_address"}},
+	}
+
+	for _, rules := range invalid {
+		_, err := transform.Compile(rules)
+		assert.True(t, errors.Is(err, models.ErrInvalidTransform), "rules %+v", rules)
+	}
+}
@@ -0,0 +1,74 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/event-analytics-service/internal/useragent"
+)
+
+func TestUserAgentParse(t *testing.T) {
+	tests := []struct {
+		name string
+		ua   string
+		want useragent.Info
+	}{
+		{
+			name: "chrome on windows",
+			ua:   "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			want: useragent.Info{DeviceType: useragent.DeviceDesktop, Browser: "Chrome", OS: "Windows"},
+		},
+		{
+			name: "edge on windows",
+			ua:   "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.2210.91",
+			want: useragent.Info{DeviceType: useragent.DeviceDesktop, Browser: "Edge", OS: "Windows"},
+		},
+		{
+			name: "safari on iphone",
+			ua:   "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1",
+			want: useragent.Info{DeviceType: useragent.DeviceMobile, Browser: "Safari", OS: "iOS"},
+		},
+		{
+			name: "safari on ipad",
+			ua:   "Mozilla/5.0 (iPad; CPU OS 16_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.6 Mobile/15E148 Safari/604.1",
+			want: useragent.Info{DeviceType: useragent.DeviceTablet, Browser: "Safari", OS: "iOS"},
+		},
+		{
+			name: "chrome on android phone",
+			ua:   "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36",
+			want: useragent.Info{DeviceType: useragent.DeviceMobile, Browser: "Chrome", OS: "Android"},
+		},
+		{
+			name: "firefox on linux",
+			ua:   "Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0",
+			want: useragent.Info{DeviceType: useragent.DeviceDesktop, Browser: "Firefox", OS: "Linux"},
+		},
+		{
+			name: "chrome on chromebook",
+			ua:   "Mozilla/5.0 (X11; CrOS x86_64 14541.0.0) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			want: useragent.Info{DeviceType: useragent.DeviceDesktop, Browser: "Chrome", OS: "Chrome OS"},
+		},
+		{
+			// "cros" внутри "Microsoft" не означает Chrome OS
+			name: "microsoft outlook",
+			ua:   "Microsoft Office/16.0 (Microsoft Outlook 16.0.17126; Pro)",
+			want: useragent.Info{DeviceType: useragent.DeviceOther},
+		},
+		{
+			name: "bing preview on linux",
+			ua:   "Mozilla/5.0 (X11; Linux x86_64; compatible; MicrosoftPreview/2.0; +https://aka.ms/MicrosoftPreview) Chrome/120.0.0.0 Safari/537.36",
+			want: useragent.Info{DeviceType: useragent.DeviceDesktop, Browser: "Chrome", OS: "Linux"},
+		},
+		{
+			name: "empty",
+			ua:   "",
+			want: useragent.Info{DeviceType: useragent.DeviceOther},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, useragent.Parse(tt.ua))
+		})
+	}
+}